@@ -0,0 +1,54 @@
+package actions
+
+import (
+	"go.albinodrought.com/neptunes-pride/internal/matches"
+	"go.albinodrought.com/neptunes-pride/internal/matchstore"
+	"go.albinodrought.com/neptunes-pride/internal/opsec"
+	"go.albinodrought.com/neptunes-pride/internal/types"
+)
+
+// DefaultCoverageHistoryLimit is how many stored snapshots per player are checked
+// when looking for the last time a star was seen
+const DefaultCoverageHistoryLimit = 1000
+
+// FindCoverageGaps finds the stars no ally scans in the merged snapshot, then walks
+// back through stored snapshots to find out how long each star has been dark.
+func FindCoverageGaps(db matchstore.MatchStore, match *matches.Match, accessProfile matches.AccessProfile, merged *types.APIResponse, historyLimit int) (opsec.CoverageReport, error) {
+	allyIDs := AllyIDs(match, accessProfile)
+
+	report := opsec.FindCoverageGaps(merged, allyIDs)
+	if len(report.Stars) == 0 {
+		return report, nil
+	}
+
+	for _, allyID := range allyIDs {
+		snapshotTimes, err := db.ListSnapshotTimes(match.GameNumber, allyID, historyLimit)
+		if err == matchstore.ErrSnapshotNotFound || err == matchstore.ErrMatchNotFound {
+			continue
+		}
+		if err != nil {
+			return report, err
+		}
+
+		// snapshot times are newest-first
+		for _, snapshotTime := range snapshotTimes {
+			if snapshotTime > report.Now {
+				// time travelling, ignore the future
+				continue
+			}
+
+			if report.Seen(snapshotTime) {
+				break
+			}
+
+			snapshot, err := db.FindSnapshot(match.GameNumber, allyID, snapshotTime)
+			if err != nil {
+				return report, err
+			}
+
+			report.RecordSighting(snapshot)
+		}
+	}
+
+	return report, nil
+}
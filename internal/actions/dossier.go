@@ -0,0 +1,84 @@
+package actions
+
+import (
+	"log"
+	"strconv"
+
+	"go.albinodrought.com/neptunes-pride/internal/dossier"
+	"go.albinodrought.com/neptunes-pride/internal/matches"
+	"go.albinodrought.com/neptunes-pride/internal/matchstore"
+)
+
+// DefaultDossierHistoryLimit is how many stored snapshots per player are checked for attacks
+const DefaultDossierHistoryLimit = 200
+
+// BuildDossier finds every game an alias played through the stored alias index.
+// accessProfileFor decides which matches may be looked at, and through which access profile.
+// Matches that fail to load are logged and left out.
+func BuildDossier(db matchstore.MatchStore, alias string, accessProfileFor func(match *matches.Match) (matches.AccessProfile, bool), historyLimit int) (dossier.Dossier, error) {
+	aliasGames, err := db.ListAliasGames(alias)
+	if err != nil {
+		return dossier.Dossier{}, err
+	}
+
+	games := []dossier.Game{}
+	for _, aliasGame := range aliasGames {
+		game, ok, err := buildDossierGame(db, aliasGame, accessProfileFor, historyLimit)
+		if err != nil {
+			log.Printf("Skipping match %v in dossier for %v: %v", aliasGame.GameNumber, alias, err)
+			continue
+		}
+		if ok {
+			games = append(games, game)
+		}
+	}
+
+	return dossier.Summarize(alias, games), nil
+}
+
+func buildDossierGame(db matchstore.MatchStore, aliasGame matchstore.AliasGame, accessProfileFor func(match *matches.Match) (matches.AccessProfile, bool), historyLimit int) (dossier.Game, bool, error) {
+	match, err := db.FindMatchOrFail(aliasGame.GameNumber)
+	if err != nil {
+		return dossier.Game{}, false, err
+	}
+
+	accessProfile, ok := accessProfileFor(match)
+	if !ok {
+		return dossier.Game{}, false, nil
+	}
+
+	merged, err := MergedSnapshot(db, match, accessProfile, map[string]string{}, nil)
+	if err == ErrNoSnapshotsLoaded {
+		return dossier.Game{}, false, nil
+	}
+	if err != nil {
+		return dossier.Game{}, false, err
+	}
+
+	player, ok := merged.ScanningData.Players[strconv.Itoa(aliasGame.PlayerUID)]
+	if !ok {
+		return dossier.Game{}, false, nil
+	}
+
+	attacks := dossier.Attacks{}
+	attacks.Record(merged, player.UID)
+	for _, allyID := range AllyIDs(match, accessProfile) {
+		snapshotTimes, err := db.ListSnapshotTimes(match.GameNumber, allyID, historyLimit)
+		if err == matchstore.ErrSnapshotNotFound || err == matchstore.ErrMatchNotFound {
+			continue
+		}
+		if err != nil {
+			return dossier.Game{}, false, err
+		}
+
+		for _, snapshotTime := range snapshotTimes {
+			snapshot, err := db.FindSnapshot(match.GameNumber, allyID, snapshotTime)
+			if err != nil {
+				return dossier.Game{}, false, err
+			}
+			attacks.Record(snapshot, player.UID)
+		}
+	}
+
+	return dossier.NewGame(match.GameNumber, match.Name, match.Finished, merged, player, attacks), true, nil
+}
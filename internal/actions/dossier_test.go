@@ -0,0 +1,53 @@
+package actions
+
+import (
+	"testing"
+
+	"go.albinodrought.com/neptunes-pride/internal/fixtures"
+	"go.albinodrought.com/neptunes-pride/internal/matches"
+	"go.albinodrought.com/neptunes-pride/internal/matchstore"
+)
+
+func TestBuildDossier(t *testing.T) {
+	db := matchstore.OpenMemory()
+	snapshot := fixtures.Load(t, "../opsec/aburrido.json")
+	if err := db.SaveSnapshot("123", snapshot); err != nil {
+		t.Fatal(err)
+	}
+
+	match, _ := db.FindOrCreateMatch("123")
+	match.Name = "Burrito Galaxy"
+	match.PlayerCreds[4] = matches.PlayerCreds{PlayerUID: 4, LatestSnapshot: snapshot.ScanningData.Now}
+	if err := db.SaveMatch(match); err != nil {
+		t.Fatal(err)
+	}
+
+	// indexed, but the match itself is gone
+	if err := db.SaveAliases("456", map[int]string{1: "Expansive Brain"}); err != nil {
+		t.Fatal(err)
+	}
+
+	everyMatch := func(match *matches.Match) (matches.AccessProfile, bool) {
+		return matches.PermissiveAccessProfile(), true
+	}
+
+	playerDossier, err := BuildDossier(db, "expansive brain", everyMatch, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(playerDossier.Games) != 1 {
+		t.Fatalf("expected the broken match to be skipped, got %+v", playerDossier.Games)
+	}
+	game := playerDossier.Games[0]
+	if game.GameNumber != "123" || game.MatchName != "Burrito Galaxy" || game.PlayerUID != 4 {
+		t.Errorf("expected player 4 in game 123, got %+v", game)
+	}
+
+	noMatch := func(match *matches.Match) (matches.AccessProfile, bool) {
+		return matches.AccessProfile{}, false
+	}
+	playerDossier, err = BuildDossier(db, "expansive brain", noMatch, 10)
+	if err != nil || len(playerDossier.Games) != 0 {
+		t.Errorf("expected protected matches to be left out, got %+v (%v)", playerDossier.Games, err)
+	}
+}
@@ -2,17 +2,33 @@ package actions
 
 import (
 	"fmt"
+	"log"
+	"sort"
+	"strconv"
 
+	"go.albinodrought.com/neptunes-pride/internal/geometry"
 	"go.albinodrought.com/neptunes-pride/internal/matches"
+	"go.albinodrought.com/neptunes-pride/internal/matchstore"
 	"go.albinodrought.com/neptunes-pride/internal/notifications"
 	"go.albinodrought.com/neptunes-pride/internal/opsec"
 	"go.albinodrought.com/neptunes-pride/internal/types"
 )
 
+// mentionPlayer @mentions a player on Discord if we know their user ID, otherwise uses their alias
+func mentionPlayer(match *matches.Match, playerUID int, alias string) string {
+	if match.DiscordUserIDs != nil {
+		if discordID, ok := match.DiscordUserIDs[playerUID]; ok {
+			return fmt.Sprintf("<@%v>", discordID)
+		}
+	}
+	return alias
+}
+
 type notifiableThreat struct {
-	baseID string
-	threat *opsec.Threat
-	match  *matches.Match
+	baseID    string
+	threat    *opsec.Threat
+	match     *matches.Match
+	frontline bool
 }
 
 func (t *notifiableThreat) ID() string {
@@ -20,7 +36,7 @@ func (t *notifiableThreat) ID() string {
 }
 
 func (t *notifiableThreat) createMessage(fleetOwner string, targetStarOwner string) string {
-	return fmt.Sprintf(
+	message := fmt.Sprintf(
 		"%v's carrier %v is attacking %v's star %v with %v units",
 		fleetOwner,
 		t.threat.Fleet.Name,
@@ -28,6 +44,12 @@ func (t *notifiableThreat) createMessage(fleetOwner string, targetStarOwner stri
 		t.threat.TargetStar.Name,
 		t.threat.Fleet.Strength,
 	)
+
+	if t.frontline {
+		message += " on the frontline"
+	}
+
+	return message
 }
 
 func (t *notifiableThreat) Message() string {
@@ -57,17 +79,47 @@ func (t *notifiableThreat) DiscordMessage() string {
 	return t.createMessage(fleetOwner, targetStarOwner)
 }
 
-func CheckNotifiables(match *matches.Match, resp *types.APIResponse) []notifications.Notifiable {
+func CheckNotifiables(db matchstore.MatchStore, match *matches.Match, resp *types.APIResponse) []notifications.Notifiable {
 	notifiables := []notifications.Notifiable{}
 
+	frontlineStars := map[string]bool{}
+	frontline := geometry.ComputeTerritory(resp).Frontline(resp, AllyIDs(match, matches.PermissiveAccessProfile()))
+	for _, frontlineStar := range frontline {
+		frontlineStars[strconv.Itoa(frontlineStar.StarUID)] = true
+	}
+
 	threats := opsec.FindThreats(resp)
+
+	// frontline threats are the most urgent, send them first
+	sort.SliceStable(threats, func(i, j int) bool {
+		return frontlineStars[threats[i].TargetStarID] && !frontlineStars[threats[j].TargetStarID]
+	})
+
 	for i := range threats { // uses index to avoid loop variable overwriting
 		notifiables = append(notifiables, &notifiableThreat{
-			baseID: fmt.Sprintf("%v-%v", match.GameNumber, resp.ScanningData.Productions), // max 1 notification per production
-			threat: &threats[i],
-			match:  match,
+			baseID:    fmt.Sprintf("%v-%v", match.GameNumber, resp.ScanningData.Productions), // max 1 notification per production
+			threat:    &threats[i],
+			match:     match,
+			frontline: frontlineStars[threats[i].TargetStarID],
 		})
 	}
 
+	notifiables = append(notifiables, checkProductionNotifiables(match, resp)...)
+	notifiables = append(notifiables, checkVictoryNotifiables(match, resp)...)
+
+	// some notifications need to know what changed since the last poll
+	previous, err := PreviousMergedSnapshot(db, match)
+	if err == ErrNoSnapshotsLoaded {
+		previous = nil
+	} else if err != nil {
+		log.Printf("failed to load previous snapshot for match %v, skipping history-based notifications: %v", match.GameNumber, err)
+		previous = nil
+	}
+
+	notifiables = append(notifiables, checkResearchNotifiables(match, resp, previous)...)
+	notifiables = append(notifiables, checkTurnNotifiables(match, resp, previous)...)
+	notifiables = append(notifiables, checkAFKNotifiables(match, resp, previous)...)
+	notifiables = append(notifiables, checkDiplomacyNotifiables(match, resp, previous)...)
+
 	return notifiables
 }
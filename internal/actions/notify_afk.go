@@ -0,0 +1,105 @@
+package actions
+
+import (
+	"fmt"
+	"strings"
+
+	"go.albinodrought.com/neptunes-pride/internal/afk"
+	"go.albinodrought.com/neptunes-pride/internal/matches"
+	"go.albinodrought.com/neptunes-pride/internal/notifications"
+	"go.albinodrought.com/neptunes-pride/internal/types"
+)
+
+// landGrabsPerNotification caps how many stars are listed in an AFK notification
+const landGrabsPerNotification = 3
+
+type notifiableAFK struct {
+	match     *matches.Match
+	event     afk.Event
+	landGrabs []afk.LandGrab
+}
+
+func (n *notifiableAFK) ID() string {
+	return fmt.Sprintf("afk-%v-%v-%v-%v", n.match.GameNumber, n.event.PlayerUID, n.event.Kind, n.event.MissedTurns)
+}
+
+func (n *notifiableAFK) createMessage(mention func(uid int, alias string) string) string {
+	message := ""
+	switch n.event.Kind {
+	case afk.KindAI:
+		message = fmt.Sprintf("%v was taken over by AI", n.event.PlayerAlias)
+	case afk.KindInactive:
+		message = fmt.Sprintf("%v is now inactive", n.event.PlayerAlias)
+	case afk.KindConceded:
+		message = fmt.Sprintf("%v conceded", n.event.PlayerAlias)
+	case afk.KindMissedTurns:
+		message = fmt.Sprintf("%v has missed %v turns", n.event.PlayerAlias, n.event.MissedTurns)
+	}
+
+	return message + landGrabsMessage(n.landGrabs, mention)
+}
+
+// landGrabsMessage lists the best stars to take from an AFK player, appended to a notification
+func landGrabsMessage(landGrabs []afk.LandGrab, mention func(uid int, alias string) string) string {
+	if len(landGrabs) == 0 {
+		return ""
+	}
+
+	grabs := []string{}
+	for i, landGrab := range landGrabs {
+		if i >= landGrabsPerNotification {
+			break
+		}
+		grabs = append(grabs, fmt.Sprintf(
+			"%v (%v ships, %v in %v ticks with %v ships)",
+			landGrab.StarName,
+			landGrab.Garrison,
+			mention(landGrab.ByUID, landGrab.ByAlias),
+			landGrab.Ticks,
+			landGrab.ShipsNeeded,
+		))
+	}
+
+	return fmt.Sprintf(", %v stars up for grabs: %v", len(landGrabs), strings.Join(grabs, ", "))
+}
+
+func (n *notifiableAFK) Message() string {
+	return n.createMessage(func(uid int, alias string) string {
+		return alias
+	})
+}
+
+func (n *notifiableAFK) DiscordMessage() string {
+	return n.createMessage(func(uid int, alias string) string {
+		return mentionPlayer(n.match, uid, alias)
+	})
+}
+
+func checkAFKNotifiables(match *matches.Match, resp *types.APIResponse, previous *types.APIResponse) []notifications.Notifiable {
+	notifiables := []notifications.Notifiable{}
+
+	if previous == nil {
+		return notifiables
+	}
+
+	accessProfile := matches.PermissiveAccessProfile()
+	allies := map[int]bool{}
+	for _, allyID := range AllyIDs(match, accessProfile) {
+		allies[allyID] = true
+	}
+
+	for _, event := range afk.Detect(previous, resp, afk.DefaultMissedTurnsThreshold) {
+		if event.Kind == afk.KindMissedTurns && allies[event.PlayerUID] && notifiesMissedTurns(resp) {
+			// the missed turn notification already lists the land grabs
+			continue
+		}
+
+		notifiables = append(notifiables, &notifiableAFK{
+			match:     match,
+			event:     event,
+			landGrabs: FindLandGrabs(match, accessProfile, resp, event.PlayerUID),
+		})
+	}
+
+	return notifiables
+}
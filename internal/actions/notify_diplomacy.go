@@ -0,0 +1,68 @@
+package actions
+
+import (
+	"fmt"
+
+	"go.albinodrought.com/neptunes-pride/internal/diplomacy"
+	"go.albinodrought.com/neptunes-pride/internal/matches"
+	"go.albinodrought.com/neptunes-pride/internal/notifications"
+	"go.albinodrought.com/neptunes-pride/internal/types"
+)
+
+type notifiableDiplomacy struct {
+	match  *matches.Match
+	change diplomacy.Change
+	// tick the change was noticed, countdowns end a fixed number of ticks after it
+	tick int
+}
+
+func (n *notifiableDiplomacy) ID() string {
+	if n.change.Kind == diplomacy.ChangeCountdown {
+		// the tick war breaks out is stable between polls
+		return fmt.Sprintf("war-countdown-%v-%v-%v-%v", n.match.GameNumber, n.change.FromUID, n.change.ToUID, n.tick+n.change.CountdownToWar)
+	}
+	// the same change can be seen again by a later poll, so the tick it was noticed isn't part of it
+	return fmt.Sprintf("war-state-%v-%v-%v-%v-%v", n.match.GameNumber, n.change.FromUID, n.change.ToUID, n.change.PreviousState, n.change.State)
+}
+
+func (n *notifiableDiplomacy) createMessage(from string, to string) string {
+	if n.change.Kind == diplomacy.ChangeCountdown {
+		return fmt.Sprintf("%v and %v will be at war in %v ticks", from, to, n.change.CountdownToWar)
+	}
+	return fmt.Sprintf(
+		"%v and %v went from %v to %v",
+		from,
+		to,
+		diplomacy.StateName(n.change.PreviousState),
+		n.change.StateName,
+	)
+}
+
+func (n *notifiableDiplomacy) Message() string {
+	return n.createMessage(n.change.FromAlias, n.change.ToAlias)
+}
+
+func (n *notifiableDiplomacy) DiscordMessage() string {
+	return n.createMessage(
+		mentionPlayer(n.match, n.change.FromUID, n.change.FromAlias),
+		mentionPlayer(n.match, n.change.ToUID, n.change.ToAlias),
+	)
+}
+
+func checkDiplomacyNotifiables(match *matches.Match, resp *types.APIResponse, previous *types.APIResponse) []notifications.Notifiable {
+	notifiables := []notifications.Notifiable{}
+
+	if previous == nil {
+		return notifiables
+	}
+
+	for _, change := range diplomacy.Changes(previous, resp) {
+		notifiables = append(notifiables, &notifiableDiplomacy{
+			match:  match,
+			change: change,
+			tick:   resp.ScanningData.Tick,
+		})
+	}
+
+	return notifiables
+}
@@ -0,0 +1,30 @@
+package actions
+
+import (
+	"testing"
+
+	"go.albinodrought.com/neptunes-pride/internal/diplomacy"
+	"go.albinodrought.com/neptunes-pride/internal/matches"
+)
+
+func TestDiplomacyNotifiedOnce(t *testing.T) {
+	match := matches.NewMatch("123")
+	change := diplomacy.Change{
+		Relation:      diplomacy.Relation{FromUID: 1, ToUID: 4, State: 3},
+		Kind:          diplomacy.ChangeState,
+		PreviousState: 0,
+	}
+
+	// a later poll that sees the same change again
+	first := &notifiableDiplomacy{match: match, change: change, tick: 10}
+	again := &notifiableDiplomacy{match: match, change: change, tick: 12}
+	if first.ID() != again.ID() {
+		t.Errorf("expected the same change to keep its ID, got %v and %v", first.ID(), again.ID())
+	}
+
+	back := change
+	back.State, back.PreviousState = change.PreviousState, change.State
+	if (&notifiableDiplomacy{match: match, change: back, tick: 14}).ID() == first.ID() {
+		t.Errorf("expected changing back to notify again")
+	}
+}
@@ -0,0 +1,73 @@
+package actions
+
+import (
+	"fmt"
+
+	"go.albinodrought.com/neptunes-pride/internal/forecast"
+	"go.albinodrought.com/neptunes-pride/internal/matches"
+	"go.albinodrought.com/neptunes-pride/internal/notifications"
+	"go.albinodrought.com/neptunes-pride/internal/types"
+)
+
+// productionWarningTicks is how close the next production has to be before allies are told what they'll have
+const productionWarningTicks = 1
+
+type notifiableProduction struct {
+	match       *matches.Match
+	productions int
+	ticks       int
+	forecast    forecast.PlayerForecast
+}
+
+func (n *notifiableProduction) ID() string {
+	return fmt.Sprintf("production-%v-%v-%v", n.match.GameNumber, n.productions, n.forecast.PlayerUID)
+}
+
+func (n *notifiableProduction) createMessage(player string) string {
+	ticks := "ticks"
+	if n.ticks == 1 {
+		ticks = "tick"
+	}
+
+	return fmt.Sprintf(
+		"%v: production in %v %v, you'll have $%v and %v new ships",
+		player,
+		n.ticks,
+		ticks,
+		n.forecast.CashAtProduction,
+		n.forecast.Ships,
+	)
+}
+
+func (n *notifiableProduction) Message() string {
+	return n.createMessage(n.forecast.PlayerAlias)
+}
+
+func (n *notifiableProduction) DiscordMessage() string {
+	return n.createMessage(mentionPlayer(n.match, n.forecast.PlayerUID, n.forecast.PlayerAlias))
+}
+
+func checkProductionNotifiables(match *matches.Match, resp *types.APIResponse) []notifications.Notifiable {
+	notifiables := []notifications.Notifiable{}
+
+	predicted := forecast.Predict(resp)
+	if predicted.TicksUntilProduction > productionWarningTicks || resp.ScanningData.Paused || resp.ScanningData.GameOver == types.GameOverYes {
+		return notifiables
+	}
+
+	for _, allyID := range AllyIDs(match, matches.PermissiveAccessProfile()) {
+		playerForecast, ok := predicted.ForPlayer(allyID)
+		if !ok || !playerForecast.CashKnown {
+			continue
+		}
+
+		notifiables = append(notifiables, &notifiableProduction{
+			match:       match,
+			productions: predicted.Productions,
+			ticks:       predicted.TicksUntilProduction,
+			forecast:    playerForecast,
+		})
+	}
+
+	return notifiables
+}
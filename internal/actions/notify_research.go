@@ -0,0 +1,90 @@
+package actions
+
+import (
+	"fmt"
+
+	"go.albinodrought.com/neptunes-pride/internal/matches"
+	"go.albinodrought.com/neptunes-pride/internal/notifications"
+	"go.albinodrought.com/neptunes-pride/internal/research"
+	"go.albinodrought.com/neptunes-pride/internal/types"
+)
+
+// researchWarningTicks is how close an ally's research has to be before everyone is told
+const researchWarningTicks = 2
+
+type notifiableResearchETA struct {
+	match *matches.Match
+	eta   research.ETA
+}
+
+func (n *notifiableResearchETA) ID() string {
+	// max 1 notification per tech level
+	return fmt.Sprintf("research-eta-%v-%v-%v-%v", n.match.GameNumber, n.eta.PlayerUID, n.eta.Tech, n.eta.TargetLevel)
+}
+
+func (n *notifiableResearchETA) createMessage(player string) string {
+	ticks := "ticks"
+	if n.eta.Ticks == 1 {
+		ticks = "tick"
+	}
+
+	return fmt.Sprintf("%v finishes %v %v in %v %v", player, research.NiceName(n.eta.Tech), n.eta.TargetLevel, n.eta.Ticks, ticks)
+}
+
+func (n *notifiableResearchETA) Message() string {
+	return n.createMessage(n.eta.PlayerAlias)
+}
+
+func (n *notifiableResearchETA) DiscordMessage() string {
+	return n.createMessage(mentionPlayer(n.match, n.eta.PlayerUID, n.eta.PlayerAlias))
+}
+
+type notifiableLevelUp struct {
+	match   *matches.Match
+	levelUp research.LevelUp
+}
+
+func (n *notifiableLevelUp) ID() string {
+	return fmt.Sprintf("research-level-up-%v-%v-%v-%v", n.match.GameNumber, n.levelUp.PlayerUID, n.levelUp.Tech, n.levelUp.Level)
+}
+
+func (n *notifiableLevelUp) Message() string {
+	return fmt.Sprintf("%v just gained %v %v", n.levelUp.PlayerAlias, research.NiceName(n.levelUp.Tech), n.levelUp.Level)
+}
+
+func checkResearchNotifiables(match *matches.Match, resp *types.APIResponse, previous *types.APIResponse) []notifications.Notifiable {
+	notifiables := []notifications.Notifiable{}
+
+	allies := map[int]bool{}
+	for _, allyID := range AllyIDs(match, matches.PermissiveAccessProfile()) {
+		allies[allyID] = true
+	}
+
+	for _, eta := range research.ETAs(resp) {
+		if !allies[eta.PlayerUID] || eta.Ticks > researchWarningTicks {
+			continue
+		}
+
+		notifiables = append(notifiables, &notifiableResearchETA{
+			match: match,
+			eta:   eta,
+		})
+	}
+
+	if previous == nil {
+		return notifiables
+	}
+
+	for _, levelUp := range research.LevelUps(previous, resp) {
+		if allies[levelUp.PlayerUID] {
+			continue
+		}
+
+		notifiables = append(notifiables, &notifiableLevelUp{
+			match:   match,
+			levelUp: levelUp,
+		})
+	}
+
+	return notifiables
+}
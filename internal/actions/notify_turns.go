@@ -0,0 +1,122 @@
+package actions
+
+import (
+	"fmt"
+	"time"
+
+	"go.albinodrought.com/neptunes-pride/internal/afk"
+	"go.albinodrought.com/neptunes-pride/internal/matches"
+	"go.albinodrought.com/neptunes-pride/internal/notifications"
+	"go.albinodrought.com/neptunes-pride/internal/turns"
+	"go.albinodrought.com/neptunes-pride/internal/types"
+)
+
+// turnReminderWindow is how close to the deadline allies get pinged about their turn
+const turnReminderWindow = 2 * time.Hour
+
+type notifiableNotReady struct {
+	match    *matches.Match
+	player   types.Player
+	deadline int64
+	left     time.Duration
+}
+
+func (n *notifiableNotReady) ID() string {
+	// max 1 notification per turn
+	return fmt.Sprintf("turn-not-ready-%v-%v-%v", n.match.GameNumber, n.deadline, n.player.UID)
+}
+
+func (n *notifiableNotReady) createMessage(player string) string {
+	return fmt.Sprintf("%v hasn't submitted their turn, %v left", player, n.left.Round(time.Minute))
+}
+
+func (n *notifiableNotReady) Message() string {
+	return n.createMessage(n.player.Alias)
+}
+
+func (n *notifiableNotReady) DiscordMessage() string {
+	return n.createMessage(mentionPlayer(n.match, n.player.UID, n.player.Alias))
+}
+
+type notifiableMissedTurn struct {
+	match  *matches.Match
+	missed turns.MissedTurns
+	// landGrabs are set once the player counts as AFK
+	landGrabs []afk.LandGrab
+}
+
+func (n *notifiableMissedTurn) ID() string {
+	return fmt.Sprintf("turn-missed-%v-%v-%v", n.match.GameNumber, n.missed.PlayerUID, n.missed.MissedTurns)
+}
+
+func (n *notifiableMissedTurn) createMessage(mention func(uid int, alias string) string) string {
+	message := fmt.Sprintf("%v missed a turn, %v missed so far", mention(n.missed.PlayerUID, n.missed.PlayerAlias), n.missed.MissedTurns)
+	return message + landGrabsMessage(n.landGrabs, mention)
+}
+
+func (n *notifiableMissedTurn) Message() string {
+	return n.createMessage(func(uid int, alias string) string {
+		return alias
+	})
+}
+
+func (n *notifiableMissedTurn) DiscordMessage() string {
+	return n.createMessage(func(uid int, alias string) string {
+		return mentionPlayer(n.match, uid, alias)
+	})
+}
+
+// notifiesMissedTurns returns true if allies' missed turns are notified for this snapshot
+func notifiesMissedTurns(resp *types.APIResponse) bool {
+	return turns.IsTurnBased(&resp.ScanningData) && resp.ScanningData.GameOver != types.GameOverYes
+}
+
+func checkTurnNotifiables(match *matches.Match, resp *types.APIResponse, previous *types.APIResponse) []notifications.Notifiable {
+	notifiables := []notifications.Notifiable{}
+
+	data := &resp.ScanningData
+	if !notifiesMissedTurns(resp) {
+		return notifiables
+	}
+
+	allyIDs := AllyIDs(match, matches.PermissiveAccessProfile())
+
+	deadline := turns.Deadline(data)
+	left := time.Duration(deadline-data.Now) * time.Millisecond
+	if deadline > 0 && left > 0 && left <= turnReminderWindow {
+		for _, player := range turns.NotReady(resp, allyIDs) {
+			notifiables = append(notifiables, &notifiableNotReady{
+				match:    match,
+				player:   player,
+				deadline: deadline,
+				left:     left,
+			})
+		}
+	}
+
+	if previous == nil {
+		return notifiables
+	}
+
+	allies := map[int]bool{}
+	for _, allyID := range allyIDs {
+		allies[allyID] = true
+	}
+
+	for _, missed := range turns.MissedTurnIncreases(previous, resp) {
+		if !allies[missed.PlayerUID] {
+			continue
+		}
+
+		notification := &notifiableMissedTurn{
+			match:  match,
+			missed: missed,
+		}
+		if missed.MissedTurns >= afk.DefaultMissedTurnsThreshold {
+			notification.landGrabs = FindLandGrabs(match, matches.PermissiveAccessProfile(), resp, missed.PlayerUID)
+		}
+		notifiables = append(notifiables, notification)
+	}
+
+	return notifiables
+}
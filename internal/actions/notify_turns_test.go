@@ -0,0 +1,52 @@
+package actions
+
+import (
+	"strings"
+	"testing"
+
+	"go.albinodrought.com/neptunes-pride/internal/fixtures"
+	"go.albinodrought.com/neptunes-pride/internal/matches"
+	"go.albinodrought.com/neptunes-pride/internal/types"
+)
+
+func TestMissedTurnNotifiedOnce(t *testing.T) {
+	match := matches.NewMatch("123")
+	match.PlayerCreds[4] = matches.PlayerCreds{PlayerUID: 4, PlayerAlias: "Aburrido"}
+	match.PlayerCreds[5] = matches.PlayerCreds{PlayerUID: 5, PlayerAlias: "Burrito"}
+
+	missTurns := func(snapshot *types.APIResponse, missedTurns int) *types.APIResponse {
+		snapshot.ScanningData.TurnBased = 1
+		for _, uid := range []string{"1", "4"} {
+			player := snapshot.ScanningData.Players[uid]
+			player.MissedTurns = missedTurns
+			snapshot.ScanningData.Players[uid] = player
+		}
+		return snapshot
+	}
+	previous := missTurns(fixtures.Load(t, "../opsec/aburrido.json"), 1)
+	current := missTurns(fixtures.Load(t, "../opsec/aburrido.json"), 2)
+
+	notified := map[int][]string{}
+	for _, notifiable := range append(checkTurnNotifiables(match, current, previous), checkAFKNotifiables(match, current, previous)...) {
+		switch n := notifiable.(type) {
+		case *notifiableMissedTurn:
+			notified[n.missed.PlayerUID] = append(notified[n.missed.PlayerUID], n.Message())
+		case *notifiableAFK:
+			notified[n.event.PlayerUID] = append(notified[n.event.PlayerUID], n.Message())
+		}
+	}
+
+	// ally 4 gets the turn notification with land grabs, the enemy only the AFK one
+	if len(notified[4]) != 1 {
+		t.Fatalf("expected a single missed turn notification for the ally, got %v", notified[4])
+	}
+	if !strings.HasPrefix(notified[4][0], "Expansive Brain missed a turn, 2 missed so far, 25 stars up for grabs: ") {
+		t.Errorf("expected the ally's stars to be up for grabs, got %v", notified[4][0])
+	}
+	if !strings.Contains(notified[4][0], "Rotanev (15 ships, Chiquito Buritto in 11 ticks with 25 ships)") {
+		t.Errorf("expected the other ally to grab the nearest star, got %v", notified[4][0])
+	}
+	if len(notified[1]) != 1 || !strings.Contains(notified[1][0], "has missed 2 turns") {
+		t.Errorf("expected a single AFK notification for the enemy, got %v", notified[1])
+	}
+}
@@ -0,0 +1,61 @@
+package actions
+
+import (
+	"fmt"
+
+	"go.albinodrought.com/neptunes-pride/internal/matches"
+	"go.albinodrought.com/neptunes-pride/internal/notifications"
+	"go.albinodrought.com/neptunes-pride/internal/types"
+	"go.albinodrought.com/neptunes-pride/internal/victory"
+)
+
+type notifiableVictoryThreshold struct {
+	match           *matches.Match
+	player          victory.PlayerProgress
+	threshold       int
+	starsForVictory int
+}
+
+func (n *notifiableVictoryThreshold) ID() string {
+	// max 1 notification per threshold
+	return fmt.Sprintf("victory-%v-%v-%v", n.match.GameNumber, n.player.PlayerUID, n.threshold)
+}
+
+func (n *notifiableVictoryThreshold) createMessage(player string) string {
+	return fmt.Sprintf(
+		"%v has %v of %v stars needed for victory (%.0f%%)",
+		player,
+		n.player.Stars,
+		n.starsForVictory,
+		n.player.Percent,
+	)
+}
+
+func (n *notifiableVictoryThreshold) Message() string {
+	return n.createMessage(n.player.PlayerAlias)
+}
+
+func (n *notifiableVictoryThreshold) DiscordMessage() string {
+	return n.createMessage(mentionPlayer(n.match, n.player.PlayerUID, n.player.PlayerAlias))
+}
+
+func checkVictoryNotifiables(match *matches.Match, resp *types.APIResponse) []notifications.Notifiable {
+	notifiables := []notifications.Notifiable{}
+
+	progress := victory.Analyze(resp, nil, nil)
+	for _, player := range progress.Players {
+		threshold, ok := player.Threshold(victory.DefaultThresholds)
+		if !ok {
+			continue
+		}
+
+		notifiables = append(notifiables, &notifiableVictoryThreshold{
+			match:           match,
+			player:          player,
+			threshold:       threshold,
+			starsForVictory: progress.StarsForVictory,
+		})
+	}
+
+	return notifiables
+}
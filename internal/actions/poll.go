@@ -7,20 +7,58 @@ import (
 	"strconv"
 	"time"
 
+	"go.albinodrought.com/neptunes-pride/internal/matches"
 	"go.albinodrought.com/neptunes-pride/internal/matchstore"
 	"go.albinodrought.com/neptunes-pride/internal/multierror"
 	"go.albinodrought.com/neptunes-pride/internal/npapi"
+	"go.albinodrought.com/neptunes-pride/internal/turns"
 	"go.albinodrought.com/neptunes-pride/internal/types"
 )
 
 type PollOptions struct {
 	Force         bool
 	MinTimePassed time.Duration
+	// TurnResolutionDelay is how long to wait after a turn-based deadline before polling,
+	// giving NP time to resolve the turn
+	TurnResolutionDelay time.Duration
 }
 
 var DefaultPollOptions = PollOptions{
-	Force:         false,
-	MinTimePassed: 15 * time.Minute,
+	Force:               false,
+	MinTimePassed:       15 * time.Minute,
+	TurnResolutionDelay: time.Minute,
+}
+
+// turnPollTime is when a turn-based match should next be polled, zero if it isn't turn-based
+func turnPollTime(match *matches.Match, pollOptions *PollOptions) time.Time {
+	if match.TurnDeadline <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, match.TurnDeadline*int64(time.Millisecond)).Add(pollOptions.TurnResolutionDelay)
+}
+
+// NextTurnPoll finds the soonest time a turn-based match should be polled,
+// false if no unfinished match is turn-based
+func NextTurnPoll(db matchstore.MatchStore, pollOptions *PollOptions) (time.Time, bool, error) {
+	if pollOptions == nil {
+		pollOptions = &DefaultPollOptions
+	}
+
+	next := time.Time{}
+	err := db.EachMatch(true, func(gameNumber string, match *matches.Match) {
+		if match.Finished {
+			return
+		}
+		pollTime := turnPollTime(match, pollOptions)
+		if pollTime.IsZero() {
+			return
+		}
+		if next.IsZero() || pollTime.Before(next) {
+			next = pollTime
+		}
+	})
+
+	return next, !next.IsZero(), err
 }
 
 type PollResult struct {
@@ -56,7 +94,8 @@ func PollMatch(ctx context.Context, db matchstore.MatchStore, client npapi.Neptu
 	}
 
 	if match.Finished {
-		return pollResult, nil
+		// finished matches aren't polled again, but a report that failed to generate is retried
+		return pollResult, generateMissingReport(db, match)
 	}
 
 	if len(match.PlayerCreds) == 0 {
@@ -66,8 +105,13 @@ func PollMatch(ctx context.Context, db matchstore.MatchStore, client npapi.Neptu
 
 	pollErrors := []error{}
 
+	// poll right after a turn resolves, even if we polled recently
+	turnPoll := turnPollTime(match, pollOptions)
+	turnResolved := !turnPoll.IsZero() && time.Now().After(turnPoll)
+
 	for i, config := range match.PlayerCreds {
-		if !pollOptions.Force && time.Since(config.LastPoll) < pollOptions.MinTimePassed {
+		resolvedSinceLastPoll := turnResolved && config.LastPoll.Before(turnPoll)
+		if !pollOptions.Force && !resolvedSinceLastPoll && time.Since(config.LastPoll) < pollOptions.MinTimePassed {
 			log.Printf("recently polled game %v user %v \"%v\" on %v", gameNumber, config.PlayerUID, config.PlayerAlias, config.LastPoll)
 			continue
 		}
@@ -125,6 +169,7 @@ func PollMatch(ctx context.Context, db matchstore.MatchStore, client npapi.Neptu
 		config.LastPoll = time.Now()
 		config.LatestSnapshot = resp.ScanningData.Now
 		match.PlayerCreds[i] = config
+		match.TurnDeadline = turns.Deadline(&resp.ScanningData)
 
 		if resp.ScanningData.GameOver == types.GameOverYes {
 			match.Finished = true
@@ -145,9 +190,38 @@ func PollMatch(ctx context.Context, db matchstore.MatchStore, client npapi.Neptu
 		})
 	}
 
+	if match.Finished {
+		// the match just finished, it won't be polled again
+		if err := generateMissingReport(db, match); err != nil {
+			pollErrors = append(pollErrors, err)
+		}
+	}
+
 	return pollResult, multierror.Optional(pollErrors)
 }
 
+// generateMissingReport stores a finished match's end-of-game report, unless it already has one
+func generateMissingReport(db matchstore.MatchStore, match *matches.Match) error {
+	_, err := db.FindReport(match.GameNumber)
+	if err == nil {
+		return nil
+	}
+
+	if err == matchstore.ErrReportNotFound {
+		_, err = GenerateReport(db, match)
+	}
+	if err != nil {
+		return PollError{
+			Base:       err,
+			GameNumber: match.GameNumber,
+			Message:    "failed generating end-of-game report",
+		}
+	}
+
+	log.Printf("generated end-of-game report for game %v", match.GameNumber)
+	return nil
+}
+
 func PollMatches(ctx context.Context, db matchstore.MatchStore, client npapi.NeptunesPrideClient, gameNumbers []string, pollOptions *PollOptions) (map[string]PollResult, error) {
 	pollResults := make(map[string]PollResult)
 	pollErrors := []error{}
@@ -0,0 +1,115 @@
+package actions
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"go.albinodrought.com/neptunes-pride/internal/fixtures"
+	"go.albinodrought.com/neptunes-pride/internal/matches"
+	"go.albinodrought.com/neptunes-pride/internal/matchstore"
+	"go.albinodrought.com/neptunes-pride/internal/notifications"
+	"go.albinodrought.com/neptunes-pride/internal/types"
+)
+
+type countingSink struct {
+	sent int
+}
+
+func (sink *countingSink) Send(ctx context.Context, notifiable notifications.Notifiable) error {
+	sink.sent++
+	return nil
+}
+
+// TestPollEphemeral runs a poll and its notifications the way `serve --ephemeral` does
+func TestPollEphemeral(t *testing.T) {
+	missTurns := func(snapshot *types.APIResponse, missedTurns int) *types.APIResponse {
+		snapshot.ScanningData.TurnBased = 1
+		uid := strconv.Itoa(snapshot.ScanningData.PlayerUID)
+		player := snapshot.ScanningData.Players[uid]
+		player.MissedTurns = missedTurns
+		snapshot.ScanningData.Players[uid] = player
+		return snapshot
+	}
+
+	db := matchstore.OpenMemory()
+	guard := notifications.NewMemoryGuard()
+	client := &stateClient{missTurns(fixtures.Load(t, "../opsec/burrito.json"), 0)}
+	playerUID := client.resp.ScanningData.PlayerUID
+
+	match, _ := db.FindOrCreateMatch("123")
+	match.PlayerCreds[playerUID] = matches.PlayerCreds{PlayerUID: playerUID, APIKey: "abc"}
+	if err := db.SaveMatch(match); err != nil {
+		t.Fatal(err)
+	}
+
+	// the player misses a turn between polls
+	for _, missedTurns := range []int{0, 1} {
+		client.resp = missTurns(fixtures.Load(t, "../opsec/burrito.json"), missedTurns)
+		client.resp.ScanningData.Now += int64(missedTurns) * 60 * 60 * 1000
+
+		pollResults, err := PollAllMatches(context.Background(), db, client, &PollOptions{Force: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !pollResults["123"].Changed {
+			t.Fatalf("expected the poll to store a snapshot, got %+v", pollResults)
+		}
+	}
+
+	match, _ = db.FindMatchOrFail("123")
+	if match.PlayerCreds[playerUID].LatestSnapshot != client.resp.ScanningData.Now {
+		t.Errorf("expected the latest snapshot to be recorded, got %+v", match.PlayerCreds[playerUID])
+	}
+
+	merged, err := MergedSnapshot(db, match, matches.PermissiveAccessProfile(), map[string]string{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notifiables := CheckNotifiables(db, match, merged)
+	missedTurn := []notifications.Notifiable{}
+	for _, notifiable := range notifiables {
+		if _, ok := notifiable.(*notifiableMissedTurn); ok {
+			missedTurn = append(missedTurn, notifiable)
+		}
+	}
+	if len(missedTurn) != 1 {
+		t.Fatalf("expected a missed turn notification, got %+v", notifiables)
+	}
+
+	// the guard remembers what was sent, for as long as the process runs
+	sink := &countingSink{}
+	for i := 0; i < 2; i++ {
+		if err := notifications.SendGuarded(context.Background(), guard, missedTurn, []notifications.Sink{sink}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if sink.sent != 1 {
+		t.Errorf("expected the notification to be sent once, got %v", sink.sent)
+	}
+}
+
+// TestPollFinishedReport generates the report of a finished match if an earlier poll failed to
+func TestPollFinishedReport(t *testing.T) {
+	db := matchstore.OpenMemory()
+	snapshot := fixtures.Load(t, "../opsec/burrito.json")
+	if err := db.SaveSnapshot("123", snapshot); err != nil {
+		t.Fatal(err)
+	}
+
+	playerUID := snapshot.ScanningData.PlayerUID
+	match, _ := db.FindOrCreateMatch("123")
+	match.PlayerCreds[playerUID] = matches.PlayerCreds{PlayerUID: playerUID, APIKey: "abc", LatestSnapshot: snapshot.ScanningData.Now}
+	match.Finished = true
+	if err := db.SaveMatch(match); err != nil {
+		t.Fatal(err)
+	}
+
+	// no client, finished matches aren't polled
+	if _, err := PollMatch(context.Background(), db, nil, "123", &PollOptions{Force: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.FindReport("123"); err != nil {
+		t.Errorf("expected the missing report to be generated, got %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package actions
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	"go.albinodrought.com/neptunes-pride/internal/matches"
+	"go.albinodrought.com/neptunes-pride/internal/matchstore"
+	"go.albinodrought.com/neptunes-pride/internal/opsec"
+	"go.albinodrought.com/neptunes-pride/internal/report"
+	"go.albinodrought.com/neptunes-pride/internal/types"
+)
+
+var ErrNothingToReport = errors.New("no snapshots to build a report from")
+
+type storedSnapshot struct {
+	playerUID int
+	time      int64
+}
+
+// GenerateReport builds an end-of-game report from every stored snapshot and saves it.
+// Snapshots polled for the same tick are merged before being recorded.
+func GenerateReport(db matchstore.MatchStore, match *matches.Match) ([]byte, error) {
+	stored := []storedSnapshot{}
+	for _, allyID := range AllyIDs(match, matches.PermissiveAccessProfile()) {
+		snapshotTimes, err := db.ListSnapshotTimes(match.GameNumber, allyID, math.MaxInt32)
+		if err == matchstore.ErrSnapshotNotFound || err == matchstore.ErrMatchNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for _, snapshotTime := range snapshotTimes {
+			stored = append(stored, storedSnapshot{allyID, snapshotTime})
+		}
+	}
+
+	// oldest first, so snapshots for the same tick are next to each other
+	sort.Slice(stored, func(i, j int) bool {
+		return stored[i].time < stored[j].time
+	})
+
+	builder := report.NewBuilder()
+	pending := []*types.APIResponse{}
+	flush := func() {
+		if len(pending) > 0 {
+			builder.Record(opsec.Merge(pending...))
+			pending = []*types.APIResponse{}
+		}
+	}
+
+	for _, s := range stored {
+		snapshot, err := db.FindSnapshot(match.GameNumber, s.playerUID, s.time)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(pending) > 0 && pending[0].ScanningData.Tick != snapshot.ScanningData.Tick {
+			flush()
+		}
+		pending = append(pending, snapshot)
+	}
+	flush()
+
+	built, ok := builder.Build(match.GameNumber, match.Name)
+	if !ok {
+		return nil, ErrNothingToReport
+	}
+
+	html, err := built.HTML()
+	if err != nil {
+		return nil, err
+	}
+
+	return html, db.SaveReport(match.GameNumber, html)
+}
@@ -0,0 +1,51 @@
+package actions
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"go.albinodrought.com/neptunes-pride/internal/fixtures"
+	"go.albinodrought.com/neptunes-pride/internal/matches"
+	"go.albinodrought.com/neptunes-pride/internal/matchstore"
+	"go.albinodrought.com/neptunes-pride/internal/npapi"
+	"go.albinodrought.com/neptunes-pride/internal/types"
+)
+
+type stateClient struct {
+	resp *types.APIResponse
+}
+
+func (client *stateClient) State(ctx context.Context, request *npapi.Request) (*types.APIResponse, error) {
+	return client.resp, nil
+}
+
+func TestSetCredentialsAfterImport(t *testing.T) {
+	from := matchstore.OpenMemory()
+	match, _ := from.FindOrCreateMatch("123")
+	match.PlayerCreds[4] = matches.PlayerCreds{PlayerUID: 4, APIKey: "abc"}
+	if err := from.SaveMatch(match); err != nil {
+		t.Fatal(err)
+	}
+
+	// credentials are stripped by default
+	archive := &bytes.Buffer{}
+	if _, err := matchstore.Export(from, "123", archive, nil); err != nil {
+		t.Fatal(err)
+	}
+	db := matchstore.OpenMemory()
+	if _, err := matchstore.Import(db, bytes.NewReader(archive.Bytes()), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	client := &stateClient{fixtures.Load(t, "../opsec/burrito.json")}
+	if err := SetCredentials(context.Background(), db, client, "123", "def"); err != nil {
+		t.Fatal(err)
+	}
+
+	imported, _ := db.FindMatchOrFail("123")
+	playerUID := client.resp.ScanningData.PlayerUID
+	if imported.PlayerCreds[playerUID].APIKey != "def" {
+		t.Errorf("expected the new key for player %v, got %+v", playerUID, imported.PlayerCreds)
+	}
+}
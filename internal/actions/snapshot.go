@@ -0,0 +1,139 @@
+package actions
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.albinodrought.com/neptunes-pride/internal/matches"
+	"go.albinodrought.com/neptunes-pride/internal/matchstore"
+	"go.albinodrought.com/neptunes-pride/internal/opsec"
+	"go.albinodrought.com/neptunes-pride/internal/types"
+)
+
+var ErrNoSnapshotsLoaded = errors.New("no snapshots loaded")
+
+type MergeOptions struct {
+	// Extrapolate projects every snapshot forward to Now before merging
+	Extrapolate bool
+	// Now defaults to the current time
+	Now time.Time
+}
+
+var DefaultMergeOptions = MergeOptions{
+	Extrapolate: false,
+}
+
+// AllyIDs lists the players we have credentials for that the access profile can view
+func AllyIDs(match *matches.Match, accessProfile matches.AccessProfile) []int {
+	allyIDs := []int{}
+	for _, creds := range match.PlayerCreds {
+		if accessProfile.CanViewPlayerID(creds.PlayerUID) {
+			allyIDs = append(allyIDs, creds.PlayerUID)
+		}
+	}
+	sort.Ints(allyIDs)
+	return allyIDs
+}
+
+// MergedSnapshot loads the latest snapshot of every player visible to the access profile
+// and merges them together.
+// Overrides map stringified player IDs to a specific snapshot time, or "latest".
+func MergedSnapshot(db matchstore.MatchStore, match *matches.Match, accessProfile matches.AccessProfile, overrides map[string]string, mergeOptions *MergeOptions) (*types.APIResponse, error) {
+	if mergeOptions == nil {
+		mergeOptions = &DefaultMergeOptions
+	}
+
+	now := mergeOptions.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	ignoredSnapshots := 0
+	snapshotsToLoad := make(map[int]int64, len(match.PlayerCreds))
+	for _, creds := range match.PlayerCreds {
+		if !accessProfile.CanViewPlayerID(creds.PlayerUID) {
+			continue
+		}
+
+		if creds.PollingDisabled {
+			// these were messing up the "Last Polled" time, only load these if specified by user below
+			snapshotsToLoad[creds.PlayerUID] = 0
+		} else {
+			snapshotsToLoad[creds.PlayerUID] = creds.LatestSnapshot
+		}
+
+		customSnapshot, ok := overrides[strconv.Itoa(creds.PlayerUID)]
+		if ok && customSnapshot != "" && customSnapshot != "latest" {
+			customSnapshotInt, err := strconv.ParseInt(customSnapshot, 10, 64)
+			if err != nil {
+				log.Printf("Malformed snapshot int for match %v player %v, %v: %v", match.GameNumber, creds.PlayerUID, customSnapshot, err)
+				return nil, err
+			}
+
+			snapshotsToLoad[creds.PlayerUID] = customSnapshotInt
+		}
+
+		if snapshotsToLoad[creds.PlayerUID] == 0 {
+			ignoredSnapshots++
+		}
+	}
+
+	var err error
+	i := 0
+	loadedSnapshots := make([]*types.APIResponse, len(snapshotsToLoad)-ignoredSnapshots)
+	for playerID, snapshotTime := range snapshotsToLoad {
+		if snapshotTime == 0 {
+			continue // ignored
+		}
+
+		loadedSnapshots[i], err = db.FindSnapshot(match.GameNumber, playerID, snapshotTime)
+		i++
+		if err != nil {
+			log.Printf("Could not load snapshot for match %v player %v, %v: %v", match.GameNumber, playerID, snapshotTime, err)
+			return nil, err
+		}
+
+		if mergeOptions.Extrapolate {
+			opsec.Extrapolate(loadedSnapshots[i-1], now.UnixNano()/int64(time.Millisecond))
+		}
+	}
+
+	if len(loadedSnapshots) == 0 {
+		return nil, ErrNoSnapshotsLoaded
+	}
+
+	return opsec.Merge(loadedSnapshots...), nil
+}
+
+// PreviousMergedSnapshot merges the snapshot before the latest one of every polled player.
+// Used to find out what changed during the last poll.
+func PreviousMergedSnapshot(db matchstore.MatchStore, match *matches.Match) (*types.APIResponse, error) {
+	overrides := map[string]string{}
+	for _, creds := range match.PlayerCreds {
+		if creds.PollingDisabled {
+			continue
+		}
+
+		snapshotTimes, err := db.ListSnapshotTimes(match.GameNumber, creds.PlayerUID, 2)
+		if err == matchstore.ErrSnapshotNotFound || err == matchstore.ErrMatchNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if len(snapshotTimes) < 2 {
+			// no history yet, ignore this player
+			overrides[strconv.Itoa(creds.PlayerUID)] = "0"
+			continue
+		}
+
+		// snapshot times are newest-first
+		overrides[strconv.Itoa(creds.PlayerUID)] = strconv.FormatInt(snapshotTimes[1], 10)
+	}
+
+	return MergedSnapshot(db, match, matches.PermissiveAccessProfile(), overrides, nil)
+}
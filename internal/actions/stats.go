@@ -0,0 +1,42 @@
+package actions
+
+import (
+	"math"
+	"strconv"
+
+	"go.albinodrought.com/neptunes-pride/internal/matchstore"
+	"go.albinodrought.com/neptunes-pride/internal/stats"
+)
+
+// BackfillStats extracts stats rows from every stored snapshot of a match,
+// for snapshots saved before stats were recorded
+func BackfillStats(db matchstore.MatchStore, gameNumber string, log func(v ...interface{})) error {
+	match, err := db.FindMatchOrFail(gameNumber)
+	if err != nil {
+		return err
+	}
+
+	for _, creds := range match.PlayerCreds {
+		snapshotTimes, err := db.ListSnapshotTimes(gameNumber, creds.PlayerUID, math.MaxInt32)
+		if err == matchstore.ErrSnapshotNotFound || err == matchstore.ErrMatchNotFound {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		log("backfilling stats for game ", gameNumber, " player ", strconv.Itoa(creds.PlayerUID), " from ", len(snapshotTimes), " snapshots")
+		for _, snapshotTime := range snapshotTimes {
+			snapshot, err := db.FindSnapshot(gameNumber, creds.PlayerUID, snapshotTime)
+			if err != nil {
+				return err
+			}
+
+			if err := db.SaveStats(gameNumber, stats.Extract(snapshot)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
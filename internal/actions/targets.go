@@ -0,0 +1,42 @@
+package actions
+
+import (
+	"go.albinodrought.com/neptunes-pride/internal/afk"
+	"go.albinodrought.com/neptunes-pride/internal/graph"
+	"go.albinodrought.com/neptunes-pride/internal/matches"
+	"go.albinodrought.com/neptunes-pride/internal/targets"
+	"go.albinodrought.com/neptunes-pride/internal/types"
+)
+
+// FindTargets ranks the stars a friendly player could capture next
+func FindTargets(match *matches.Match, accessProfile matches.AccessProfile, merged *types.APIResponse, playerUID int, options *targets.Options) []targets.Target {
+	return findTargets(match, merged, playerUID, AllyIDs(match, accessProfile), options)
+}
+
+func findTargets(match *matches.Match, merged *types.APIResponse, playerUID int, allyIDs []int, options *targets.Options) []targets.Target {
+	matrix := graph.DefaultMatrixCache.ForGame(match.GameNumber, merged)
+	playerGraph := graph.New(matrix, merged, playerUID)
+	return targets.Find(merged, playerGraph, allyIDs, options)
+}
+
+// FindLandGrabs picks which friendly player should take each of a player's stars,
+// useful once that player stops defending them
+func FindLandGrabs(match *matches.Match, accessProfile matches.AccessProfile, merged *types.APIResponse, ownerUID int) []afk.LandGrab {
+	options := targets.DefaultOptions
+	options.Limit = 0
+
+	// the owner's stars are up for grabs, even when they're an ally
+	allyIDs := []int{}
+	for _, allyID := range AllyIDs(match, accessProfile) {
+		if allyID != ownerUID {
+			allyIDs = append(allyIDs, allyID)
+		}
+	}
+
+	targetsByPlayer := map[int][]targets.Target{}
+	for _, allyID := range allyIDs {
+		targetsByPlayer[allyID] = findTargets(match, merged, allyID, allyIDs, &options)
+	}
+
+	return afk.LandGrabs(merged, ownerUID, targetsByPlayer)
+}
@@ -0,0 +1,54 @@
+package actions
+
+import (
+	"go.albinodrought.com/neptunes-pride/internal/matches"
+	"go.albinodrought.com/neptunes-pride/internal/matchstore"
+	"go.albinodrought.com/neptunes-pride/internal/types"
+	"go.albinodrought.com/neptunes-pride/internal/victory"
+)
+
+// DefaultVictoryHistoryLimit is how many stored snapshots per player are charted
+// for games without stats
+const DefaultVictoryHistoryLimit = 1000
+
+// VictoryProgress charts every player's star count from stored stats
+// and projects who reaches stars_for_victory first.
+// Games polled before stats were recorded are charted from their snapshots instead.
+func VictoryProgress(db matchstore.MatchStore, match *matches.Match, accessProfile matches.AccessProfile, merged *types.APIResponse, historyLimit int) (victory.Progress, error) {
+	history := victory.NewHistory()
+
+	allSeries, err := db.ListStats(match.GameNumber)
+	if err == matchstore.ErrMatchNotFound {
+		err = recordSnapshotHistory(db, match, accessProfile, history, historyLimit)
+	} else if err == nil {
+		history.RecordStats(allSeries)
+	}
+	if err != nil {
+		return victory.Progress{}, err
+	}
+
+	return victory.Analyze(merged, history, nil), nil
+}
+
+func recordSnapshotHistory(db matchstore.MatchStore, match *matches.Match, accessProfile matches.AccessProfile, history *victory.History, historyLimit int) error {
+	for _, allyID := range AllyIDs(match, accessProfile) {
+		snapshotTimes, err := db.ListSnapshotTimes(match.GameNumber, allyID, historyLimit)
+		if err == matchstore.ErrSnapshotNotFound || err == matchstore.ErrMatchNotFound {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, snapshotTime := range snapshotTimes {
+			snapshot, err := db.FindSnapshot(match.GameNumber, allyID, snapshotTime)
+			if err != nil {
+				return err
+			}
+
+			history.Record(snapshot)
+		}
+	}
+
+	return nil
+}
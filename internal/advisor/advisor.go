@@ -0,0 +1,258 @@
+package advisor
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+
+	"go.albinodrought.com/neptunes-pride/internal/forecast"
+	"go.albinodrought.com/neptunes-pride/internal/types"
+)
+
+const (
+	KindEconomy  = "economy"
+	KindIndustry = "industry"
+	KindScience  = "science"
+)
+
+const (
+	// GoalIncome maximises cash earned at the next production
+	GoalIncome = "income"
+	// GoalShips maximises ships built per production
+	GoalShips = "ships"
+	// GoalScience maximises research points per production
+	GoalScience = "science"
+)
+
+var ErrUnknownGoal = errors.New("unknown goal")
+var ErrNoPrivateData = errors.New("no private data for player, do we have their key?")
+
+// CostFactors mirror the galaxy's development cost setting (NP defaults to 2, "standard")
+type CostFactors struct {
+	Economy  float64
+	Industry float64
+	Science  float64
+}
+
+var DefaultCostFactors = CostFactors{
+	Economy:  2,
+	Industry: 2,
+	Science:  2,
+}
+
+// terraformingResourcesPerLevel is how many resources each terraforming level adds to every star
+const terraformingResourcesPerLevel = 5
+
+// Resources of a star, including terraforming
+func Resources(star types.Star, terraformingLevel int) int {
+	if star.Resources > 0 {
+		return star.Resources
+	}
+	return star.NaturalResources + terraformingLevel*terraformingResourcesPerLevel
+}
+
+// UpgradeCost to buy the next level of infrastructure at a star, same formulas as NP
+func UpgradeCost(kind string, currentLevel int, resources int, factors CostFactors) int {
+	if resources <= 0 {
+		return math.MaxInt32
+	}
+
+	base := 0.0
+	switch kind {
+	case KindEconomy:
+		base = 2.5 * factors.Economy
+	case KindIndustry:
+		base = 5 * factors.Industry
+	case KindScience:
+		base = 20 * factors.Science
+	}
+
+	return int(math.Floor(base * float64(currentLevel+1) / (float64(resources) / 100)))
+}
+
+type StarCosts struct {
+	StarUID   int    `json:"star_uid"`
+	StarName  string `json:"star_name"`
+	Resources int    `json:"resources"`
+	Economy   int    `json:"economy"`
+	Industry  int    `json:"industry"`
+	Science   int    `json:"science"`
+}
+
+type Purchase struct {
+	StarUID  int    `json:"star_uid"`
+	StarName string `json:"star_name"`
+	Kind     string `json:"kind"`
+	// Level after the purchase
+	Level int `json:"level"`
+	Cost  int `json:"cost"`
+}
+
+type Advice struct {
+	PlayerUID   int    `json:"player_uid"`
+	PlayerAlias string `json:"player_alias"`
+	Goal        string `json:"goal"`
+	Cash        int    `json:"cash"`
+	Budget      int    `json:"budget"`
+	Spent       int    `json:"spent"`
+	// Costs of the next upgrade at every star, before any purchases
+	Costs     []StarCosts `json:"costs"`
+	Purchases []Purchase  `json:"purchases"`
+	// Gains per production once every purchase is made
+	IncomeGain  int `json:"income_gain"`
+	ShipsGain   int `json:"ships_gain"`
+	ScienceGain int `json:"science_gain"`
+}
+
+type Options struct {
+	Goal string
+	// Budget to spend, negative to spend all of the player's cash
+	Budget      int
+	CostFactors CostFactors
+}
+
+var DefaultOptions = Options{
+	Goal:        GoalIncome,
+	Budget:      -1,
+	CostFactors: DefaultCostFactors,
+}
+
+type upgradeable struct {
+	star      types.Star
+	resources int
+	levels    map[string]int
+}
+
+// Advise recommends the infrastructure a player should buy to reach a goal.
+// Purchases are picked greedily by value per dollar, re-pricing after every purchase.
+func Advise(resp *types.APIResponse, playerUID int, options *Options) (Advice, error) {
+	if options == nil {
+		options = &DefaultOptions
+	}
+
+	player, ok := resp.ScanningData.Players[strconv.Itoa(playerUID)]
+	if !ok || !player.PrivatePlayer.Useful() {
+		// only keyed players expose their cash
+		return Advice{}, ErrNoPrivateData
+	}
+
+	// value of one more level of each kind towards the goal
+	values := map[string]float64{}
+	switch options.Goal {
+	case GoalIncome:
+		values[KindEconomy] = forecast.IncomePerEconomy
+	case GoalShips:
+		values[KindIndustry] = float64(forecast.ShipsPerProduction(1, player.Tech.Manufacturing.Level))
+	case GoalScience:
+		values[KindScience] = float64(resp.ScanningData.ProductionRate)
+	default:
+		return Advice{}, ErrUnknownGoal
+	}
+
+	advice := Advice{
+		PlayerUID:   playerUID,
+		PlayerAlias: player.Alias,
+		Goal:        options.Goal,
+		Cash:        player.Cash,
+		Budget:      options.Budget,
+		Costs:       []StarCosts{},
+		Purchases:   []Purchase{},
+	}
+	if advice.Budget < 0 {
+		advice.Budget = player.Cash
+	}
+
+	stars := []*upgradeable{}
+	for _, star := range resp.ScanningData.Stars {
+		if star.PlayerID != playerUID || !star.PrivateStar.Useful() {
+			continue
+		}
+
+		resources := Resources(star, player.Tech.Terraforming.Level)
+		stars = append(stars, &upgradeable{
+			star:      star,
+			resources: resources,
+			levels: map[string]int{
+				KindEconomy:  star.Economy,
+				KindIndustry: star.Industry,
+				KindScience:  star.Science,
+			},
+		})
+		advice.Costs = append(advice.Costs, StarCosts{
+			StarUID:   star.UID,
+			StarName:  star.Name,
+			Resources: resources,
+			Economy:   UpgradeCost(KindEconomy, star.Economy, resources, options.CostFactors),
+			Industry:  UpgradeCost(KindIndustry, star.Industry, resources, options.CostFactors),
+			Science:   UpgradeCost(KindScience, star.Science, resources, options.CostFactors),
+		})
+	}
+
+	if len(stars) == 0 {
+		return Advice{}, ErrNoPrivateData
+	}
+
+	sort.Slice(stars, func(i, j int) bool {
+		return stars[i].star.UID < stars[j].star.UID
+	})
+	sort.Slice(advice.Costs, func(i, j int) bool {
+		return advice.Costs[i].StarUID < advice.Costs[j].StarUID
+	})
+
+	remaining := advice.Budget
+	for {
+		var best *upgradeable
+		bestKind := ""
+		bestCost := 0
+		bestRatio := 0.0
+
+		for _, candidate := range stars {
+			for _, kind := range []string{KindEconomy, KindIndustry, KindScience} {
+				value := values[kind]
+				if value <= 0 {
+					continue
+				}
+
+				cost := UpgradeCost(kind, candidate.levels[kind], candidate.resources, options.CostFactors)
+				if cost > remaining {
+					continue
+				}
+
+				ratio := value / math.Max(1, float64(cost))
+				if best == nil || ratio > bestRatio || (ratio == bestRatio && cost < bestCost) {
+					best = candidate
+					bestKind = kind
+					bestCost = cost
+					bestRatio = ratio
+				}
+			}
+		}
+
+		if best == nil {
+			break
+		}
+
+		best.levels[bestKind]++
+		remaining -= bestCost
+		advice.Spent += bestCost
+		advice.Purchases = append(advice.Purchases, Purchase{
+			StarUID:  best.star.UID,
+			StarName: best.star.Name,
+			Kind:     bestKind,
+			Level:    best.levels[bestKind],
+			Cost:     bestCost,
+		})
+
+		switch bestKind {
+		case KindEconomy:
+			advice.IncomeGain += forecast.IncomePerEconomy
+		case KindIndustry:
+			advice.ShipsGain += forecast.ShipsPerProduction(1, player.Tech.Manufacturing.Level)
+		case KindScience:
+			advice.ScienceGain += resp.ScanningData.ProductionRate
+		}
+	}
+
+	return advice, nil
+}
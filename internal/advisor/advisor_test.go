@@ -0,0 +1,50 @@
+package advisor
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"go.albinodrought.com/neptunes-pride/internal/forecast"
+	"go.albinodrought.com/neptunes-pride/internal/types"
+)
+
+func TestAdvise(t *testing.T) {
+	data, err := ioutil.ReadFile("../opsec/burrito.json")
+	if err != nil {
+		panic(err)
+	}
+
+	burrito := &types.APIResponse{}
+	if err := json.Unmarshal(data, burrito); err != nil {
+		panic(err)
+	}
+
+	// star #5 Maia: 60 resources, 5 economy
+	if cost := UpgradeCost(KindEconomy, 5, 60, DefaultCostFactors); cost != 50 {
+		t.Errorf("expected economy upgrade to cost 50, got %v", cost)
+	}
+
+	options := DefaultOptions
+	options.Budget = 500
+
+	advice, err := Advise(burrito, 5, &options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if advice.Spent > advice.Budget || len(advice.Purchases) == 0 {
+		t.Errorf("expected purchases within budget, got %+v", advice)
+	}
+	for _, purchase := range advice.Purchases {
+		if purchase.Kind != KindEconomy {
+			t.Errorf("expected only economy purchases for income, got %+v", purchase)
+		}
+	}
+	if advice.IncomeGain != len(advice.Purchases)*forecast.IncomePerEconomy {
+		t.Errorf("expected income gain to match purchases, got %+v", advice)
+	}
+
+	if _, err := Advise(burrito, 1, &options); err != ErrNoPrivateData {
+		t.Errorf("expected no private data for player 1, got %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+package afk
+
+import (
+	"sort"
+
+	"go.albinodrought.com/neptunes-pride/internal/targets"
+	"go.albinodrought.com/neptunes-pride/internal/types"
+)
+
+const (
+	// KindAI means the game's AI took over the player's empire
+	KindAI = "ai"
+	// KindInactive means NP marked the player as inactive
+	KindInactive = "inactive"
+	// KindConceded means the player conceded
+	KindConceded = "conceded"
+	// KindMissedTurns means the player keeps missing turns in a turn-based game
+	KindMissedTurns = "missed_turns"
+)
+
+// DefaultMissedTurnsThreshold is how many missed turns it takes before a player counts as AFK
+const DefaultMissedTurnsThreshold = 2
+
+type Event struct {
+	PlayerUID   int    `json:"player_uid"`
+	PlayerAlias string `json:"player_alias"`
+	Kind        string `json:"kind"`
+	MissedTurns int    `json:"missed_turns"`
+}
+
+// Detect finds the players who went AFK, were taken over by AI, or conceded between two snapshots
+func Detect(previous *types.APIResponse, current *types.APIResponse, missedTurnsThreshold int) []Event {
+	events := []Event{}
+
+	for playerIndex, player := range current.ScanningData.Players {
+		previousPlayer, ok := previous.ScanningData.Players[playerIndex]
+		if !ok {
+			continue
+		}
+
+		event := Event{
+			PlayerUID:   player.UID,
+			PlayerAlias: player.Alias,
+			MissedTurns: player.MissedTurns,
+		}
+
+		if player.Ai != 0 && previousPlayer.Ai == 0 {
+			event.Kind = KindAI
+			events = append(events, event)
+		}
+
+		if player.Conceded != previousPlayer.Conceded {
+			switch player.Conceded {
+			case types.ConcededYes:
+				event.Kind = KindConceded
+				events = append(events, event)
+			case types.ConcededInactive:
+				event.Kind = KindInactive
+				events = append(events, event)
+			}
+		}
+
+		if player.MissedTurns > previousPlayer.MissedTurns && player.MissedTurns >= missedTurnsThreshold {
+			event.Kind = KindMissedTurns
+			events = append(events, event)
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].PlayerUID != events[j].PlayerUID {
+			return events[i].PlayerUID < events[j].PlayerUID
+		}
+		return events[i].Kind < events[j].Kind
+	})
+
+	return events
+}
+
+type LandGrab struct {
+	StarUID  int    `json:"star_uid"`
+	StarName string `json:"star_name"`
+	// ByUID is the friendly player best placed to take the star
+	ByUID       int     `json:"by_uid"`
+	ByAlias     string  `json:"by_alias"`
+	Ticks       int     `json:"ticks"`
+	Garrison    int     `json:"garrison"`
+	ShipsNeeded int     `json:"ships_needed"`
+	Value       float64 `json:"value"`
+	Score       float64 `json:"score"`
+}
+
+// LandGrabs picks the best friendly player to take each of an AFK player's stars, best first.
+// targetsByPlayer maps each friendly player's uid to their ranked targets.
+func LandGrabs(resp *types.APIResponse, ownerUID int, targetsByPlayer map[int][]targets.Target) []LandGrab {
+	best := map[int]LandGrab{}
+
+	for playerUID, playerTargets := range targetsByPlayer {
+		for _, target := range playerTargets {
+			if target.OwnerUID != ownerUID {
+				continue
+			}
+
+			current, ok := best[target.StarUID]
+			if ok && (current.Score > target.Score || (current.Score == target.Score && current.ByUID < playerUID)) {
+				continue
+			}
+
+			alias := ""
+			for _, player := range resp.ScanningData.Players {
+				if player.UID == playerUID {
+					alias = player.Alias
+					break
+				}
+			}
+
+			best[target.StarUID] = LandGrab{
+				StarUID:     target.StarUID,
+				StarName:    target.StarName,
+				ByUID:       playerUID,
+				ByAlias:     alias,
+				Ticks:       target.Route.Ticks,
+				Garrison:    target.ProjectedGarrison,
+				ShipsNeeded: target.ShipsNeeded,
+				Value:       target.Value,
+				Score:       target.Score,
+			}
+		}
+	}
+
+	landGrabs := make([]LandGrab, 0, len(best))
+	for _, landGrab := range best {
+		landGrabs = append(landGrabs, landGrab)
+	}
+
+	sort.Slice(landGrabs, func(i, j int) bool {
+		if landGrabs[i].Score != landGrabs[j].Score {
+			return landGrabs[i].Score > landGrabs[j].Score
+		}
+		return landGrabs[i].StarUID < landGrabs[j].StarUID
+	})
+
+	return landGrabs
+}
@@ -0,0 +1,98 @@
+package afk
+
+import (
+	"testing"
+
+	"go.albinodrought.com/neptunes-pride/internal/graph"
+	"go.albinodrought.com/neptunes-pride/internal/targets"
+	"go.albinodrought.com/neptunes-pride/internal/types"
+)
+
+func TestDetect(t *testing.T) {
+	snapshot := func(update func(players map[string]types.Player)) *types.APIResponse {
+		resp := &types.APIResponse{}
+		resp.ScanningData.Players = map[string]types.Player{}
+		for i, uid := range []string{"0", "1", "2", "3"} {
+			player := types.Player{}
+			player.UID = i
+			player.Alias = "player " + uid
+			resp.ScanningData.Players[uid] = player
+		}
+		if update != nil {
+			update(resp.ScanningData.Players)
+		}
+		return resp
+	}
+
+	previous := snapshot(nil)
+	current := snapshot(func(players map[string]types.Player) {
+		ai := players["0"]
+		ai.Ai = 1
+		players["0"] = ai
+
+		conceded := players["1"]
+		conceded.Conceded = types.ConcededYes
+		players["1"] = conceded
+
+		inactive := players["2"]
+		inactive.Conceded = types.ConcededInactive
+		inactive.MissedTurns = DefaultMissedTurnsThreshold
+		players["2"] = inactive
+
+		missedOne := players["3"]
+		missedOne.MissedTurns = 1
+		players["3"] = missedOne
+	})
+
+	events := Detect(previous, current, DefaultMissedTurnsThreshold)
+	expected := []Event{
+		{PlayerUID: 0, PlayerAlias: "player 0", Kind: KindAI},
+		{PlayerUID: 1, PlayerAlias: "player 1", Kind: KindConceded},
+		{PlayerUID: 2, PlayerAlias: "player 2", Kind: KindInactive, MissedTurns: DefaultMissedTurnsThreshold},
+		{PlayerUID: 2, PlayerAlias: "player 2", Kind: KindMissedTurns, MissedTurns: DefaultMissedTurnsThreshold},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("expected %v events, got %+v", len(expected), events)
+	}
+	for i := range expected {
+		if events[i] != expected[i] {
+			t.Errorf("expected event %v to be %+v, got %+v", i, expected[i], events[i])
+		}
+	}
+
+	if len(Detect(current, current, DefaultMissedTurnsThreshold)) != 0 {
+		t.Errorf("expected no events when nothing changed")
+	}
+}
+
+func TestLandGrabs(t *testing.T) {
+	resp := &types.APIResponse{}
+	resp.ScanningData.Players = map[string]types.Player{}
+	for i, uid := range []string{"0", "1"} {
+		player := types.Player{}
+		player.UID = i
+		player.Alias = "player " + uid
+		resp.ScanningData.Players[uid] = player
+	}
+
+	landGrabs := LandGrabs(resp, 2, map[int][]targets.Target{
+		0: {
+			{StarUID: 10, OwnerUID: 2, Score: 5, Route: graph.Route{Ticks: 4}},
+			{StarUID: 11, OwnerUID: 3, Score: 50},
+		},
+		1: {
+			{StarUID: 10, OwnerUID: 2, Score: 8, Route: graph.Route{Ticks: 2}},
+			{StarUID: 12, OwnerUID: 2, Score: 1},
+		},
+	})
+
+	if len(landGrabs) != 2 {
+		t.Fatalf("expected 2 land grabs, got %+v", landGrabs)
+	}
+	if landGrabs[0].StarUID != 10 || landGrabs[0].ByUID != 1 || landGrabs[0].Ticks != 2 {
+		t.Errorf("expected player 1 to be best placed for star 10, got %+v", landGrabs[0])
+	}
+	if landGrabs[1].StarUID != 12 || landGrabs[1].ByAlias != "player 1" {
+		t.Errorf("expected player 1 to take star 12, got %+v", landGrabs[1])
+	}
+}
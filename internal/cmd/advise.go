@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"go.albinodrought.com/neptunes-pride/internal/actions"
+	"go.albinodrought.com/neptunes-pride/internal/advisor"
+	"go.albinodrought.com/neptunes-pride/internal/matches"
+)
+
+var (
+	adviseCmdGoal   string
+	adviseCmdBudget int
+)
+
+var adviseCmd = &cobra.Command{
+	Use:   "advise [game number] [player uid]",
+	Short: "Recommend economy, industry and science purchases for a player",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		db, err := openDB()
+		if err != nil {
+			log.Fatal("failed to open DB: ", err)
+		}
+
+		match, err := db.FindMatchOrFail(args[0])
+		if err != nil {
+			log.Fatal("failed finding match: ", err)
+		}
+
+		playerUID, err := strconv.Atoi(args[1])
+		if err != nil {
+			log.Fatal("failed parsing player uid: ", err)
+		}
+
+		merged, err := actions.MergedSnapshot(db, match, matches.PermissiveAccessProfile(), map[string]string{}, nil)
+		if err != nil {
+			log.Fatal("failed merging snapshot: ", err)
+		}
+
+		options := advisor.DefaultOptions
+		options.Goal = adviseCmdGoal
+		options.Budget = adviseCmdBudget
+
+		advice, err := advisor.Advise(merged, playerUID, &options)
+		if err != nil {
+			log.Fatal("failed advising player: ", err)
+		}
+
+		fmt.Printf("%v has $%v, spending $%v of $%v for %v\n", advice.PlayerAlias, advice.Cash, advice.Spent, advice.Budget, advice.Goal)
+		for _, purchase := range advice.Purchases {
+			fmt.Printf("  buy %v %v at %v (#%v) for $%v\n", purchase.Kind, purchase.Level, purchase.StarName, purchase.StarUID, purchase.Cost)
+		}
+		fmt.Printf("gains per production: $%v, %v ships, %v research\n", advice.IncomeGain, advice.ShipsGain, advice.ScienceGain)
+	},
+}
+
+func init() {
+	adviseCmd.Flags().StringVar(&adviseCmdGoal, "goal", advisor.DefaultOptions.Goal, "What to spend on: income, ships or science")
+	adviseCmd.Flags().IntVar(&adviseCmdBudget, "budget", advisor.DefaultOptions.Budget, "How much cash to spend (-1 for all of it)")
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"log"
+
+	"github.com/spf13/cobra"
+	"go.albinodrought.com/neptunes-pride/internal/actions"
+)
+
+var backfillStatsCmd = &cobra.Command{
+	Use:   "backfill-stats [...game numbers, or \"all\"]",
+	Short: "Extract per-player stats from snapshots stored before stats were recorded",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		db, err := openDB()
+		if err != nil {
+			log.Fatal("failed to open DB", err)
+		}
+
+		gameNumbers := args
+		if len(args) == 1 && args[0] == "all" {
+			gameNumbers, err = db.Matches()
+			if err != nil {
+				log.Fatal("failed listing matches: ", err)
+			}
+		}
+
+		for _, gameNumber := range gameNumbers {
+			if err := actions.BackfillStats(db, gameNumber, log.Println); err != nil {
+				log.Fatal("failed backfilling stats: ", err)
+			}
+		}
+
+		log.Println("ok")
+	},
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"errors"
 	"net/http"
 	"os"
 
@@ -9,22 +10,100 @@ import (
 	"go.albinodrought.com/neptunes-pride/internal/matchstore"
 	"go.albinodrought.com/neptunes-pride/internal/notifications"
 	"go.albinodrought.com/neptunes-pride/internal/npapi"
+	"go.albinodrought.com/neptunes-pride/internal/secrets"
 )
 
 var (
+	matchStoreBackend   string
 	matchStoreDbPath    string
+	autoMigrate         bool
 	notificationsDbPath string
 	discordWebhookURL   string
+	secretKeyFile       string
 )
 
+// errMemoryBackend stops commands from writing to a store that's gone once they exit
+var errMemoryBackend = errors.New("the memory backend keeps nothing after the command exits, use `np-scanner serve --ephemeral` instead")
+
 func addGlobalConfigFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&matchStoreBackend, "db-backend", "bolt", "Database Backend (Match Store), bolt or sqlite")
 	cmd.PersistentFlags().StringVar(&matchStoreDbPath, "db-path", "np.db", "Database Path (Match Store)")
+	cmd.PersistentFlags().BoolVar(&autoMigrate, "auto-migrate", matchstore.DefaultBoltOptions.AutoMigrate, "Back up and upgrade an outdated bolt database when opening it")
 	cmd.PersistentFlags().StringVar(&notificationsDbPath, "notifications-db-path", "np-notifications.db", "Database Path (Notifications)")
 	cmd.PersistentFlags().StringVar(&discordWebhookURL, "discord-webhook-url", os.Getenv("NP_SCANNER_DISCORD_WEBHOOK_URL"), "Discord Webhook URL")
+	cmd.PersistentFlags().StringVar(&secretKeyFile, "secret-key-file", os.Getenv("NP_SCANNER_SECRET_KEY_FILE"), "Key file for encrypting API keys and Discord IDs (or env var NP_SCANNER_SECRET_KEY)")
+}
+
+// loadKeyring returns nil if no secret key is configured, old keys are only used to open secrets
+func loadKeyring(oldKeyFiles ...string) (*secrets.Keyring, error) {
+	var key []byte
+	var err error
+
+	if secretKeyFile != "" {
+		key, err = secrets.LoadKeyFile(secretKeyFile)
+	} else if encoded := os.Getenv("NP_SCANNER_SECRET_KEY"); encoded != "" {
+		key, err = secrets.ParseKey(encoded)
+	} else {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	oldKeys := [][]byte{}
+	for _, oldKeyFile := range oldKeyFiles {
+		oldKey, err := secrets.LoadKeyFile(oldKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		oldKeys = append(oldKeys, oldKey)
+	}
+
+	return secrets.NewKeyring(key, oldKeys...)
 }
 
+// openDB refuses databases with plain text secrets once a secret key is configured
 func openDB() (matchstore.MatchStore, error) {
-	return matchstore.Open(matchStoreDbPath)
+	keyring, err := loadKeyring()
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := openDBWithKeyring(keyring)
+	if err != nil {
+		return nil, err
+	}
+
+	if keyring != nil {
+		if err := matchstore.CheckSecretsSealed(db); err != nil {
+			return nil, err
+		}
+	}
+
+	return db, nil
+}
+
+func openDBWithKeyring(keyring *secrets.Keyring) (matchstore.MatchStore, error) {
+	var db matchstore.MatchStore
+	var err error
+
+	if matchStoreBackend == "memory" {
+		return nil, errMemoryBackend
+	}
+
+	if matchStoreBackend == "bolt" {
+		options := matchstore.DefaultBoltOptions
+		options.AutoMigrate = autoMigrate
+		options.Secrets = keyring
+		db, err = matchstore.OpenBolt(matchStoreDbPath, &options)
+	} else {
+		db, err = matchstore.OpenBackend(matchStoreBackend, matchStoreDbPath)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return matchstore.WithSecrets(db, keyring), nil
 }
 
 func openNotificationGuard() (notifications.Guard, error) {
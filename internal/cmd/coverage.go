@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+	"go.albinodrought.com/neptunes-pride/internal/actions"
+	"go.albinodrought.com/neptunes-pride/internal/matches"
+	"go.albinodrought.com/neptunes-pride/internal/opsec"
+)
+
+var coverageCmdHistoryLimit int
+
+func describeCoverageCandidate(candidate *opsec.CoverageCandidate) string {
+	if candidate == nil {
+		return "no ally can cover this"
+	}
+	if candidate.Shortfall == 0 {
+		return fmt.Sprintf("%v is in range", candidate.PlayerAlias)
+	}
+	return fmt.Sprintf("%v is %.2f short", candidate.PlayerAlias, candidate.Shortfall)
+}
+
+func describeDarkTicks(darkTicks int) string {
+	if darkTicks < 0 {
+		return "never seen"
+	}
+	return fmt.Sprintf("dark for %v ticks", darkTicks)
+}
+
+var coverageCmd = &cobra.Command{
+	Use:   "coverage [game number]",
+	Short: "List stars and regions no ally is scanning",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		db, err := openDB()
+		if err != nil {
+			log.Fatal("failed to open DB: ", err)
+		}
+
+		match, err := db.FindMatchOrFail(args[0])
+		if err != nil {
+			log.Fatal("failed finding match: ", err)
+		}
+
+		accessProfile := matches.PermissiveAccessProfile()
+
+		merged, err := actions.MergedSnapshot(db, match, accessProfile, map[string]string{}, nil)
+		if err != nil {
+			log.Fatal("failed merging snapshot: ", err)
+		}
+
+		report, err := actions.FindCoverageGaps(db, match, accessProfile, merged, coverageCmdHistoryLimit)
+		if err != nil {
+			log.Fatal("failed finding coverage gaps: ", err)
+		}
+
+		fmt.Printf("scanning %v of %v stars\n", report.VisibleStars, report.TotalStars)
+
+		fmt.Println("regions:")
+		for _, region := range report.Regions {
+			fmt.Printf("  %v stars around (%.2f, %.2f), %v, %v\n", len(region.StarUIDs), region.X, region.Y, describeDarkTicks(region.DarkTicks), describeCoverageCandidate(region.BestAlly))
+		}
+
+		fmt.Println("stars:")
+		for _, gap := range report.Stars {
+			fmt.Printf("  %v (#%v), %v, %v\n", gap.StarName, gap.StarUID, describeDarkTicks(gap.DarkTicks), describeCoverageCandidate(gap.BestAlly))
+		}
+	},
+}
+
+func init() {
+	coverageCmd.Flags().IntVar(&coverageCmdHistoryLimit, "history-limit", actions.DefaultCoverageHistoryLimit, "Check this many stored snapshots per player when looking for the last sighting")
+}
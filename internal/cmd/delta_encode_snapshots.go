@@ -0,0 +1,23 @@
+package cmd
+
+import (
+	"log"
+
+	"github.com/spf13/cobra"
+)
+
+var deltaEncodeSnapshotsCmd = &cobra.Command{
+	Use:   "delta-encode-snapshots",
+	Short: "Rewrite stored snapshots as keyframes plus deltas",
+	Args:  cobra.MaximumNArgs(0),
+	Run: func(cmd *cobra.Command, args []string) {
+		db, err := openDB()
+		if err != nil {
+			log.Fatal("failed to open DB", err)
+		}
+		if err := db.DeltaEncodeSnapshots(log.Println); err != nil {
+			log.Fatal("failed to delta encode snapshots", err)
+		}
+		log.Println("ok, compact the database (e.g. `bbolt compact`) to reclaim the freed space")
+	},
+}
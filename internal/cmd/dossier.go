@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"go.albinodrought.com/neptunes-pride/internal/actions"
+	"go.albinodrought.com/neptunes-pride/internal/matches"
+)
+
+var dossierCmdHistoryLimit int
+
+var dossierCmd = &cobra.Command{
+	Use:   "dossier [alias]",
+	Short: "Report on how a player has played across every stored game",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		db, err := openDB()
+		if err != nil {
+			log.Fatal("failed to open DB: ", err)
+		}
+
+		everyMatch := func(match *matches.Match) (matches.AccessProfile, bool) {
+			return matches.PermissiveAccessProfile(), true
+		}
+
+		playerDossier, err := actions.BuildDossier(db, args[0], everyMatch, dossierCmdHistoryLimit)
+		if err != nil {
+			log.Fatal("failed building dossier: ", err)
+		}
+
+		if len(playerDossier.Games) == 0 {
+			fmt.Printf("%v is not in any stored game\n", playerDossier.Alias)
+			return
+		}
+
+		fmt.Printf(
+			"%v: %v games, %v won, %v conceded, average rank %.1f, %v attacks launched\n",
+			playerDossier.Alias,
+			playerDossier.GamesPlayed,
+			playerDossier.GamesWon,
+			playerDossier.GamesConceded,
+			playerDossier.AverageRank,
+			playerDossier.AttacksLaunched,
+		)
+		fmt.Printf("favorite techs: %v\n", strings.Join(playerDossier.FavoriteTechs, ", "))
+
+		for _, game := range playerDossier.Games {
+			status := "in progress"
+			if game.Finished {
+				status = "finished"
+			}
+			notes := ""
+			if game.Conceded {
+				notes += ", conceded"
+			}
+			if game.AI {
+				notes += ", AI"
+			}
+			fmt.Printf(
+				"  %v %v (%v): rank %v of %v, %v/%v stars, priorities %v, %v attacks%v\n",
+				game.GameNumber,
+				game.MatchName,
+				status,
+				game.Rank,
+				game.Players,
+				game.TotalStars,
+				game.StarsForVictory,
+				strings.Join(game.TechPriorities, ", "),
+				game.AttacksLaunched,
+				notes,
+			)
+		}
+	},
+}
+
+func init() {
+	dossierCmd.Flags().IntVar(&dossierCmdHistoryLimit, "history-limit", actions.DefaultDossierHistoryLimit, "Check this many stored snapshots per player when counting attacks")
+}
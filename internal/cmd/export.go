@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+	"go.albinodrought.com/neptunes-pride/internal/matchstore"
+)
+
+var (
+	exportCmdOutput             string
+	exportCmdIncludeCredentials bool
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export [game number]",
+	Short: "Write a match and every snapshot to an archive that can be imported into another DB",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		db, err := openDB()
+		if err != nil {
+			log.Fatal("failed to open DB: ", err)
+		}
+
+		output := exportCmdOutput
+		if output == "" {
+			output = args[0] + ".tar.gz"
+		}
+
+		// archives with credentials hold API keys in plain text
+		mode := os.FileMode(0644)
+		if exportCmdIncludeCredentials {
+			mode = 0600
+		}
+
+		file, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+		if err != nil {
+			log.Fatal("failed to create archive: ", err)
+		}
+
+		manifest, err := matchstore.Export(db, args[0], file, &matchstore.ExportOptions{
+			StripCredentials: !exportCmdIncludeCredentials,
+			Log:              log.Println,
+		})
+		if err != nil {
+			file.Close()
+			log.Fatal("failed exporting match: ", err)
+		}
+		if err := file.Close(); err != nil {
+			log.Fatal("failed writing archive: ", err)
+		}
+
+		log.Println("ok, exported game", manifest.GameNumber, "to", output)
+	},
+}
+
+func init() {
+	exportCmd.Flags().StringVarP(&exportCmdOutput, "output", "o", "", "Archive to write (defaults to [game number].tar.gz)")
+	exportCmd.Flags().BoolVar(&exportCmdIncludeCredentials, "include-credentials", false, "Keep API keys, Discord IDs and access codes, for moving a game between servers")
+}
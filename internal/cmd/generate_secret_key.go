@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+	"go.albinodrought.com/neptunes-pride/internal/secrets"
+)
+
+var generateSecretKeyCmd = &cobra.Command{
+	Use:   "generate-secret-key [path]",
+	Short: "Generate a key for encrypting API keys and Discord IDs, printed or written to a new file",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		key, err := secrets.GenerateKey()
+		if err != nil {
+			log.Fatal("failed to generate secret key: ", err)
+		}
+
+		if len(args) == 0 {
+			fmt.Println(key)
+			return
+		}
+
+		// never overwrite a key, secrets encrypted with it would be lost
+		file, err := os.OpenFile(args[0], os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			log.Fatal("failed to create key file: ", err)
+		}
+		if _, err := file.WriteString(key + "\n"); err != nil {
+			file.Close()
+			log.Fatal("failed to write key file: ", err)
+		}
+		if err := file.Close(); err != nil {
+			log.Fatal("failed to write key file: ", err)
+		}
+
+		log.Println("ok, wrote secret key to", args[0])
+	},
+}
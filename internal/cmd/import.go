@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+	"go.albinodrought.com/neptunes-pride/internal/matchstore"
+)
+
+var importCmdOverwrite bool
+
+var importCmd = &cobra.Command{
+	Use:   "import [archive]",
+	Short: "Restore a match archived with `np-scanner export`",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		db, err := openDB()
+		if err != nil {
+			log.Fatal("failed to open DB: ", err)
+		}
+
+		file, err := os.Open(args[0])
+		if err != nil {
+			log.Fatal("failed to open archive: ", err)
+		}
+		defer file.Close()
+
+		manifest, err := matchstore.Import(db, file, &matchstore.ImportOptions{
+			Overwrite: importCmdOverwrite,
+			Log:       log.Println,
+		})
+		if err != nil {
+			log.Fatal("failed importing match: ", err)
+		}
+
+		if manifest.CredentialsStripped {
+			log.Println("credentials were stripped from this archive, add players with `np-scanner set` to keep polling")
+		}
+		log.Println("ok, imported game", manifest.GameNumber)
+	},
+}
+
+func init() {
+	importCmd.Flags().BoolVar(&importCmdOverwrite, "overwrite", false, "Merge into a match that already exists")
+}
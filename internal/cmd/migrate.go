@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"log"
+
+	"github.com/spf13/cobra"
+	"go.albinodrought.com/neptunes-pride/internal/matchstore"
+)
+
+var migrateCmdBackup bool
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Upgrade the bolt database to the latest schema",
+	Args:  cobra.MaximumNArgs(0),
+	Run: func(cmd *cobra.Command, args []string) {
+		if matchStoreBackend != "bolt" {
+			log.Println("only bolt databases have schema migrations, nothing to do for", matchStoreBackend)
+			return
+		}
+
+		keyring, err := loadKeyring()
+		if err != nil {
+			log.Fatal("failed to load secret key: ", err)
+		}
+
+		options := matchstore.DefaultBoltOptions
+		options.Backup = migrateCmdBackup
+		options.Secrets = keyring
+
+		applied, err := matchstore.Migrate(matchStoreDbPath, &options)
+		if err != nil {
+			log.Fatal("failed to migrate DB: ", err)
+		}
+
+		if len(applied) == 0 {
+			log.Println("already at schema version", matchstore.SchemaVersion)
+			return
+		}
+		log.Println("ok, ran", len(applied), "migrations, now at schema version", matchstore.SchemaVersion)
+	},
+}
+
+func init() {
+	migrateCmd.Flags().BoolVar(&migrateCmdBackup, "backup", matchstore.DefaultBoltOptions.Backup, "Copy the database next to itself before migrating")
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"io"
+	"log"
+
+	"github.com/spf13/cobra"
+	"go.albinodrought.com/neptunes-pride/internal/matchstore"
+)
+
+var (
+	migrateStoreCmdFrom     string
+	migrateStoreCmdFromPath string
+	migrateStoreCmdTo       string
+	migrateStoreCmdToPath   string
+)
+
+var migrateStoreCmd = &cobra.Command{
+	Use:   "migrate-store",
+	Short: "Copy every match, snapshot, stat and report into a store of another backend",
+	Args:  cobra.MaximumNArgs(0),
+	Run: func(cmd *cobra.Command, args []string) {
+		fromPath := migrateStoreCmdFromPath
+		if fromPath == "" {
+			fromPath = matchStoreDbPath
+		}
+
+		if migrateStoreCmdFrom == "memory" || migrateStoreCmdTo == "memory" {
+			log.Fatal(errMemoryBackend)
+		}
+
+		if fromPath == migrateStoreCmdToPath {
+			log.Fatal("refusing to migrate a store into itself: ", fromPath)
+		}
+
+		from, err := matchstore.OpenBackend(migrateStoreCmdFrom, fromPath)
+		if err != nil {
+			log.Fatal("failed to open source DB: ", err)
+		}
+
+		to, err := matchstore.OpenBackend(migrateStoreCmdTo, migrateStoreCmdToPath)
+		if err != nil {
+			log.Fatal("failed to open target DB: ", err)
+		}
+
+		if err := matchstore.Copy(from, to, log.Println); err != nil {
+			log.Fatal("failed to migrate store: ", err)
+		}
+
+		// flush the sqlite write-ahead log before exiting
+		if closer, ok := to.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				log.Fatal("failed to close target DB: ", err)
+			}
+		}
+
+		log.Println("ok, use it with --db-backend", migrateStoreCmdTo, "--db-path", migrateStoreCmdToPath)
+	},
+}
+
+func init() {
+	migrateStoreCmd.Flags().StringVar(&migrateStoreCmdFrom, "from", "bolt", "Backend to copy from")
+	migrateStoreCmd.Flags().StringVar(&migrateStoreCmdFromPath, "from-path", "", "Database path to copy from (defaults to --db-path)")
+	migrateStoreCmd.Flags().StringVar(&migrateStoreCmdTo, "to", "sqlite", "Backend to copy to")
+	migrateStoreCmd.Flags().StringVar(&migrateStoreCmdToPath, "to-path", "np.sqlite", "Database path to copy to")
+}
@@ -27,8 +27,9 @@ var pollCmd = &cobra.Command{
 		client := openClient()
 
 		pollOptions := &actions.PollOptions{
-			Force:         pollCmdForce,
-			MinTimePassed: pollCmdMinTimePassed,
+			Force:               pollCmdForce,
+			MinTimePassed:       pollCmdMinTimePassed,
+			TurnResolutionDelay: actions.DefaultPollOptions.TurnResolutionDelay,
 		}
 
 		if len(args) == 1 && args[0] == "all" {
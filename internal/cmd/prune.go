@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"log"
+
+	"github.com/spf13/cobra"
+	"go.albinodrought.com/neptunes-pride/internal/matchstore"
+)
+
+var (
+	pruneCmdDryRun    bool
+	pruneCmdRetention matchstore.RetentionPolicy
+)
+
+func addRetentionFlags(cmd *cobra.Command, policy *matchstore.RetentionPolicy) {
+	cmd.Flags().DurationVar(&policy.KeepAll, "keep-all", matchstore.DefaultRetentionPolicy.KeepAll, "Keep every snapshot this recent")
+	cmd.Flags().DurationVar(&policy.PerTick, "keep-per-tick", matchstore.DefaultRetentionPolicy.PerTick, "After keep-all, keep one snapshot per tick for this long, then one per production")
+}
+
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Downsample old snapshots according to the retention policy",
+	Args:  cobra.MaximumNArgs(0),
+	Run: func(cmd *cobra.Command, args []string) {
+		db, err := openDB()
+		if err != nil {
+			log.Fatal("failed to open DB", err)
+		}
+
+		results, err := db.PruneSnapshots(pruneCmdRetention, pruneCmdDryRun, log.Println)
+		if err != nil {
+			log.Fatal("failed to prune snapshots", err)
+		}
+
+		total, removed, rewritten := 0, 0, 0
+		for _, result := range results {
+			total += result.Total
+			removed += result.Removed
+			rewritten += result.Rewritten
+		}
+
+		if pruneCmdDryRun {
+			log.Println("dry run, would remove", removed, "of", total, "snapshots and rewrite", rewritten)
+			return
+		}
+		log.Println("removed", removed, "of", total, "snapshots and rewrote", rewritten)
+	},
+}
+
+func init() {
+	pruneCmd.Flags().BoolVar(&pruneCmdDryRun, "dry-run", false, "Report what would be removed without removing anything")
+	addRetentionFlags(pruneCmd, &pruneCmdRetention)
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"log"
+
+	"github.com/spf13/cobra"
+	"go.albinodrought.com/neptunes-pride/internal/actions"
+)
+
+var reportCmdOutput string
+
+var reportCmd = &cobra.Command{
+	Use:   "report [game number]",
+	Short: "Generate and store an end-of-game report",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		db, err := openDB()
+		if err != nil {
+			log.Fatal("failed to open DB: ", err)
+		}
+
+		match, err := db.FindMatchOrFail(args[0])
+		if err != nil {
+			log.Fatal("failed finding match: ", err)
+		}
+
+		report, err := actions.GenerateReport(db, match)
+		if err != nil {
+			log.Fatal("failed generating report: ", err)
+		}
+
+		if reportCmdOutput != "" {
+			err = ioutil.WriteFile(reportCmdOutput, report, 0644)
+			if err != nil {
+				log.Fatal("failed writing report: ", err)
+			}
+		}
+
+		log.Println("generated report for game", match.GameNumber)
+	},
+}
+
+func init() {
+	reportCmd.Flags().StringVar(&reportCmdOutput, "output", "", "Also write the HTML report to this file")
+}
@@ -18,11 +18,27 @@ func Execute() error {
 
 func init() {
 	addGlobalConfigFlags(rootCmd)
+	rootCmd.AddCommand(adviseCmd)
+	rootCmd.AddCommand(backfillStatsCmd)
 	rootCmd.AddCommand(compressSnapshotsCmd)
+	rootCmd.AddCommand(coverageCmd)
+	rootCmd.AddCommand(deltaEncodeSnapshotsCmd)
 	rootCmd.AddCommand(disablePlayerCmd)
+	rootCmd.AddCommand(dossierCmd)
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(generateSecretKeyCmd)
+	rootCmd.AddCommand(importCmd)
+	rootCmd.AddCommand(migrateCmd)
+	rootCmd.AddCommand(migrateStoreCmd)
 	rootCmd.AddCommand(pollCmd)
 	rootCmd.AddCommand(protectCmd)
+	rootCmd.AddCommand(pruneCmd)
+	rootCmd.AddCommand(reportCmd)
+	rootCmd.AddCommand(rotateSecretKeyCmd)
 	rootCmd.AddCommand(setCmd)
 	rootCmd.AddCommand(setDiscordCmd)
 	rootCmd.AddCommand(serveCmd)
+	rootCmd.AddCommand(targetsCmd)
+	rootCmd.AddCommand(tradesCmd)
+	rootCmd.AddCommand(victoryCmd)
 }
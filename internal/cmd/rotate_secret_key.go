@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"log"
+
+	"github.com/spf13/cobra"
+	"go.albinodrought.com/neptunes-pride/internal/matchstore"
+)
+
+var rotateSecretKeyCmdOldKeyFiles []string
+
+var rotateSecretKeyCmd = &cobra.Command{
+	Use:   "rotate-secret-key",
+	Short: "Encrypt every match's API keys and Discord IDs with the current secret key",
+	Long: "Encrypt every match's API keys and Discord IDs with the current secret key.\n" +
+		"Secrets encrypted with an old key are rewrapped, pass the old key with --old-key-file.\n" +
+		"Secrets still in plain text are encrypted.\n" +
+		"The database is compacted afterwards, so replaced secrets don't linger in free space.",
+	Args: cobra.MaximumNArgs(0),
+	Run: func(cmd *cobra.Command, args []string) {
+		keyring, err := loadKeyring(rotateSecretKeyCmdOldKeyFiles...)
+		if err != nil {
+			log.Fatal("failed to load secret keys: ", err)
+		}
+		if keyring == nil {
+			log.Fatal("no secret key configured, pass --secret-key-file or set NP_SCANNER_SECRET_KEY")
+		}
+
+		db, err := openDBWithKeyring(keyring)
+		if err != nil {
+			log.Fatal("failed to open DB: ", err)
+		}
+
+		resealed, err := matchstore.ResealSecrets(db, keyring, log.Println)
+		if err != nil {
+			log.Fatal("failed to reseal secrets: ", err)
+		}
+
+		if resealed > 0 {
+			if err := matchstore.CompactSecrets(db); err != nil {
+				log.Fatal("failed to compact DB: ", err)
+			}
+		}
+
+		log.Println("ok, resealed", resealed, "matches with key", keyring.PrimaryKeyID())
+	},
+}
+
+func init() {
+	rotateSecretKeyCmd.Flags().StringArrayVar(&rotateSecretKeyCmdOldKeyFiles, "old-key-file", []string{}, "Key file secrets are currently encrypted with, can be repeated")
+}
@@ -3,29 +3,75 @@ package cmd
 import (
 	"context"
 	"log"
+	"os"
 	"time"
 
 	"github.com/spf13/cobra"
+	"go.albinodrought.com/neptunes-pride/internal/matchstore"
+	"go.albinodrought.com/neptunes-pride/internal/notifications"
 	"go.albinodrought.com/neptunes-pride/internal/web"
 )
 
 var (
-	serveCmdAddress    string
-	serveCmdPollPeriod time.Duration
+	serveCmdAddress     string
+	serveCmdPollPeriod  time.Duration
+	serveCmdPrunePeriod time.Duration
+	serveCmdRetention   matchstore.RetentionPolicy
+	serveCmdEphemeral   bool
+	serveCmdSeeds       []string
 )
 
+// seedStore imports match archives into an ephemeral store
+func seedStore(db matchstore.MatchStore, paths []string) error {
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+
+		manifest, err := matchstore.Import(db, file, &matchstore.ImportOptions{
+			Overwrite: true,
+			Log:       log.Println,
+		})
+		file.Close()
+		if err != nil {
+			return err
+		}
+		log.Println("seeded game", manifest.GameNumber, "from", path)
+	}
+	return nil
+}
+
 var serveCmd = &cobra.Command{
 	Use:   "serve",
 	Short: "Serve the API and web UI",
 	Run: func(cmd *cobra.Command, args []string) {
-		db, err := openDB()
-		if err != nil {
-			log.Fatal("failed to open DB", err)
+		var db matchstore.MatchStore
+		var guard notifications.Guard
+		var err error
+
+		if len(serveCmdSeeds) > 0 && !serveCmdEphemeral {
+			log.Fatal("--seed only works with --ephemeral, use `np-scanner import` to restore into a database")
 		}
 
-		guard, err := openNotificationGuard()
-		if err != nil {
-			log.Fatal("failed to open notification guard", err)
+		if serveCmdEphemeral {
+			log.Println("ephemeral: matches, snapshots and sent notifications are lost on exit")
+			db = matchstore.OpenMemory()
+			guard = notifications.NewMemoryGuard()
+
+			if err := seedStore(db, serveCmdSeeds); err != nil {
+				log.Fatal("failed to seed ephemeral store: ", err)
+			}
+		} else {
+			db, err = openDB()
+			if err != nil {
+				log.Fatal("failed to open DB", err)
+			}
+
+			guard, err = openNotificationGuard()
+			if err != nil {
+				log.Fatal("failed to open notification guard", err)
+			}
 		}
 
 		sinks := buildSinks()
@@ -33,8 +79,10 @@ var serveCmd = &cobra.Command{
 		client := openClient()
 
 		err = web.Run(context.Background(), db, client, guard, sinks, &web.WebOptions{
-			Address:    serveCmdAddress,
-			PollPeriod: serveCmdPollPeriod,
+			Address:     serveCmdAddress,
+			PollPeriod:  serveCmdPollPeriod,
+			PrunePeriod: serveCmdPrunePeriod,
+			Retention:   serveCmdRetention,
 		})
 		if err != nil {
 			log.Fatal(err)
@@ -45,4 +93,8 @@ var serveCmd = &cobra.Command{
 func init() {
 	serveCmd.Flags().StringVar(&serveCmdAddress, "address", web.DefaultWebOptions.Address, "Address to listen on")
 	serveCmd.Flags().DurationVar(&serveCmdPollPeriod, "poll-period", web.DefaultWebOptions.PollPeriod, "Check for match updates this often")
+	serveCmd.Flags().DurationVar(&serveCmdPrunePeriod, "prune-period", web.DefaultWebOptions.PrunePeriod, "Prune old snapshots this often (0 to disable)")
+	addRetentionFlags(serveCmd, &serveCmdRetention)
+	serveCmd.Flags().BoolVar(&serveCmdEphemeral, "ephemeral", false, "Keep everything in memory instead of the databases, for demos")
+	serveCmd.Flags().StringArrayVar(&serveCmdSeeds, "seed", []string{}, "Match archive written by np-scanner export to load into the ephemeral store, can be repeated")
 }
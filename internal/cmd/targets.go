@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"go.albinodrought.com/neptunes-pride/internal/actions"
+	"go.albinodrought.com/neptunes-pride/internal/matches"
+	"go.albinodrought.com/neptunes-pride/internal/targets"
+)
+
+var (
+	targetsCmdMaxTicks int
+	targetsCmdLimit    int
+)
+
+var targetsCmd = &cobra.Command{
+	Use:   "targets [game number] [player uid]",
+	Short: "Rank the stars a player could capture by value and cost",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		db, err := openDB()
+		if err != nil {
+			log.Fatal("failed to open DB: ", err)
+		}
+
+		match, err := db.FindMatchOrFail(args[0])
+		if err != nil {
+			log.Fatal("failed finding match: ", err)
+		}
+
+		playerUID, err := strconv.Atoi(args[1])
+		if err != nil {
+			log.Fatal("failed parsing player uid: ", err)
+		}
+
+		accessProfile := matches.PermissiveAccessProfile()
+
+		merged, err := actions.MergedSnapshot(db, match, accessProfile, map[string]string{}, nil)
+		if err != nil {
+			log.Fatal("failed merging snapshot: ", err)
+		}
+
+		options := targets.DefaultOptions
+		options.MaxTicks = targetsCmdMaxTicks
+		options.Limit = targetsCmdLimit
+
+		for i, target := range actions.FindTargets(match, accessProfile, merged, playerUID, &options) {
+			owner := target.OwnerAlias
+			if owner == "" {
+				owner = "unowned"
+			}
+			scanned := ""
+			if !target.Scanned {
+				scanned = " (not scanned)"
+			}
+			fmt.Printf(
+				"%3d. %v (#%v, %v)%v: score %.2f, %v ticks away, needs %v ships vs %v at weapons %v\n",
+				i+1,
+				target.StarName,
+				target.StarUID,
+				owner,
+				scanned,
+				target.Score,
+				target.Route.Ticks,
+				target.ShipsNeeded,
+				target.ProjectedGarrison,
+				target.DefenderWeapons,
+			)
+		}
+	},
+}
+
+func init() {
+	targetsCmd.Flags().IntVar(&targetsCmdMaxTicks, "max-ticks", targets.DefaultOptions.MaxTicks, "Ignore targets further away than this many ticks (0 for no limit)")
+	targetsCmd.Flags().IntVar(&targetsCmdLimit, "limit", targets.DefaultOptions.Limit, "Show at most this many targets (0 for no limit)")
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+	"go.albinodrought.com/neptunes-pride/internal/actions"
+	"go.albinodrought.com/neptunes-pride/internal/matches"
+	"go.albinodrought.com/neptunes-pride/internal/research"
+	"go.albinodrought.com/neptunes-pride/internal/trades"
+)
+
+var tradesCmd = &cobra.Command{
+	Use:   "trades [game number]",
+	Short: "Suggest tech trades between allies for this production",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		db, err := openDB()
+		if err != nil {
+			log.Fatal("failed to open DB: ", err)
+		}
+
+		match, err := db.FindMatchOrFail(args[0])
+		if err != nil {
+			log.Fatal("failed finding match: ", err)
+		}
+
+		accessProfile := matches.PermissiveAccessProfile()
+
+		merged, err := actions.MergedSnapshot(db, match, accessProfile, map[string]string{}, nil)
+		if err != nil {
+			log.Fatal("failed merging snapshot: ", err)
+		}
+
+		plan := trades.Find(merged, actions.AllyIDs(match, accessProfile), nil)
+		fmt.Printf("production %v, trade cost $%v per level\n", plan.Productions, plan.TradeCost)
+		for _, trade := range plan.Trades {
+			affordable := ""
+			if !trade.Affordable {
+				affordable = " (can't afford yet)"
+			}
+			fmt.Printf(
+				"  %v sends %v %v to %v for $%v, %v%v\n",
+				trade.FromAlias,
+				research.NiceName(trade.Tech),
+				trade.Level,
+				trade.ToAlias,
+				trade.Cost,
+				trade.Reason,
+				affordable,
+			)
+		}
+	},
+}
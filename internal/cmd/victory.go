@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.albinodrought.com/neptunes-pride/internal/actions"
+	"go.albinodrought.com/neptunes-pride/internal/matches"
+)
+
+var victoryCmdHistoryLimit int
+
+var victoryCmd = &cobra.Command{
+	Use:   "victory [game number]",
+	Short: "Show every player's progress towards victory and who is projected to win",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		db, err := openDB()
+		if err != nil {
+			log.Fatal("failed to open DB: ", err)
+		}
+
+		match, err := db.FindMatchOrFail(args[0])
+		if err != nil {
+			log.Fatal("failed finding match: ", err)
+		}
+
+		accessProfile := matches.PermissiveAccessProfile()
+
+		merged, err := actions.MergedSnapshot(db, match, accessProfile, map[string]string{}, nil)
+		if err != nil {
+			log.Fatal("failed merging snapshot: ", err)
+		}
+
+		progress, err := actions.VictoryProgress(db, match, accessProfile, merged, victoryCmdHistoryLimit)
+		if err != nil {
+			log.Fatal("failed charting victory progress: ", err)
+		}
+
+		fmt.Printf("%v stars needed for victory, %v in the galaxy\n", progress.StarsForVictory, progress.TotalStars)
+		for _, player := range progress.Players {
+			projection := "not gaining stars"
+			if player.TicksToVictory >= 0 {
+				projection = fmt.Sprintf("wins in %v ticks (%v)", player.TicksToVictory, time.Unix(0, player.VictoryAt*int64(time.Millisecond)).Format(time.RFC1123))
+			}
+			fmt.Printf(
+				"  %v: %v stars (%.1f%%), %+.2f stars per tick, %v\n",
+				player.PlayerAlias,
+				player.Stars,
+				player.Percent,
+				player.StarsPerTick,
+				projection,
+			)
+		}
+
+		if progress.ProjectedWinnerUID == -1 {
+			fmt.Println("nobody is on track to win")
+		} else {
+			fmt.Printf("projected winner: %v\n", progress.ProjectedWinnerAlias)
+		}
+	},
+}
+
+func init() {
+	victoryCmd.Flags().IntVar(&victoryCmdHistoryLimit, "history-limit", actions.DefaultVictoryHistoryLimit, "Chart this many stored snapshots per player for games without stats")
+}
@@ -0,0 +1,155 @@
+package diplomacy
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"go.albinodrought.com/neptunes-pride/internal/types"
+)
+
+// War states as found in PrivatePlayer.War
+const (
+	StatePeace = 0
+	StateWar   = 3
+)
+
+// StateName describes a war state
+func StateName(state int) string {
+	switch state {
+	case StatePeace:
+		return "peace"
+	case StateWar:
+		return "war"
+	}
+	return fmt.Sprintf("state %v", state)
+}
+
+// Relation is one player's war state towards another, as seen from the first player's key
+type Relation struct {
+	FromUID   int    `json:"from_uid"`
+	FromAlias string `json:"from_alias"`
+	ToUID     int    `json:"to_uid"`
+	ToAlias   string `json:"to_alias"`
+	State     int    `json:"state"`
+	StateName string `json:"state_name"`
+	// CountdownToWar is the ticks until war breaks out, 0 if no war is pending
+	CountdownToWar int `json:"countdown_to_war"`
+}
+
+// Matrix lists the war state of every pair we know about, only keyed players expose theirs.
+// Sorted by from, then to.
+func Matrix(resp *types.APIResponse) []Relation {
+	players := resp.ScanningData.Players
+	relations := []Relation{}
+
+	for _, from := range players {
+		if from.War == nil {
+			continue
+		}
+
+		for toIndex, state := range from.War {
+			to, ok := players[toIndex]
+			if !ok || to.UID == from.UID {
+				continue
+			}
+
+			relation := Relation{
+				FromUID:   from.UID,
+				FromAlias: from.Alias,
+				ToUID:     to.UID,
+				ToAlias:   to.Alias,
+				State:     state,
+				StateName: StateName(state),
+			}
+			if from.CountdownToWar != nil {
+				relation.CountdownToWar = from.CountdownToWar[toIndex]
+			}
+
+			relations = append(relations, relation)
+		}
+	}
+
+	sort.Slice(relations, func(i, j int) bool {
+		if relations[i].FromUID != relations[j].FromUID {
+			return relations[i].FromUID < relations[j].FromUID
+		}
+		return relations[i].ToUID < relations[j].ToUID
+	})
+
+	return relations
+}
+
+const (
+	// ChangeState means the war state between two players changed
+	ChangeState = "state"
+	// ChangeCountdown means a countdown to war started
+	ChangeCountdown = "countdown"
+)
+
+type Change struct {
+	Relation
+	Kind          string `json:"kind"`
+	PreviousState int    `json:"previous_state"`
+}
+
+type pair struct {
+	low  int
+	high int
+	kind string
+}
+
+func newPair(a int, b int, kind string) pair {
+	if a > b {
+		a, b = b, a
+	}
+	return pair{a, b, kind}
+}
+
+// Changes finds war states that changed and countdowns that started between two snapshots.
+// When both players are keyed the change is only reported once.
+func Changes(previous *types.APIResponse, current *types.APIResponse) []Change {
+	changes := []Change{}
+	seen := map[pair]bool{}
+
+	for _, relation := range Matrix(current) {
+		previousPlayer, ok := previous.ScanningData.Players[strconv.Itoa(relation.FromUID)]
+		if !ok || previousPlayer.War == nil {
+			// we didn't know this player's war state before, nothing to compare against
+			continue
+		}
+
+		toIndex := strconv.Itoa(relation.ToUID)
+		previousState, ok := previousPlayer.War[toIndex]
+		if !ok {
+			continue
+		}
+		previousCountdown := 0
+		if previousPlayer.CountdownToWar != nil {
+			previousCountdown = previousPlayer.CountdownToWar[toIndex]
+		}
+
+		kind := ""
+		if relation.State != previousState {
+			kind = ChangeState
+		} else if relation.CountdownToWar > 0 && previousCountdown <= 0 {
+			kind = ChangeCountdown
+		} else {
+			continue
+		}
+
+		key := newPair(relation.FromUID, relation.ToUID, kind)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		changes = append(changes, Change{
+			Relation:      relation,
+			Kind:          kind,
+			PreviousState: previousState,
+		})
+	}
+
+	return changes
+}
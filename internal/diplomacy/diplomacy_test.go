@@ -0,0 +1,50 @@
+package diplomacy
+
+import (
+	"testing"
+
+	"go.albinodrought.com/neptunes-pride/internal/types"
+)
+
+func TestChanges(t *testing.T) {
+	snapshot := func(war map[string]map[string]int, countdown map[string]map[string]int) *types.APIResponse {
+		resp := &types.APIResponse{}
+		resp.ScanningData.Players = map[string]types.Player{}
+		for i, uid := range []string{"0", "1", "2"} {
+			player := types.Player{}
+			player.UID = i
+			player.Alias = "player " + uid
+			player.War = war[uid]
+			player.CountdownToWar = countdown[uid]
+			resp.ScanningData.Players[uid] = player
+		}
+		return resp
+	}
+
+	previous := snapshot(map[string]map[string]int{
+		"0": {"1": StatePeace, "2": StatePeace},
+		"1": {"0": StatePeace, "2": StatePeace},
+	}, nil)
+	current := snapshot(map[string]map[string]int{
+		"0": {"1": StateWar, "2": StatePeace},
+		"1": {"0": StateWar, "2": StatePeace},
+	}, map[string]map[string]int{
+		"1": {"2": 24},
+	})
+
+	if matrix := Matrix(current); len(matrix) != 4 {
+		t.Errorf("expected 4 relations, got %+v", matrix)
+	}
+
+	changes := Changes(previous, current)
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changes, got %+v", changes)
+	}
+
+	if changes[0].Kind != ChangeState || changes[0].FromUID != 0 || changes[0].ToUID != 1 || changes[0].State != StateWar {
+		t.Errorf("expected players 0 and 1 to go to war once, got %+v", changes[0])
+	}
+	if changes[1].Kind != ChangeCountdown || changes[1].FromUID != 1 || changes[1].ToUID != 2 || changes[1].CountdownToWar != 24 {
+		t.Errorf("expected a countdown between players 1 and 2, got %+v", changes[1])
+	}
+}
@@ -0,0 +1,173 @@
+package dossier
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.albinodrought.com/neptunes-pride/internal/opsec"
+	"go.albinodrought.com/neptunes-pride/internal/research"
+	"go.albinodrought.com/neptunes-pride/internal/types"
+)
+
+// prioritiesPerGame is how many of a player's highest techs count as their priorities
+const prioritiesPerGame = 3
+
+type Game struct {
+	GameNumber string `json:"game_number"`
+	MatchName  string `json:"match_name"`
+	Finished   bool   `json:"finished"`
+	PlayerUID  int    `json:"player_uid"`
+	Alias      string `json:"alias"`
+	// Rank by stars, then strength, as of the latest snapshot
+	Rank            int            `json:"rank"`
+	Players         int            `json:"players"`
+	TotalStars      int            `json:"total_stars"`
+	StarsForVictory int            `json:"stars_for_victory"`
+	Tech            map[string]int `json:"tech"`
+	// TechPriorities are the player's highest techs, highest first
+	TechPriorities []string `json:"tech_priorities"`
+	// AttacksLaunched counts distinct carrier attacks seen across stored snapshots
+	AttacksLaunched int  `json:"attacks_launched"`
+	Conceded        bool `json:"conceded"`
+	AI              bool `json:"ai"`
+}
+
+type Dossier struct {
+	Alias           string   `json:"alias"`
+	GamesPlayed     int      `json:"games_played"`
+	GamesWon        int      `json:"games_won"`
+	GamesConceded   int      `json:"games_conceded"`
+	AverageRank     float64  `json:"average_rank"`
+	AttacksLaunched int      `json:"attacks_launched"`
+	FavoriteTechs   []string `json:"favorite_techs"`
+	Games           []Game   `json:"games"`
+}
+
+// FindPlayer finds a player by alias, ignoring case
+func FindPlayer(resp *types.APIResponse, alias string) (types.Player, bool) {
+	for _, player := range resp.ScanningData.Players {
+		if strings.EqualFold(player.Alias, alias) {
+			return player, true
+		}
+	}
+	return types.Player{}, false
+}
+
+// Rank of a player by stars then strength, 1 is first place
+func Rank(resp *types.APIResponse, playerUID int) (int, int) {
+	players := make([]types.Player, 0, len(resp.ScanningData.Players))
+	for _, player := range resp.ScanningData.Players {
+		players = append(players, player)
+	}
+
+	sort.Slice(players, func(i, j int) bool {
+		if players[i].TotalStars != players[j].TotalStars {
+			return players[i].TotalStars > players[j].TotalStars
+		}
+		if players[i].TotalStrength != players[j].TotalStrength {
+			return players[i].TotalStrength > players[j].TotalStrength
+		}
+		return players[i].UID < players[j].UID
+	})
+
+	for i, player := range players {
+		if player.UID == playerUID {
+			return i + 1, len(players)
+		}
+	}
+	return 0, len(players)
+}
+
+// Attacks are keyed by carrier and target star, so the same attack seen in many snapshots counts once
+type Attacks map[string]bool
+
+// Record the attacks a player has in flight
+func (attacks Attacks) Record(resp *types.APIResponse, playerUID int) {
+	for _, threat := range opsec.FindThreats(resp) {
+		if threat.FleetOwner.UID != playerUID {
+			continue
+		}
+		attacks[fmt.Sprintf("%v-%v", threat.Fleet.UID, threat.TargetStarID)] = true
+	}
+}
+
+// NewGame describes how a player did in a game, from the latest snapshot
+func NewGame(gameNumber string, matchName string, finished bool, resp *types.APIResponse, player types.Player, attacks Attacks) Game {
+	game := Game{
+		GameNumber:      gameNumber,
+		MatchName:       matchName,
+		Finished:        finished,
+		PlayerUID:       player.UID,
+		Alias:           player.Alias,
+		TotalStars:      player.TotalStars,
+		StarsForVictory: resp.ScanningData.StarsForVictory,
+		Tech:            map[string]int{},
+		TechPriorities:  []string{},
+		AttacksLaunched: len(attacks),
+		Conceded:        player.Conceded != types.ConcededNo,
+		AI:              player.Ai != 0,
+	}
+	game.Rank, game.Players = Rank(resp, player.UID)
+
+	for _, tech := range research.Techs {
+		status, _ := research.Status(player.Tech, tech)
+		game.Tech[tech] = status.Level
+	}
+
+	priorities := append([]string{}, research.Techs...)
+	sort.SliceStable(priorities, func(i, j int) bool {
+		return game.Tech[priorities[i]] > game.Tech[priorities[j]]
+	})
+	if len(priorities) > prioritiesPerGame {
+		priorities = priorities[:prioritiesPerGame]
+	}
+	game.TechPriorities = priorities
+
+	return game
+}
+
+// Summarize a player's games, unfinished games count towards everything but wins
+func Summarize(alias string, games []Game) Dossier {
+	dossier := Dossier{
+		Alias:         alias,
+		GamesPlayed:   len(games),
+		FavoriteTechs: []string{},
+		Games:         games,
+	}
+
+	sort.Slice(dossier.Games, func(i, j int) bool {
+		return dossier.Games[i].GameNumber < dossier.Games[j].GameNumber
+	})
+
+	ranks := 0
+	priorityCounts := map[string]int{}
+	for _, game := range games {
+		if game.Finished && game.Rank == 1 {
+			dossier.GamesWon++
+		}
+		if game.Conceded {
+			dossier.GamesConceded++
+		}
+		ranks += game.Rank
+		dossier.AttacksLaunched += game.AttacksLaunched
+		for _, tech := range game.TechPriorities {
+			priorityCounts[tech]++
+		}
+	}
+
+	if len(games) > 0 {
+		dossier.AverageRank = float64(ranks) / float64(len(games))
+	}
+
+	for _, tech := range research.Techs {
+		if priorityCounts[tech] > 0 {
+			dossier.FavoriteTechs = append(dossier.FavoriteTechs, tech)
+		}
+	}
+	sort.SliceStable(dossier.FavoriteTechs, func(i, j int) bool {
+		return priorityCounts[dossier.FavoriteTechs[i]] > priorityCounts[dossier.FavoriteTechs[j]]
+	})
+
+	return dossier
+}
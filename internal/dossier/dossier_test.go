@@ -0,0 +1,61 @@
+package dossier
+
+import (
+	"strconv"
+	"testing"
+
+	"go.albinodrought.com/neptunes-pride/internal/research"
+	"go.albinodrought.com/neptunes-pride/internal/types"
+)
+
+func TestNewGameAndSummarize(t *testing.T) {
+	resp := &types.APIResponse{}
+	resp.ScanningData.StarsForVictory = 50
+	resp.ScanningData.Players = map[string]types.Player{}
+	for i, stars := range []int{10, 30, 20} {
+		player := types.Player{}
+		player.UID = i
+		player.TotalStars = stars
+		resp.ScanningData.Players[strconv.Itoa(i)] = player
+	}
+
+	player := resp.ScanningData.Players["2"]
+	player.Alias = "Burrito"
+	player.Tech.Weapons.Level = 9
+	player.Tech.Banking.Level = 5
+	player.Tech.Scanning.Level = 3
+	player.Conceded = types.ConcededYes
+	resp.ScanningData.Players["2"] = player
+
+	found, ok := FindPlayer(resp, "burrito")
+	if !ok || found.UID != 2 {
+		t.Fatalf("expected to find burrito case-insensitively, got %+v %v", found, ok)
+	}
+
+	game := NewGame("123", "test game", true, resp, found, Attacks{"1-2": true, "3-4": true})
+	if game.Rank != 2 || game.Players != 3 {
+		t.Errorf("expected rank 2 of 3, got %v of %v", game.Rank, game.Players)
+	}
+	expectedPriorities := []string{research.TechWeapons, research.TechBanking, research.TechScanning}
+	for i, tech := range expectedPriorities {
+		if game.TechPriorities[i] != tech {
+			t.Errorf("expected priority %v to be %v, got %v", i, tech, game.TechPriorities)
+		}
+	}
+
+	winner := game
+	winner.GameNumber = "456"
+	winner.Rank = 1
+	winner.Conceded = false
+
+	dossier := Summarize("Burrito", []Game{winner, game})
+	if dossier.GamesPlayed != 2 || dossier.GamesWon != 1 || dossier.GamesConceded != 1 {
+		t.Errorf("unexpected totals: %+v", dossier)
+	}
+	if dossier.AverageRank != 1.5 || dossier.AttacksLaunched != 4 {
+		t.Errorf("unexpected rank or attacks: %+v", dossier)
+	}
+	if dossier.Games[0].GameNumber != "123" {
+		t.Errorf("expected games to be sorted by game number, got %+v", dossier.Games)
+	}
+}
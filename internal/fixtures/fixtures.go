@@ -0,0 +1,26 @@
+package fixtures
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"go.albinodrought.com/neptunes-pride/internal/types"
+)
+
+// Load reads an API response for a test, every call returns a fresh copy to modify
+func Load(t testing.TB, path string) *types.APIResponse {
+	t.Helper()
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response := &types.APIResponse{}
+	if err := json.Unmarshal(data, response); err != nil {
+		t.Fatal(err)
+	}
+
+	return response
+}
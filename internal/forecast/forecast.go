@@ -0,0 +1,146 @@
+package forecast
+
+import (
+	"math"
+	"sort"
+
+	"go.albinodrought.com/neptunes-pride/internal/types"
+)
+
+// IncomePerEconomy is the cash each economy level earns per production
+const IncomePerEconomy = 10
+
+// IncomePerBanking is the extra cash each banking level earns per production
+const IncomePerBanking = 75
+
+// Income a player earns at every production
+func Income(player types.Player) int {
+	return player.TotalEconomy*IncomePerEconomy + player.Tech.Banking.Level*IncomePerBanking
+}
+
+// ShipsPerProduction built by a star over a full production cycle
+func ShipsPerProduction(industry int, manufacturingLevel int) int {
+	return industry * (manufacturingLevel + 5)
+}
+
+// ShipsPerTick built by a star
+func ShipsPerTick(industry int, manufacturingLevel int, productionRate int) float64 {
+	if productionRate <= 0 {
+		return 0
+	}
+	return float64(ShipsPerProduction(industry, manufacturingLevel)) / float64(productionRate)
+}
+
+type StarForecast struct {
+	StarUID  int    `json:"star_uid"`
+	StarName string `json:"star_name"`
+	Ships    int    `json:"ships"`
+}
+
+type PlayerForecast struct {
+	PlayerUID   int    `json:"player_uid"`
+	PlayerAlias string `json:"player_alias"`
+	Income      int    `json:"income"`
+	// CashKnown is only set for players we have keys for
+	CashKnown        bool `json:"cash_known"`
+	Cash             int  `json:"cash"`
+	CashAtProduction int  `json:"cash_at_production"`
+	// Ships built across every star until the next production
+	Ships int `json:"ships"`
+	// Stars lists ships built per star, only for stars we can see
+	Stars          []StarForecast `json:"stars"`
+	Researching    string         `json:"researching"`
+	ResearchPoints int            `json:"research_points"`
+}
+
+type Forecast struct {
+	Tick                 int              `json:"tick"`
+	Productions          int              `json:"productions"`
+	TicksUntilProduction int              `json:"ticks_until_production"`
+	NextProductionAt     int64            `json:"next_production_at"`
+	Players              []PlayerForecast `json:"players"`
+}
+
+// TicksUntilProduction is how many more ticks until the next production
+func TicksUntilProduction(data *types.ScanningData) int {
+	ticks := data.ProductionRate - data.ProductionCounter
+	if ticks < 0 {
+		return 0
+	}
+	return ticks
+}
+
+// TimeOfTick estimates when a future tick will happen (unix millis)
+func TimeOfTick(data *types.ScanningData, ticks int) int64 {
+	remaining := math.Max(0, float64(ticks)-data.TickFragment)
+	return data.Now + int64(remaining*float64(data.TickRate)*60*1000)
+}
+
+// Predict what every player will have at the next production
+func Predict(resp *types.APIResponse) Forecast {
+	data := &resp.ScanningData
+	ticks := TicksUntilProduction(data)
+
+	forecast := Forecast{
+		Tick:                 data.Tick,
+		Productions:          data.Productions,
+		TicksUntilProduction: ticks,
+		NextProductionAt:     TimeOfTick(data, ticks),
+		Players:              []PlayerForecast{},
+	}
+
+	for _, player := range data.Players {
+		manufacturing := player.Tech.Manufacturing.Level
+
+		playerForecast := PlayerForecast{
+			PlayerUID:      player.UID,
+			PlayerAlias:    player.Alias,
+			Income:         Income(player),
+			CashKnown:      player.PrivatePlayer.Useful(),
+			Cash:           player.Cash,
+			Ships:          int(ShipsPerTick(player.TotalIndustry, manufacturing, data.ProductionRate) * float64(ticks)),
+			Stars:          []StarForecast{},
+			Researching:    player.Researching,
+			ResearchPoints: player.TotalScience * ticks,
+		}
+		if playerForecast.CashKnown {
+			playerForecast.CashAtProduction = player.Cash + playerForecast.Income
+		}
+
+		for _, star := range data.Stars {
+			if star.PlayerID != player.UID || star.Industry <= 0 {
+				continue
+			}
+
+			// ShipsPerTick on the star is the fractional ship carried over between ticks
+			ships := star.ShipsPerTick + ShipsPerTick(star.Industry, manufacturing, data.ProductionRate)*float64(ticks)
+			playerForecast.Stars = append(playerForecast.Stars, StarForecast{
+				StarUID:  star.UID,
+				StarName: star.Name,
+				Ships:    int(ships),
+			})
+		}
+
+		sort.Slice(playerForecast.Stars, func(i, j int) bool {
+			return playerForecast.Stars[i].StarUID < playerForecast.Stars[j].StarUID
+		})
+
+		forecast.Players = append(forecast.Players, playerForecast)
+	}
+
+	sort.Slice(forecast.Players, func(i, j int) bool {
+		return forecast.Players[i].PlayerUID < forecast.Players[j].PlayerUID
+	})
+
+	return forecast
+}
+
+// ForPlayer finds a single player's forecast
+func (forecast Forecast) ForPlayer(playerUID int) (PlayerForecast, bool) {
+	for _, playerForecast := range forecast.Players {
+		if playerForecast.PlayerUID == playerUID {
+			return playerForecast, true
+		}
+	}
+	return PlayerForecast{}, false
+}
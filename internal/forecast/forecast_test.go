@@ -0,0 +1,133 @@
+package forecast
+
+import (
+	"testing"
+
+	"go.albinodrought.com/neptunes-pride/internal/types"
+)
+
+func TestShipsPerTick(t *testing.T) {
+	cases := []struct {
+		name               string
+		industry           int
+		manufacturingLevel int
+		productionRate     int
+		expected           float64
+	}{
+		{"no manufacturing", 12, 0, 24, 2.5},
+		{"manufacturing adds a ship per industry", 12, 1, 24, 3},
+		{"no industry", 0, 3, 24, 0},
+		{"production rate not known yet", 12, 1, 0, 0},
+	}
+
+	for _, c := range cases {
+		if found := ShipsPerTick(c.industry, c.manufacturingLevel, c.productionRate); found != c.expected {
+			t.Errorf("%v: expected %v ships per tick, got %v", c.name, c.expected, found)
+		}
+	}
+}
+
+func TestTimeOfTick(t *testing.T) {
+	const hour = 60 * 60 * 1000
+
+	cases := []struct {
+		name         string
+		tickFragment float64
+		ticks        int
+		expected     int64
+	}{
+		{"now", 0.25, 0, 1000},
+		{"rest of the current tick", 0.25, 1, 1000 + hour*3/4},
+		{"a few ticks away", 0.25, 3, 1000 + hour*11/4},
+		{"tick just started", 0, 2, 1000 + 2*hour},
+		{"fragment past the tick is clamped", 1.5, 1, 1000},
+	}
+
+	for _, c := range cases {
+		data := &types.ScanningData{Now: 1000, TickRate: 60, TickFragment: c.tickFragment}
+		if found := TimeOfTick(data, c.ticks); found != c.expected {
+			t.Errorf("%v: expected tick at %v, got %v", c.name, c.expected, found)
+		}
+	}
+}
+
+func TestPredict(t *testing.T) {
+	player := func(uid int, economy int, industry int, manufacturing int, banking int, cash int) types.Player {
+		player := types.Player{}
+		player.UID = uid
+		player.TotalEconomy = economy
+		player.TotalIndustry = industry
+		player.TotalScience = 2
+		player.Tech.Manufacturing.Level = manufacturing
+		player.Tech.Banking.Level = banking
+		player.Cash = cash
+		return player
+	}
+	star := func(uid int, owner int, industry int, carriedOver float64) types.Star {
+		return types.Star{
+			PublicStar:  types.PublicStar{UID: uid, PlayerID: owner},
+			PrivateStar: types.PrivateStar{Industry: industry, ShipsPerTick: carriedOver},
+		}
+	}
+
+	cases := []struct {
+		name              string
+		productionCounter int
+		ticks             int
+		// ships of player 1, and of their star 1
+		ships     int
+		starShips int
+	}{
+		{"mid cycle", 20, 4, 3, 3},
+		{"just produced, a full cycle to go", 0, 24, 18, 18},
+		{"production due this tick", 24, 0, 0, 0},
+		{"counter past the production rate", 30, 0, 0, 0},
+	}
+
+	for _, c := range cases {
+		resp := &types.APIResponse{ScanningData: types.ScanningData{
+			Now:               1000,
+			TickRate:          60,
+			Tick:              100,
+			ProductionRate:    24,
+			ProductionCounter: c.productionCounter,
+			Players: map[string]types.Player{
+				// keyed, with banking: 10 * 10 + 2 * 75 income
+				"1": player(1, 10, 3, 1, 2, 40),
+				// not keyed, so cash is unknown
+				"2": player(2, 5, 0, 0, 1, 0),
+			},
+			Stars: map[string]types.Star{
+				"1": star(1, 1, 3, 0.5),
+				"2": star(2, 2, 0, 0),
+			},
+		}}
+
+		forecast := Predict(resp)
+		if forecast.TicksUntilProduction != c.ticks {
+			t.Errorf("%v: expected production in %v ticks, got %v", c.name, c.ticks, forecast.TicksUntilProduction)
+		}
+		if forecast.NextProductionAt != TimeOfTick(&resp.ScanningData, c.ticks) {
+			t.Errorf("%v: expected production at tick %v, got %v", c.name, c.ticks, forecast.NextProductionAt)
+		}
+
+		keyed, ok := forecast.ForPlayer(1)
+		if !ok {
+			t.Fatalf("%v: expected a forecast for player 1", c.name)
+		}
+		if keyed.Income != 250 || !keyed.CashKnown || keyed.CashAtProduction != 290 {
+			t.Errorf("%v: expected 250 income on top of 40 cash, got %+v", c.name, keyed)
+		}
+		if keyed.Ships != c.ships || len(keyed.Stars) != 1 || keyed.Stars[0].Ships != c.starShips {
+			t.Errorf("%v: expected %v ships, %v at star 1, got %+v", c.name, c.ships, c.starShips, keyed)
+		}
+		if keyed.ResearchPoints != 2*c.ticks {
+			t.Errorf("%v: expected %v research points, got %v", c.name, 2*c.ticks, keyed.ResearchPoints)
+		}
+
+		public, _ := forecast.ForPlayer(2)
+		if public.Income != 125 || public.CashKnown || public.CashAtProduction != 0 || len(public.Stars) != 0 {
+			t.Errorf("%v: expected income but no cash or stars for player 2, got %+v", c.name, public)
+		}
+	}
+}
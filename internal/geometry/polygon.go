@@ -0,0 +1,160 @@
+package geometry
+
+import "math"
+
+// NoNeighbor tags polygon edges that were not produced by clipping against another site
+const NoNeighbor = -1
+
+// epsilon is the smallest edge length considered when checking neighbours
+const epsilon = 1e-9
+
+type Point struct {
+	X float64
+	Y float64
+}
+
+func (p Point) DistanceTo(other Point) float64 {
+	return math.Hypot(other.X-p.X, other.Y-p.Y)
+}
+
+// Polygon is a convex polygon.
+// Neighbors[i] tags the edge from Points[i] to Points[i+1] (wrapping around)
+// with the site that produced it.
+type Polygon struct {
+	Points    []Point
+	Neighbors []int
+}
+
+// Circle approximates a circle with a regular polygon
+func Circle(center Point, radius float64, segments int) Polygon {
+	polygon := Polygon{
+		Points:    make([]Point, segments),
+		Neighbors: make([]int, segments),
+	}
+
+	for i := 0; i < segments; i++ {
+		angle := 2 * math.Pi * float64(i) / float64(segments)
+		polygon.Points[i] = Point{
+			X: center.X + radius*math.Cos(angle),
+			Y: center.Y + radius*math.Sin(angle),
+		}
+		polygon.Neighbors[i] = NoNeighbor
+	}
+
+	return polygon
+}
+
+// ClipCloserTo keeps the part of the polygon that is closer to site than to other.
+// New edges along the bisector are tagged with otherID.
+func (polygon Polygon) ClipCloserTo(site Point, other Point, otherID int) Polygon {
+	normal := Point{other.X - site.X, other.Y - site.Y}
+	midpoint := Point{(site.X + other.X) / 2, (site.Y + other.Y) / 2}
+
+	side := func(p Point) float64 {
+		return (p.X-midpoint.X)*normal.X + (p.Y-midpoint.Y)*normal.Y
+	}
+
+	clipped := Polygon{
+		Points:    []Point{},
+		Neighbors: []int{},
+	}
+
+	for i, current := range polygon.Points {
+		next := polygon.Points[(i+1)%len(polygon.Points)]
+		tag := polygon.Neighbors[i]
+
+		currentSide := side(current)
+		nextSide := side(next)
+
+		intersection := func() Point {
+			t := currentSide / (currentSide - nextSide)
+			return Point{
+				X: current.X + (next.X-current.X)*t,
+				Y: current.Y + (next.Y-current.Y)*t,
+			}
+		}
+
+		if currentSide <= 0 {
+			clipped.Points = append(clipped.Points, current)
+			clipped.Neighbors = append(clipped.Neighbors, tag)
+			if nextSide > 0 {
+				// leaving: the following edge runs along the bisector
+				clipped.Points = append(clipped.Points, intersection())
+				clipped.Neighbors = append(clipped.Neighbors, otherID)
+			}
+		} else if nextSide <= 0 {
+			// entering: the following edge continues the original edge
+			clipped.Points = append(clipped.Points, intersection())
+			clipped.Neighbors = append(clipped.Neighbors, tag)
+		}
+	}
+
+	return clipped
+}
+
+// Area of the polygon, using the shoelace formula
+func (polygon Polygon) Area() float64 {
+	area := 0.0
+	for i, current := range polygon.Points {
+		next := polygon.Points[(i+1)%len(polygon.Points)]
+		area += current.X*next.Y - next.X*current.Y
+	}
+	return math.Abs(area) / 2
+}
+
+// HasNeighbor returns true if the polygon shares a non-degenerate edge with the given site
+func (polygon Polygon) HasNeighbor(id int) bool {
+	for i, neighbor := range polygon.Neighbors {
+		if neighbor != id {
+			continue
+		}
+		next := polygon.Points[(i+1)%len(polygon.Points)]
+		if polygon.Points[i].DistanceTo(next) > epsilon {
+			return true
+		}
+	}
+	return false
+}
+
+// NeighborIDs lists every site that shares a non-degenerate edge with this polygon
+func (polygon Polygon) NeighborIDs() []int {
+	ids := []int{}
+	seen := map[int]bool{}
+	for _, neighbor := range polygon.Neighbors {
+		if neighbor == NoNeighbor || seen[neighbor] {
+			continue
+		}
+		seen[neighbor] = true
+		if polygon.HasNeighbor(neighbor) {
+			ids = append(ids, neighbor)
+		}
+	}
+	return ids
+}
+
+// Site is a point that owns a Voronoi cell
+type Site struct {
+	ID    int
+	Point Point
+}
+
+// VoronoiCell computes the cell of site among others, clipped to a circle of the given radius
+func VoronoiCell(site Site, radius float64, others []Site, segments int) Polygon {
+	cell := Circle(site.Point, radius, segments)
+
+	for _, other := range others {
+		if other.ID == site.ID {
+			continue
+		}
+		if site.Point.DistanceTo(other.Point) > 2*radius {
+			// bisector can't cross the circle
+			continue
+		}
+		cell = cell.ClipCloserTo(site.Point, other.Point, other.ID)
+		if len(cell.Points) == 0 {
+			break
+		}
+	}
+
+	return cell
+}
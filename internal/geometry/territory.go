@@ -0,0 +1,210 @@
+package geometry
+
+import (
+	"sort"
+	"strconv"
+
+	"go.albinodrought.com/neptunes-pride/internal/types"
+)
+
+// circleSegments is how smooth territory edges at the limit of hyperspace range are
+const circleSegments = 32
+
+// Territory is a Voronoi partition of the galaxy's stars,
+// each cell clipped by its owner's hyperspace range
+type Territory struct {
+	// Cells by star UID
+	Cells map[int]Polygon
+	// Owners by star UID, -1 if unowned
+	Owners map[int]int
+}
+
+func ComputeTerritory(resp *types.APIResponse) *Territory {
+	territory := &Territory{
+		Cells:  map[int]Polygon{},
+		Owners: map[int]int{},
+	}
+
+	sites := make([]Site, 0, len(resp.ScanningData.Stars))
+	for _, star := range resp.ScanningData.Stars {
+		x, y := star.Position()
+		sites = append(sites, Site{ID: star.UID, Point: Point{x, y}})
+		territory.Owners[star.UID] = star.PlayerID
+	}
+
+	// unowned stars still split space, give them the widest range available
+	defaultRange := 0.0
+	for _, player := range resp.ScanningData.Players {
+		if player.Tech.Propulsion.Value > defaultRange {
+			defaultRange = player.Tech.Propulsion.Value
+		}
+	}
+
+	for _, site := range sites {
+		radius := defaultRange
+		if owner, ok := resp.ScanningData.Players[strconv.Itoa(territory.Owners[site.ID])]; ok {
+			radius = owner.Tech.Propulsion.Value
+		}
+		if radius <= 0 {
+			continue
+		}
+
+		territory.Cells[site.ID] = VoronoiCell(site, radius, sites, circleSegments)
+	}
+
+	return territory
+}
+
+// Adjacent returns true if both stars' cells share an edge
+func (territory *Territory) Adjacent(a int, b int) bool {
+	return territory.Cells[a].HasNeighbor(b) && territory.Cells[b].HasNeighbor(a)
+}
+
+type Geometry struct {
+	Type string `json:"type"`
+	// Coordinates of a MultiPolygon: polygons, rings, points, [x, y]
+	Coordinates [][][][2]float64 `json:"coordinates"`
+}
+
+type FeatureProperties struct {
+	PlayerUID   int    `json:"player_uid"`
+	PlayerAlias string `json:"player_alias"`
+	StarUIDs    []int  `json:"star_uids"`
+}
+
+type Feature struct {
+	Type       string            `json:"type"`
+	Geometry   Geometry          `json:"geometry"`
+	Properties FeatureProperties `json:"properties"`
+}
+
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+}
+
+// GeoJSON returns one MultiPolygon feature per player, made of the cells of their stars
+func (territory *Territory) GeoJSON(resp *types.APIResponse) FeatureCollection {
+	starsByPlayer := map[int][]int{}
+	for starUID, owner := range territory.Owners {
+		if owner == -1 {
+			continue
+		}
+		if _, ok := territory.Cells[starUID]; !ok {
+			continue
+		}
+		starsByPlayer[owner] = append(starsByPlayer[owner], starUID)
+	}
+
+	playerIDs := make([]int, 0, len(starsByPlayer))
+	for playerID := range starsByPlayer {
+		playerIDs = append(playerIDs, playerID)
+	}
+	sort.Ints(playerIDs)
+
+	collection := FeatureCollection{
+		Type:     "FeatureCollection",
+		Features: []Feature{},
+	}
+
+	for _, playerID := range playerIDs {
+		starUIDs := starsByPlayer[playerID]
+		sort.Ints(starUIDs)
+
+		feature := Feature{
+			Type: "Feature",
+			Geometry: Geometry{
+				Type:        "MultiPolygon",
+				Coordinates: [][][][2]float64{},
+			},
+			Properties: FeatureProperties{
+				PlayerUID:   playerID,
+				PlayerAlias: resp.ScanningData.Players[strconv.Itoa(playerID)].Alias,
+				StarUIDs:    starUIDs,
+			},
+		}
+
+		for _, starUID := range starUIDs {
+			cell := territory.Cells[starUID]
+			if len(cell.Points) == 0 {
+				continue
+			}
+
+			// GeoJSON rings are closed: the first point is repeated at the end
+			ring := make([][2]float64, 0, len(cell.Points)+1)
+			for _, point := range cell.Points {
+				ring = append(ring, [2]float64{point.X, point.Y})
+			}
+			ring = append(ring, ring[0])
+
+			feature.Geometry.Coordinates = append(feature.Geometry.Coordinates, [][][2]float64{ring})
+		}
+
+		collection.Features = append(collection.Features, feature)
+	}
+
+	return collection
+}
+
+type FrontlineStar struct {
+	StarUID       int    `json:"star_uid"`
+	StarName      string `json:"star_name"`
+	PlayerUID     int    `json:"player_uid"`
+	EnemyUIDs     []int  `json:"enemy_uids"`
+	EnemyStarUIDs []int  `json:"enemy_star_uids"`
+}
+
+// Frontline lists the allied stars whose territory borders an enemy's territory.
+// Every player that isn't an ally is treated as an enemy.
+func (territory *Territory) Frontline(resp *types.APIResponse, allyIDs []int) []FrontlineStar {
+	allies := map[int]bool{}
+	for _, allyID := range allyIDs {
+		allies[allyID] = true
+	}
+
+	frontline := []FrontlineStar{}
+	for starUID, owner := range territory.Owners {
+		if !allies[owner] {
+			continue
+		}
+
+		frontlineStar := FrontlineStar{
+			StarUID:       starUID,
+			StarName:      resp.ScanningData.Stars[strconv.Itoa(starUID)].Name,
+			PlayerUID:     owner,
+			EnemyUIDs:     []int{},
+			EnemyStarUIDs: []int{},
+		}
+
+		enemies := map[int]bool{}
+		for _, neighbor := range territory.Cells[starUID].NeighborIDs() {
+			neighborOwner := territory.Owners[neighbor]
+			if neighborOwner == -1 || allies[neighborOwner] {
+				continue
+			}
+			if !territory.Adjacent(starUID, neighbor) {
+				continue
+			}
+
+			frontlineStar.EnemyStarUIDs = append(frontlineStar.EnemyStarUIDs, neighbor)
+			if !enemies[neighborOwner] {
+				enemies[neighborOwner] = true
+				frontlineStar.EnemyUIDs = append(frontlineStar.EnemyUIDs, neighborOwner)
+			}
+		}
+
+		if len(frontlineStar.EnemyStarUIDs) == 0 {
+			continue
+		}
+
+		sort.Ints(frontlineStar.EnemyUIDs)
+		sort.Ints(frontlineStar.EnemyStarUIDs)
+		frontline = append(frontline, frontlineStar)
+	}
+
+	sort.Slice(frontline, func(i, j int) bool {
+		return frontline[i].StarUID < frontline[j].StarUID
+	})
+
+	return frontline
+}
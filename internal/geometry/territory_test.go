@@ -0,0 +1,61 @@
+package geometry
+
+import (
+	"math"
+	"testing"
+
+	"go.albinodrought.com/neptunes-pride/internal/fixtures"
+)
+
+func TestVoronoiCell(t *testing.T) {
+	sites := []Site{
+		{ID: 1, Point: Point{0, 0}},
+		{ID: 2, Point: Point{1, 0}},
+		{ID: 3, Point: Point{10, 10}},
+	}
+
+	cell := VoronoiCell(sites[0], 1, sites, 64)
+
+	// the circular segment past x=0.5 is cut off by site 2
+	expectedArea := math.Pi - (math.Acos(0.5) - 0.5*math.Sqrt(0.75))
+	if math.Abs(cell.Area()-expectedArea) > 0.01 {
+		t.Errorf("expected area of %v but got %v", expectedArea, cell.Area())
+	}
+	if !cell.HasNeighbor(2) {
+		t.Errorf("expected cell to neighbor site 2, got %+v", cell.Neighbors)
+	}
+	if cell.HasNeighbor(3) {
+		t.Errorf("expected cell to not neighbor far away site 3, got %+v", cell.Neighbors)
+	}
+}
+
+func TestFrontline(t *testing.T) {
+	burrito := fixtures.Load(t, "../opsec/burrito.json")
+
+	territory := ComputeTerritory(burrito)
+	if len(territory.Cells) != len(burrito.ScanningData.Stars) {
+		t.Fatalf("expected a cell per star, got %v of %v", len(territory.Cells), len(burrito.ScanningData.Stars))
+	}
+
+	frontline := territory.Frontline(burrito, []int{5})
+	if len(frontline) == 0 {
+		t.Fatalf("expected player 5 to have a frontline")
+	}
+	for _, star := range frontline {
+		if star.PlayerUID != 5 {
+			t.Errorf("expected only player 5 stars on the frontline, got %+v", star)
+		}
+		for _, enemyUID := range star.EnemyUIDs {
+			if enemyUID == 5 || enemyUID == -1 {
+				t.Errorf("expected only enemies to border frontline stars, got %+v", star)
+			}
+		}
+	}
+
+	geoJSON := territory.GeoJSON(burrito)
+	for _, feature := range geoJSON.Features {
+		if len(feature.Geometry.Coordinates) != len(feature.Properties.StarUIDs) {
+			t.Errorf("expected a polygon per star for player %v", feature.Properties.PlayerUID)
+		}
+	}
+}
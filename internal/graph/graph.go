@@ -0,0 +1,231 @@
+package graph
+
+import (
+	"container/heap"
+	"math"
+	"strconv"
+
+	"go.albinodrought.com/neptunes-pride/internal/types"
+)
+
+// warpGateSpeedMultiplier is how much faster fleets travel between two warp gates
+const warpGateSpeedMultiplier = 3
+
+type edge struct {
+	to       int
+	distance float64
+	ticks    int
+}
+
+// Graph of the stars a player's fleets can jump between.
+// Stars are connected when they are within the player's hyperspace range,
+// and travel time accounts for warp gates.
+type Graph struct {
+	PlayerUID       int
+	HyperspaceRange float64
+	FleetSpeed      float64
+
+	matrix *DistanceMatrix
+	edges  map[int][]edge
+}
+
+// TravelTicks is how many ticks it takes a fleet to cover a distance
+func TravelTicks(distance float64, fleetSpeed float64, warp bool) int {
+	if fleetSpeed <= 0 {
+		return math.MaxInt32
+	}
+	if warp {
+		fleetSpeed *= warpGateSpeedMultiplier
+	}
+	return int(math.Ceil(distance / fleetSpeed))
+}
+
+// New builds the reachability graph for a player from a (merged) snapshot
+func New(matrix *DistanceMatrix, resp *types.APIResponse, playerUID int) *Graph {
+	hyperspaceRange := 0.0
+	if player, ok := resp.ScanningData.Players[strconv.Itoa(playerUID)]; ok {
+		hyperspaceRange = player.Tech.Propulsion.Value
+	}
+
+	return NewWithRange(matrix, resp, playerUID, hyperspaceRange)
+}
+
+// NewWithRange builds a reachability graph with a custom hyperspace range,
+// for example to plan around an upcoming propulsion level
+func NewWithRange(matrix *DistanceMatrix, resp *types.APIResponse, playerUID int, hyperspaceRange float64) *Graph {
+	graph := &Graph{
+		PlayerUID:       playerUID,
+		HyperspaceRange: hyperspaceRange,
+		FleetSpeed:      resp.ScanningData.FleetSpeed,
+		matrix:          matrix,
+		edges:           make(map[int][]edge, matrix.Len()),
+	}
+
+	gates := make(map[int]bool, len(resp.ScanningData.Stars))
+	for _, star := range resp.ScanningData.Stars {
+		gates[star.UID] = star.WarpGate > 0
+	}
+
+	uids := matrix.StarUIDs()
+	for i, a := range uids {
+		for _, b := range uids[i+1:] {
+			distance, _ := matrix.Distance(a, b)
+			if distance > hyperspaceRange {
+				continue
+			}
+
+			ticks := TravelTicks(distance, graph.FleetSpeed, gates[a] && gates[b])
+			graph.edges[a] = append(graph.edges[a], edge{b, distance, ticks})
+			graph.edges[b] = append(graph.edges[b], edge{a, distance, ticks})
+		}
+	}
+
+	return graph
+}
+
+// Neighbors lists the stars reachable from a star in a single jump
+func (graph *Graph) Neighbors(starUID int) []int {
+	neighbors := make([]int, len(graph.edges[starUID]))
+	for i, e := range graph.edges[starUID] {
+		neighbors[i] = e.to
+	}
+	return neighbors
+}
+
+type Route struct {
+	// StarUIDs from the origin to the destination, inclusive
+	StarUIDs []int   `json:"star_uids"`
+	Ticks    int     `json:"ticks"`
+	Distance float64 `json:"distance"`
+}
+
+// Routes holds the fastest routes from a set of origins
+type Routes struct {
+	ticks    map[int]int
+	distance map[int]float64
+	previous map[int]int
+}
+
+// Ticks to reach a star, false if unreachable
+func (routes *Routes) Ticks(starUID int) (int, bool) {
+	ticks, ok := routes.ticks[starUID]
+	return ticks, ok
+}
+
+// Reachable stars and the ticks needed to reach them
+func (routes *Routes) Reachable() map[int]int {
+	reachable := make(map[int]int, len(routes.ticks))
+	for starUID, ticks := range routes.ticks {
+		reachable[starUID] = ticks
+	}
+	return reachable
+}
+
+// To returns the fastest route to a star, false if unreachable
+func (routes *Routes) To(starUID int) (Route, bool) {
+	ticks, ok := routes.ticks[starUID]
+	if !ok {
+		return Route{}, false
+	}
+
+	path := []int{starUID}
+	for {
+		previous, ok := routes.previous[path[len(path)-1]]
+		if !ok {
+			break
+		}
+		path = append(path, previous)
+	}
+
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+
+	return Route{
+		StarUIDs: path,
+		Ticks:    ticks,
+		Distance: routes.distance[starUID],
+	}, true
+}
+
+// FastestRoutes runs Dijkstra's algorithm from every origin at once,
+// stopping at maxTicks (0 for no limit)
+func (graph *Graph) FastestRoutes(origins []int, maxTicks int) *Routes {
+	routes := &Routes{
+		ticks:    map[int]int{},
+		distance: map[int]float64{},
+		previous: map[int]int{},
+	}
+
+	queue := &routeQueue{}
+	for _, origin := range origins {
+		if _, ok := graph.matrix.index[origin]; !ok {
+			continue
+		}
+		routes.ticks[origin] = 0
+		routes.distance[origin] = 0
+		heap.Push(queue, routeQueueItem{origin, 0})
+	}
+
+	visited := map[int]bool{}
+	for queue.Len() > 0 {
+		current := heap.Pop(queue).(routeQueueItem)
+		if visited[current.starUID] {
+			continue
+		}
+		visited[current.starUID] = true
+
+		for _, e := range graph.edges[current.starUID] {
+			if visited[e.to] {
+				continue
+			}
+
+			ticks := current.ticks + e.ticks
+			if maxTicks > 0 && ticks > maxTicks {
+				continue
+			}
+
+			existing, ok := routes.ticks[e.to]
+			distance := routes.distance[current.starUID] + e.distance
+			if ok && (existing < ticks || (existing == ticks && routes.distance[e.to] <= distance)) {
+				continue
+			}
+
+			routes.ticks[e.to] = ticks
+			routes.distance[e.to] = distance
+			routes.previous[e.to] = current.starUID
+			heap.Push(queue, routeQueueItem{e.to, ticks})
+		}
+	}
+
+	return routes
+}
+
+// FastestRoute between two stars, false if unreachable
+func (graph *Graph) FastestRoute(from int, to int) (Route, bool) {
+	return graph.FastestRoutes([]int{from}, 0).To(to)
+}
+
+// Reachable lists the stars reachable from a star within maxTicks (0 for no limit)
+func (graph *Graph) Reachable(from int, maxTicks int) map[int]int {
+	return graph.FastestRoutes([]int{from}, maxTicks).Reachable()
+}
+
+type routeQueueItem struct {
+	starUID int
+	ticks   int
+}
+
+// routeQueue is a min-heap of routeQueueItem by ticks
+type routeQueue []routeQueueItem
+
+func (q routeQueue) Len() int            { return len(q) }
+func (q routeQueue) Less(i, j int) bool  { return q[i].ticks < q[j].ticks }
+func (q routeQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *routeQueue) Push(x interface{}) { *q = append(*q, x.(routeQueueItem)) }
+func (q *routeQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
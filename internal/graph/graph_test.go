@@ -0,0 +1,78 @@
+package graph
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"go.albinodrought.com/neptunes-pride/internal/types"
+)
+
+func TestFastestRoute(t *testing.T) {
+	resp := &types.APIResponse{
+		ScanningData: types.ScanningData{
+			FleetSpeed: 0.1,
+			Stars: map[string]types.Star{
+				"1": {PublicStar: types.PublicStar{UID: 1, X: "0", Y: "0"}, PrivateStar: types.PrivateStar{WarpGate: 1}},
+				"2": {PublicStar: types.PublicStar{UID: 2, X: "0.5", Y: "0"}},
+				"3": {PublicStar: types.PublicStar{UID: 3, X: "1", Y: "0"}, PrivateStar: types.PrivateStar{WarpGate: 1}},
+				"4": {PublicStar: types.PublicStar{UID: 4, X: "5", Y: "5"}},
+			},
+			Players: map[string]types.Player{
+				"1": {PublicPlayer: types.PublicPlayer{UID: 1, Tech: types.Tech{Propulsion: types.TechResearchStatus{PublicTechResearchStatus: types.PublicTechResearchStatus{Value: 1}}}}},
+			},
+		},
+	}
+
+	graph := New(NewDistanceMatrix(resp.ScanningData.Stars), resp, 1)
+
+	// direct warp gate jump beats the hop through star 2
+	route, ok := graph.FastestRoute(1, 3)
+	if !ok {
+		t.Fatalf("expected star 3 to be reachable")
+	}
+	if route.Ticks != 4 || len(route.StarUIDs) != 2 {
+		t.Errorf("expected a 4 tick direct route, got %+v", route)
+	}
+
+	route, ok = graph.FastestRoute(2, 3)
+	if !ok || route.Ticks != 5 {
+		t.Errorf("expected a 5 tick route from star 2, got %+v", route)
+	}
+
+	if _, ok := graph.FastestRoute(1, 4); ok {
+		t.Errorf("expected star 4 to be out of range")
+	}
+
+	reachable := graph.Reachable(2, 4)
+	if len(reachable) != 1 {
+		t.Errorf("expected nothing but the origin to be reachable in 4 ticks, got %+v", reachable)
+	}
+}
+
+func TestMatrixCache(t *testing.T) {
+	data, err := ioutil.ReadFile("../opsec/burrito.json")
+	if err != nil {
+		panic(err)
+	}
+
+	resp := &types.APIResponse{}
+	if err := json.Unmarshal(data, resp); err != nil {
+		panic(err)
+	}
+
+	cache := NewMatrixCache()
+	matrix := cache.ForGame("burrito", resp)
+	if matrix.Len() != len(resp.ScanningData.Stars) {
+		t.Errorf("expected %v stars, got %v", len(resp.ScanningData.Stars), matrix.Len())
+	}
+	if cache.ForGame("burrito", resp) != matrix {
+		t.Errorf("expected matrix to be cached")
+	}
+
+	graph := New(matrix, resp, resp.ScanningData.PlayerUID)
+	reachable := graph.Reachable(5, 0)
+	if len(reachable) < 2 {
+		t.Errorf("expected player to reach more than their home star, got %+v", reachable)
+	}
+}
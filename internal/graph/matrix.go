@@ -0,0 +1,101 @@
+package graph
+
+import (
+	"sort"
+	"sync"
+
+	"go.albinodrought.com/neptunes-pride/internal/types"
+)
+
+// DistanceMatrix holds the distance between every pair of stars
+type DistanceMatrix struct {
+	uids      []int
+	index     map[int]int
+	distances []float64
+}
+
+func NewDistanceMatrix(stars map[string]types.Star) *DistanceMatrix {
+	matrix := &DistanceMatrix{
+		uids:  make([]int, 0, len(stars)),
+		index: make(map[int]int, len(stars)),
+	}
+
+	positions := make(map[int][2]float64, len(stars))
+	for _, star := range stars {
+		x, y := star.Position()
+		positions[star.UID] = [2]float64{x, y}
+		matrix.uids = append(matrix.uids, star.UID)
+	}
+	sort.Ints(matrix.uids)
+
+	xs := make([]float64, len(matrix.uids))
+	ys := make([]float64, len(matrix.uids))
+	for i, uid := range matrix.uids {
+		matrix.index[uid] = i
+		xs[i], ys[i] = positions[uid][0], positions[uid][1]
+	}
+
+	n := len(matrix.uids)
+	matrix.distances = make([]float64, n*n)
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			distance := types.Distance(xs[i], ys[i], xs[j], ys[j])
+			matrix.distances[i*n+j] = distance
+			matrix.distances[j*n+i] = distance
+		}
+	}
+
+	return matrix
+}
+
+// StarUIDs in ascending order
+func (matrix *DistanceMatrix) StarUIDs() []int {
+	return matrix.uids
+}
+
+func (matrix *DistanceMatrix) Len() int {
+	return len(matrix.uids)
+}
+
+// Distance between two stars, false if either star is unknown
+func (matrix *DistanceMatrix) Distance(a int, b int) (float64, bool) {
+	i, ok := matrix.index[a]
+	if !ok {
+		return 0, false
+	}
+	j, ok := matrix.index[b]
+	if !ok {
+		return 0, false
+	}
+	return matrix.distances[i*len(matrix.uids)+j], true
+}
+
+// MatrixCache keeps one distance matrix per game:
+// star positions never change, so it only needs to be built once.
+type MatrixCache struct {
+	lock     sync.Mutex
+	matrices map[string]*DistanceMatrix
+}
+
+func NewMatrixCache() *MatrixCache {
+	return &MatrixCache{
+		matrices: map[string]*DistanceMatrix{},
+	}
+}
+
+var DefaultMatrixCache = NewMatrixCache()
+
+// ForGame returns the cached matrix for a game, building it from the snapshot if needed
+func (cache *MatrixCache) ForGame(gameNumber string, resp *types.APIResponse) *DistanceMatrix {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	matrix, ok := cache.matrices[gameNumber]
+	if ok && matrix.Len() == len(resp.ScanningData.Stars) {
+		return matrix
+	}
+
+	matrix = NewDistanceMatrix(resp.ScanningData.Stars)
+	cache.matrices[gameNumber] = matrix
+	return matrix
+}
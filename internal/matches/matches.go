@@ -1,6 +1,7 @@
 package matches
 
 import (
+	"encoding/json"
 	"errors"
 	"time"
 
@@ -57,6 +58,25 @@ type Match struct {
 	DiscordUserIDs map[int]string      `json:"discord_user_ids,omitempty"`
 	OldAccessCode  []byte              `json:"access_code,omitempty"`
 	AccessProfiles []AccessProfile     `json:"access_profiles,omitempty"`
+	// TurnDeadline is when the current turn resolves in turn-based games (unix millis)
+	TurnDeadline int64 `json:"turn_deadline,omitempty"`
+}
+
+// UnmarshalJSON makes sure the maps exist, empty ones are left out when marshalling
+func (match *Match) UnmarshalJSON(data []byte) error {
+	type plainMatch Match
+	if err := json.Unmarshal(data, (*plainMatch)(match)); err != nil {
+		return err
+	}
+
+	if match.PlayerCreds == nil {
+		match.PlayerCreds = map[int]PlayerCreds{}
+	}
+	if match.DiscordUserIDs == nil {
+		match.DiscordUserIDs = map[int]string{}
+	}
+
+	return nil
 }
 
 func (match *Match) HasAccessCode() bool {
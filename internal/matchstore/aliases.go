@@ -0,0 +1,72 @@
+package matchstore
+
+import (
+	"strings"
+
+	"go.albinodrought.com/neptunes-pride/internal/types"
+)
+
+// AliasGame is a game an alias was seen playing in
+type AliasGame struct {
+	GameNumber string `json:"game_number"`
+	PlayerUID  int    `json:"player_uid"`
+}
+
+// aliasKey indexes aliases ignoring case, like dossier.FindPlayer looks them up
+func aliasKey(alias string) string {
+	return strings.ToLower(alias)
+}
+
+// snapshotAliases maps every player in a snapshot to their alias
+func snapshotAliases(snapshot *types.APIResponse) map[int]string {
+	aliases := make(map[int]string, len(snapshot.ScanningData.Players))
+	for _, player := range snapshot.ScanningData.Players {
+		if player.Alias != "" {
+			aliases[player.UID] = player.Alias
+		}
+	}
+	return aliases
+}
+
+// IndexAliases fills in the alias index from the latest snapshot of every player,
+// for snapshots saved before aliases were indexed
+func IndexAliases(store MatchStore, log func(v ...interface{})) error {
+	gameNumbers, err := store.Matches()
+	if err != nil {
+		return err
+	}
+
+	for _, gameNumber := range gameNumbers {
+		playerIDs, err := store.ListSnapshotPlayers(gameNumber)
+		if err == ErrMatchNotFound {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		aliases := map[int]string{}
+		for _, playerID := range playerIDs {
+			times, err := store.ListSnapshotTimes(gameNumber, playerID, 1)
+			if err == ErrSnapshotNotFound {
+				continue
+			} else if err != nil {
+				return err
+			}
+
+			snapshot, err := store.FindSnapshot(gameNumber, playerID, times[0])
+			if err != nil {
+				return err
+			}
+			for playerUID, alias := range snapshotAliases(snapshot) {
+				aliases[playerUID] = alias
+			}
+		}
+
+		if err := store.SaveAliases(gameNumber, aliases); err != nil {
+			return err
+		}
+		log("indexed ", len(aliases), " player aliases for game ", gameNumber)
+	}
+
+	return nil
+}
@@ -0,0 +1,370 @@
+package matchstore
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"time"
+
+	"go.albinodrought.com/neptunes-pride/internal/matches"
+	"go.albinodrought.com/neptunes-pride/internal/stats"
+	"go.albinodrought.com/neptunes-pride/internal/types"
+)
+
+// ArchiveFormat is bumped whenever archives change in a way older versions can't import
+const ArchiveFormat = 1
+
+var ErrMatchExists = errors.New("match already exists, import with overwrite to merge into it")
+var ErrArchiveInvalid = errors.New("not an np-scanner match archive")
+
+const (
+	archiveManifestPath = "manifest.json"
+	archiveMatchPath    = "match.json"
+	archiveStatsPath    = "stats.json"
+	archiveReportPath   = "report.html"
+)
+
+type ArchiveSnapshot struct {
+	PlayerUID int    `json:"player_uid"`
+	Time      int64  `json:"time"`
+	Path      string `json:"path"`
+}
+
+// ArchiveManifest is the first file of an archive and lists everything after it
+type ArchiveManifest struct {
+	Format              int               `json:"format"`
+	GameNumber          string            `json:"game_number"`
+	Name                string            `json:"name"`
+	ExportedAt          time.Time         `json:"exported_at"`
+	CredentialsStripped bool              `json:"credentials_stripped"`
+	Snapshots           []ArchiveSnapshot `json:"snapshots"`
+	HasStats            bool              `json:"has_stats"`
+	HasReport           bool              `json:"has_report"`
+}
+
+type ExportOptions struct {
+	// StripCredentials removes API keys, Discord user IDs and access codes from the exported match
+	StripCredentials bool
+	Log              func(v ...interface{})
+}
+
+var DefaultExportOptions = ExportOptions{
+	StripCredentials: true,
+	Log:              func(v ...interface{}) {},
+}
+
+type ImportOptions struct {
+	// Overwrite merges the archive into a match that already exists instead of failing.
+	// Credentials of the existing match are kept if the archive's were stripped.
+	Overwrite bool
+	Log       func(v ...interface{})
+}
+
+var DefaultImportOptions = ImportOptions{
+	Log: func(v ...interface{}) {},
+}
+
+func stripCredentials(match *matches.Match) {
+	match.PlayerCreds = map[int]matches.PlayerCreds{}
+	match.DiscordUserIDs = map[int]string{}
+	match.WipeAccessCodes()
+}
+
+// keepCredentials copies the credentials of an existing match into one imported without them
+func keepCredentials(match *matches.Match, existing *matches.Match) {
+	match.PlayerCreds = existing.PlayerCreds
+	match.DiscordUserIDs = existing.DiscordUserIDs
+	match.OldAccessCode = existing.OldAccessCode
+	match.AccessProfiles = existing.AccessProfiles
+}
+
+func snapshotArchivePath(playerUID int, time int64) string {
+	return fmt.Sprintf("snapshots/%v/%v.json", playerUID, time)
+}
+
+func writeArchiveFile(archive *tar.Writer, path string, data []byte, modTime time.Time) error {
+	err := archive.WriteHeader(&tar.Header{
+		Name:    path,
+		Mode:    0600,
+		Size:    int64(len(data)),
+		ModTime: modTime,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = archive.Write(data)
+	return err
+}
+
+func writeArchiveJSON(archive *tar.Writer, path string, value interface{}, modTime time.Time) error {
+	// indented like the test fixtures, so snapshots can be used as ones
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeArchiveFile(archive, path, data, modTime)
+}
+
+// Export writes a match, its snapshots, stats and report as a gzipped tarball of JSON files.
+// Snapshots are written oldest first per player, the same order Import saves them in.
+func Export(store MatchStore, gameNumber string, w io.Writer, options *ExportOptions) (*ArchiveManifest, error) {
+	if options == nil {
+		options = &DefaultExportOptions
+	}
+
+	match, err := store.FindMatchOrFail(gameNumber)
+	if err != nil {
+		return nil, err
+	}
+	if options.StripCredentials {
+		stripCredentials(match)
+	}
+
+	manifest := &ArchiveManifest{
+		Format:              ArchiveFormat,
+		GameNumber:          gameNumber,
+		Name:                match.Name,
+		ExportedAt:          time.Now(),
+		CredentialsStripped: options.StripCredentials,
+		Snapshots:           []ArchiveSnapshot{},
+	}
+
+	playerIDs, err := store.ListSnapshotPlayers(gameNumber)
+	if err == ErrMatchNotFound {
+		playerIDs = []int{}
+	} else if err != nil {
+		return nil, err
+	}
+
+	for _, playerID := range playerIDs {
+		times, err := store.ListSnapshotTimes(gameNumber, playerID, math.MaxInt32)
+		if err == ErrSnapshotNotFound {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		for i := len(times) - 1; i >= 0; i-- {
+			manifest.Snapshots = append(manifest.Snapshots, ArchiveSnapshot{
+				PlayerUID: playerID,
+				Time:      times[i],
+				Path:      snapshotArchivePath(playerID, times[i]),
+			})
+		}
+	}
+
+	allSeries, err := store.ListStats(gameNumber)
+	if err != nil && err != ErrMatchNotFound {
+		return nil, err
+	}
+	manifest.HasStats = len(allSeries) > 0
+
+	report, err := store.FindReport(gameNumber)
+	if err != nil && err != ErrReportNotFound {
+		return nil, err
+	}
+	manifest.HasReport = err == nil
+
+	compressed := gzip.NewWriter(w)
+	archive := tar.NewWriter(compressed)
+
+	if err := writeArchiveJSON(archive, archiveManifestPath, manifest, manifest.ExportedAt); err != nil {
+		return nil, err
+	}
+	if err := writeArchiveJSON(archive, archiveMatchPath, match, manifest.ExportedAt); err != nil {
+		return nil, err
+	}
+
+	for _, entry := range manifest.Snapshots {
+		snapshot, err := store.FindSnapshot(gameNumber, entry.PlayerUID, entry.Time)
+		if err != nil {
+			return nil, err
+		}
+		if err := writeArchiveJSON(archive, entry.Path, snapshot, time.Unix(0, entry.Time*int64(time.Millisecond))); err != nil {
+			return nil, err
+		}
+	}
+	options.Log("exported ", len(manifest.Snapshots), " snapshots for game ", gameNumber)
+
+	if manifest.HasStats {
+		if err := writeArchiveJSON(archive, archiveStatsPath, allSeries, manifest.ExportedAt); err != nil {
+			return nil, err
+		}
+	}
+	if manifest.HasReport {
+		if err := writeArchiveFile(archive, archiveReportPath, report, manifest.ExportedAt); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	if err := compressed.Close(); err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+// archiveContents is everything in an archive except its snapshots, which are handled one at a time
+type archiveContents struct {
+	manifest  *ArchiveManifest
+	match     *matches.Match
+	allSeries []stats.Series
+	report    []byte
+}
+
+// readArchive reads an archive's files in the order Export wrote them,
+// handing each snapshot to handleSnapshot so only one is in memory at a time.
+func readArchive(r io.Reader, handleSnapshot func(snapshot *types.APIResponse) error) (*archiveContents, error) {
+	compressed, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, ErrArchiveInvalid
+	}
+	defer compressed.Close()
+	archive := tar.NewReader(compressed)
+
+	nextFile := func(expectedPath string) error {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return fmt.Errorf("archive ended before %v", expectedPath)
+		} else if err != nil {
+			return err
+		}
+		if header.Name != expectedPath {
+			return fmt.Errorf("expected %v in archive, found %v", expectedPath, header.Name)
+		}
+		return nil
+	}
+	readJSON := func(expectedPath string, value interface{}) error {
+		if err := nextFile(expectedPath); err != nil {
+			return err
+		}
+		return json.NewDecoder(archive).Decode(value)
+	}
+
+	contents := &archiveContents{
+		manifest:  &ArchiveManifest{},
+		match:     &matches.Match{},
+		allSeries: []stats.Series{},
+	}
+
+	manifest := contents.manifest
+	if err := readJSON(archiveManifestPath, manifest); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrArchiveInvalid, err)
+	}
+	if manifest.Format == 0 || manifest.GameNumber == "" {
+		return nil, ErrArchiveInvalid
+	}
+	if manifest.Format > ArchiveFormat {
+		return nil, fmt.Errorf("archive format %v is newer than this version of np-scanner supports", manifest.Format)
+	}
+
+	if err := readJSON(archiveMatchPath, contents.match); err != nil {
+		return nil, err
+	}
+	if contents.match.GameNumber != manifest.GameNumber {
+		return nil, fmt.Errorf("archive is for game %v but contains game %v", manifest.GameNumber, contents.match.GameNumber)
+	}
+
+	for _, entry := range manifest.Snapshots {
+		snapshot := &types.APIResponse{}
+		if err := readJSON(entry.Path, snapshot); err != nil {
+			return nil, err
+		}
+		if snapshot.ScanningData.PlayerUID != entry.PlayerUID || snapshot.ScanningData.Now != entry.Time {
+			return nil, fmt.Errorf("snapshot %v does not match its manifest entry", entry.Path)
+		}
+		if err := handleSnapshot(snapshot); err != nil {
+			return nil, err
+		}
+	}
+
+	if manifest.HasStats {
+		if err := readJSON(archiveStatsPath, &contents.allSeries); err != nil {
+			return nil, err
+		}
+	}
+
+	if manifest.HasReport {
+		if err := nextFile(archiveReportPath); err != nil {
+			return nil, err
+		}
+		contents.report, err = ioutil.ReadAll(archive)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// reaching the end of the gzip stream checks its checksum
+	if _, err := io.Copy(ioutil.Discard, compressed); err != nil {
+		return nil, err
+	}
+
+	return contents, nil
+}
+
+// Import restores an archive written by Export.
+// The whole archive is checked before anything is saved, so a truncated or mismatched archive
+// leaves the store as it was, then it's read again to save snapshots one at a time.
+func Import(store MatchStore, r io.ReadSeeker, options *ImportOptions) (*ArchiveManifest, error) {
+	if options == nil {
+		options = &DefaultImportOptions
+	}
+
+	checked, err := readArchive(r, func(snapshot *types.APIResponse) error {
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	manifest := checked.manifest
+	match := checked.match
+
+	existing, err := store.FindMatchOrFail(manifest.GameNumber)
+	if err == nil && !options.Overwrite {
+		return nil, ErrMatchExists
+	} else if err != nil && err != ErrMatchNotFound {
+		return nil, err
+	}
+	if err == nil && manifest.CredentialsStripped {
+		keepCredentials(match, existing)
+	}
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	if err := store.SaveMatch(match); err != nil {
+		return nil, err
+	}
+
+	contents, err := readArchive(r, func(snapshot *types.APIResponse) error {
+		return store.SaveSnapshot(manifest.GameNumber, snapshot)
+	})
+	if err != nil {
+		return nil, err
+	}
+	options.Log("imported ", len(manifest.Snapshots), " snapshots for game ", manifest.GameNumber)
+
+	for _, series := range contents.allSeries {
+		if err := store.SaveStats(manifest.GameNumber, series.Rows); err != nil {
+			return nil, err
+		}
+	}
+
+	if manifest.HasReport {
+		if err := store.SaveReport(manifest.GameNumber, contents.report); err != nil {
+			return nil, err
+		}
+	}
+
+	return manifest, nil
+}
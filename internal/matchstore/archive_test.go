@@ -0,0 +1,124 @@
+package matchstore
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"go.albinodrought.com/neptunes-pride/internal/fixtures"
+	"go.albinodrought.com/neptunes-pride/internal/matches"
+	"go.albinodrought.com/neptunes-pride/internal/types"
+)
+
+func TestArchiveRoundTrip(t *testing.T) {
+	from := OpenMemory()
+	match, _ := from.FindOrCreateMatch("123")
+	match.Name = "Burrito Galaxy"
+	match.PlayerCreds[4] = matches.PlayerCreds{PlayerUID: 4, PlayerAlias: "Aburrido", APIKey: "abc"}
+	match.DiscordUserIDs = map[int]string{4: "1234"}
+	if err := from.SaveMatch(match); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	saved := []*types.APIResponse{}
+	for i := 0; i < 4; i++ {
+		snapshot := fixtures.Load(t, "../opsec/aburrido.json")
+		snapshot.ScanningData.Now = start.Add(time.Duration(i)*time.Hour).UnixNano() / int64(time.Millisecond)
+		snapshot.ScanningData.Tick = i
+		if err := from.SaveSnapshot("123", snapshot); err != nil {
+			t.Fatal(err)
+		}
+		saved = append(saved, snapshot)
+	}
+	if err := from.SaveReport("123", []byte("<html></html>")); err != nil {
+		t.Fatal(err)
+	}
+
+	exported := &bytes.Buffer{}
+	manifest, err := Export(from, "123", exported, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !manifest.CredentialsStripped || len(manifest.Snapshots) != len(saved) || !manifest.HasStats || !manifest.HasReport {
+		t.Errorf("expected everything in the manifest, got %+v", manifest)
+	}
+	archive := exported.Bytes()
+
+	// a truncated archive saves nothing, so importing it again works
+	to := OpenMemory()
+	if _, err := Import(to, bytes.NewReader(archive[:len(archive)/2]), nil); err == nil {
+		t.Fatal("expected a truncated archive to fail")
+	}
+	if _, err := to.FindMatchOrFail("123"); err != ErrMatchNotFound {
+		t.Fatalf("expected nothing to be imported from a truncated archive, got %v", err)
+	}
+	if _, err := Import(to, bytes.NewReader(archive), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	imported, err := to.FindMatchOrFail("123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.Name != "Burrito Galaxy" || imported.PlayerCreds == nil || len(imported.PlayerCreds) != 0 || imported.DiscordUserIDs == nil || len(imported.DiscordUserIDs) != 0 {
+		t.Errorf("expected the match without credentials, got %+v", imported)
+	}
+
+	for _, snapshot := range saved {
+		found, err := to.FindSnapshot("123", 4, snapshot.ScanningData.Now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectedJSON, _ := json.Marshal(snapshot)
+		foundJSON, _ := json.Marshal(found)
+		if string(expectedJSON) != string(foundJSON) {
+			t.Errorf("snapshot at %v did not come back the same", snapshot.ScanningData.Now)
+		}
+	}
+
+	allSeries, err := to.ListStats("123")
+	if err != nil || len(allSeries) == 0 || len(allSeries[0].Rows) != len(saved) {
+		t.Errorf("expected stats for every tick, got %+v (%v)", allSeries, err)
+	}
+
+	report, err := to.FindReport("123")
+	if err != nil || string(report) != "<html></html>" {
+		t.Errorf("expected the report, got %v (%v)", string(report), err)
+	}
+
+	if _, err := Import(to, bytes.NewReader(archive), nil); err != ErrMatchExists {
+		t.Errorf("expected existing matches to be left alone, got %v", err)
+	}
+	if _, err := Import(to, bytes.NewReader(archive), &ImportOptions{Overwrite: true, Log: t.Log}); err != nil {
+		t.Errorf("expected overwrite to merge, got %v", err)
+	}
+
+	// overwriting with a stripped archive keeps the credentials already there
+	if _, err := Import(from, bytes.NewReader(archive), &ImportOptions{Overwrite: true, Log: t.Log}); err != nil {
+		t.Fatal(err)
+	}
+	kept, _ := from.FindMatchOrFail("123")
+	if kept.PlayerCreds[4].APIKey != "abc" || kept.DiscordUserIDs[4] != "1234" {
+		t.Errorf("expected the existing credentials to be kept, got %+v", kept)
+	}
+
+	// credentials are only kept when asked for
+	exported.Reset()
+	if _, err := Export(from, "123", exported, &ExportOptions{Log: t.Log}); err != nil {
+		t.Fatal(err)
+	}
+	withCreds := OpenMemory()
+	if _, err := Import(withCreds, bytes.NewReader(exported.Bytes()), nil); err != nil {
+		t.Fatal(err)
+	}
+	imported, _ = withCreds.FindMatchOrFail("123")
+	if imported.PlayerCreds[4].APIKey != "abc" || imported.DiscordUserIDs[4] != "1234" {
+		t.Errorf("expected the credentials to be kept, got %+v", imported)
+	}
+
+	if _, err := Import(OpenMemory(), bytes.NewReader([]byte("burrito")), nil); err != ErrArchiveInvalid {
+		t.Errorf("expected a garbage archive to be rejected, got %v", err)
+	}
+}
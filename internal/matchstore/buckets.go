@@ -0,0 +1,33 @@
+package matchstore
+
+import bolt "go.etcd.io/bbolt"
+
+// snapshotBucket holds one player's snapshots of a game, keyed by snapshotKey in time order.
+// The snapshot encodings are written against it so every backend stores them the same way.
+type snapshotBucket interface {
+	Get(key []byte) []byte
+	Put(key []byte, value []byte) error
+	Delete(key []byte) error
+	ForEach(fn func(k, v []byte) error) error
+	Cursor() snapshotCursor
+}
+
+type snapshotCursor interface {
+	Seek(seek []byte) (key []byte, value []byte)
+	Last() (key []byte, value []byte)
+	Prev() (key []byte, value []byte)
+}
+
+// valueBucket holds one value per game, like galaxies
+type valueBucket interface {
+	Get(key []byte) []byte
+	Put(key []byte, value []byte) error
+}
+
+type boltSnapshotBucket struct {
+	*bolt.Bucket
+}
+
+func (bucket boltSnapshotBucket) Cursor() snapshotCursor {
+	return bucket.Bucket.Cursor()
+}
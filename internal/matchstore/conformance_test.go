@@ -0,0 +1,272 @@
+package matchstore
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.albinodrought.com/neptunes-pride/internal/fixtures"
+	"go.albinodrought.com/neptunes-pride/internal/matches"
+	"go.albinodrought.com/neptunes-pride/internal/stats"
+	"go.albinodrought.com/neptunes-pride/internal/types"
+)
+
+// TestConformance runs every MatchStore implementation through the same checks,
+// new backends are picked up from Backends
+func TestConformance(t *testing.T) {
+	sameJSON := func(t *testing.T, expected interface{}, found interface{}, what string) {
+		expectedJSON, _ := json.Marshal(expected)
+		foundJSON, _ := json.Marshal(found)
+		if string(expectedJSON) != string(foundJSON) {
+			t.Errorf("%v did not come back the same", what)
+		}
+	}
+
+	for _, backend := range Backends {
+		t.Run(backend, func(t *testing.T) {
+			store := openTestBackend(t, backend, filepath.Join(t.TempDir(), "test.db"))
+
+			t.Run("matches", func(t *testing.T) {
+				if _, err := store.FindMatchOrFail("123"); err != ErrMatchNotFound {
+					t.Errorf("expected a missing match, got %v", err)
+				}
+
+				created, err := store.FindOrCreateMatch("123")
+				if err != nil {
+					t.Fatal(err)
+				}
+				created.Name = "Burrito Galaxy"
+				created.TurnDeadline = 1641013200000
+				created.PlayerCreds[4] = matches.PlayerCreds{PlayerUID: 4, PlayerAlias: "Aburrido", APIKey: "abc"}
+				if err := store.SaveMatch(created); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := store.FindOrCreateMatch("456"); err != nil {
+					t.Fatal(err)
+				}
+
+				// changes aren't stored until saved
+				created.Name = "Unsaved"
+
+				found, err := store.FindMatchOrFail("123")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if found.Name != "Burrito Galaxy" || found.PlayerCreds[4].APIKey != "abc" {
+					t.Errorf("expected the saved match, got %+v", found)
+				}
+
+				gameNumbers, err := store.Matches()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(gameNumbers, []string{"123", "456"}) {
+					t.Errorf("expected both matches in order, got %v", gameNumbers)
+				}
+
+				names := []string{}
+				deadlines := []int64{}
+				err = store.EachMatch(true, func(gameNumber string, match *matches.Match) {
+					names = append(names, gameNumber+": "+match.Name)
+					deadlines = append(deadlines, match.TurnDeadline)
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(names, []string{"123: Burrito Galaxy", "456: "}) {
+					t.Errorf("expected every match decoded, got %v", names)
+				}
+				// fields left out of a match's JSON aren't carried over from the one before
+				if !reflect.DeepEqual(deadlines, []int64{1641013200000, 0}) {
+					t.Errorf("expected only the first match to have a deadline, got %v", deadlines)
+				}
+			})
+
+			// two polls per tick, so every other snapshot is a duplicate
+			start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+			saved := []*types.APIResponse{}
+			for i := 0; i < 16; i++ {
+				snapshot := fixtures.Load(t, "../opsec/aburrido.json")
+				snapshot.ScanningData.Now = start.Add(time.Duration(i)*30*time.Minute).UnixNano() / int64(time.Millisecond)
+				snapshot.ScanningData.Tick = i / 2
+				snapshot.ScanningData.Productions = i / 8
+				for fleetIndex, fleet := range snapshot.ScanningData.Fleets {
+					fleet.Strength += i / 2
+					snapshot.ScanningData.Fleets[fleetIndex] = fleet
+				}
+				saved = append(saved, snapshot)
+			}
+
+			t.Run("snapshots", func(t *testing.T) {
+				if _, err := store.ListSnapshotTimes("123", 4, 10); err != ErrMatchNotFound {
+					t.Errorf("expected a missing game before any snapshot, got %v", err)
+				}
+
+				for _, snapshot := range saved {
+					if err := store.SaveSnapshot("123", snapshot); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				players, err := store.ListSnapshotPlayers("123")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(players, []int{4}) {
+					t.Errorf("expected snapshots of player 4, got %v", players)
+				}
+
+				times, err := store.ListSnapshotTimes("123", 4, 3)
+				if err != nil {
+					t.Fatal(err)
+				}
+				expectedTimes := []int64{saved[15].ScanningData.Now, saved[14].ScanningData.Now, saved[13].ScanningData.Now}
+				if !reflect.DeepEqual(times, expectedTimes) {
+					t.Errorf("expected the newest times first, got %v", times)
+				}
+
+				for _, snapshot := range saved {
+					found, err := store.FindSnapshot("123", 4, snapshot.ScanningData.Now)
+					if err != nil {
+						t.Fatal(err)
+					}
+					sameJSON(t, snapshot, found, "snapshot")
+				}
+
+				if _, err := store.FindSnapshot("123", 4, start.UnixNano()/int64(time.Millisecond)-1); err != ErrSnapshotNotFound {
+					t.Errorf("expected a missing time, got %v", err)
+				}
+				if _, err := store.FindSnapshot("123", 5, saved[0].ScanningData.Now); err != ErrSnapshotNotFound {
+					t.Errorf("expected a missing player, got %v", err)
+				}
+				if _, err := store.ListSnapshotTimes("123", 5, 10); err != ErrSnapshotNotFound {
+					t.Errorf("expected a missing player, got %v", err)
+				}
+				if _, err := store.FindSnapshot("789", 4, saved[0].ScanningData.Now); err != ErrMatchNotFound {
+					t.Errorf("expected a missing game, got %v", err)
+				}
+			})
+
+			t.Run("aliases", func(t *testing.T) {
+				games, err := store.ListAliasGames("nobody")
+				if err != nil || len(games) != 0 {
+					t.Errorf("expected no games for an unknown alias, got %v (%v)", games, err)
+				}
+
+				if err := store.SaveAliases("456", map[int]string{2: "Expansive Brain"}); err != nil {
+					t.Fatal(err)
+				}
+
+				// indexed when the snapshots were saved, looked up ignoring case
+				games, err = store.ListAliasGames("expansive BRAIN")
+				if err != nil {
+					t.Fatal(err)
+				}
+				expected := []AliasGame{{GameNumber: "123", PlayerUID: 4}, {GameNumber: "456", PlayerUID: 2}}
+				if !reflect.DeepEqual(games, expected) {
+					t.Errorf("expected %+v, got %+v", expected, games)
+				}
+			})
+
+			t.Run("stats", func(t *testing.T) {
+				if _, err := store.ListStats("789"); err != ErrMatchNotFound {
+					t.Errorf("expected a missing game, got %v", err)
+				}
+
+				allSeries, err := store.ListStats("123")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(allSeries) == 0 {
+					t.Fatal("expected stats from the saved snapshots")
+				}
+				for _, series := range allSeries {
+					if len(series.Rows) != 8 {
+						t.Errorf("expected one row per tick for player %v, got %v", series.PlayerUID, len(series.Rows))
+					}
+				}
+
+				row := allSeries[0].Rows[0]
+				row.Tick = 100
+				if err := store.SaveStats("123", []stats.Row{row}); err != nil {
+					t.Fatal(err)
+				}
+				allSeries, _ = store.ListStats("123")
+				last := allSeries[0].Rows[len(allSeries[0].Rows)-1]
+				if last.Tick != 100 {
+					t.Errorf("expected saved rows to be listed in tick order, got %+v", last)
+				}
+			})
+
+			t.Run("reports", func(t *testing.T) {
+				if _, err := store.FindReport("123"); err != ErrReportNotFound {
+					t.Errorf("expected a missing report, got %v", err)
+				}
+				if err := store.SaveReport("123", []byte("<html></html>")); err != nil {
+					t.Fatal(err)
+				}
+				report, err := store.FindReport("123")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(report) != "<html></html>" {
+					t.Errorf("expected the saved report, got %v", string(report))
+				}
+			})
+
+			t.Run("maintenance", func(t *testing.T) {
+				if err := store.CompressSnapshots(func(v ...interface{}) {}); err == nil {
+					t.Error("expected nothing to compress")
+				}
+
+				if err := store.DeltaEncodeSnapshots(func(v ...interface{}) {}); err != nil {
+					t.Fatal(err)
+				}
+
+				// the last 2 hours are kept in full, the rest one per tick
+				policy := RetentionPolicy{
+					KeepAll: 2 * time.Hour,
+					PerTick: 24 * time.Hour,
+					Now:     start.Add(8 * time.Hour),
+				}
+
+				dryRun, err := store.PruneSnapshots(policy, true, func(v ...interface{}) {})
+				if err != nil {
+					t.Fatal(err)
+				}
+				times, _ := store.ListSnapshotTimes("123", 4, 100)
+				if len(times) != len(saved) {
+					t.Errorf("expected a dry run to remove nothing, %v left", len(times))
+				}
+
+				results, err := store.PruneSnapshots(policy, false, func(v ...interface{}) {})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(results) != 1 || results[0] != dryRun[0] {
+					t.Errorf("expected the dry run to match, got %+v and %+v", dryRun, results)
+				}
+
+				// 4 kept in full, then one for each of the 6 older ticks
+				times, _ = store.ListSnapshotTimes("123", 4, 100)
+				if len(times) != 4+6 {
+					t.Errorf("expected 10 snapshots left, got %v", len(times))
+				}
+
+				for _, snapshotTime := range times {
+					found, err := store.FindSnapshot("123", 4, snapshotTime)
+					if err != nil {
+						t.Fatalf("failed rebuilding kept snapshot at %v: %v", snapshotTime, err)
+					}
+					for _, snapshot := range saved {
+						if snapshot.ScanningData.Now == snapshotTime {
+							sameJSON(t, snapshot, found, "kept snapshot")
+						}
+					}
+				}
+			})
+		})
+	}
+}
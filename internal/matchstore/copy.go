@@ -0,0 +1,92 @@
+package matchstore
+
+import (
+	"fmt"
+	"math"
+)
+
+// Backends are the storage implementations OpenBackend knows about
+var Backends = []string{"bolt", "sqlite", "memory"}
+
+func OpenBackend(backend string, path string) (MatchStore, error) {
+	switch backend {
+	case "bolt":
+		return Open(path)
+	case "sqlite":
+		return OpenSQLite(path)
+	case "memory":
+		// nothing is kept, path is ignored
+		return OpenMemory(), nil
+	}
+	return nil, fmt.Errorf("unknown match store backend %q, expected one of %v", backend, Backends)
+}
+
+// Copy writes every match, snapshot, stats row and report from one store into another.
+// Snapshots are copied oldest first and re-encoded by the target store.
+func Copy(from MatchStore, to MatchStore, log func(v ...interface{})) error {
+	gameNumbers, err := from.Matches()
+	if err != nil {
+		return err
+	}
+
+	for _, gameNumber := range gameNumbers {
+		match, err := from.FindMatchOrFail(gameNumber)
+		if err != nil {
+			return err
+		}
+		if err := to.SaveMatch(match); err != nil {
+			return err
+		}
+
+		playerIDs, err := from.ListSnapshotPlayers(gameNumber)
+		if err == ErrMatchNotFound {
+			playerIDs = []int{}
+		} else if err != nil {
+			return err
+		}
+
+		for _, playerID := range playerIDs {
+			times, err := from.ListSnapshotTimes(gameNumber, playerID, math.MaxInt32)
+			if err == ErrSnapshotNotFound {
+				continue
+			} else if err != nil {
+				return err
+			}
+
+			for i := len(times) - 1; i >= 0; i-- {
+				snapshot, err := from.FindSnapshot(gameNumber, playerID, times[i])
+				if err != nil {
+					return err
+				}
+				if err := to.SaveSnapshot(gameNumber, snapshot); err != nil {
+					return err
+				}
+			}
+
+			log("copied ", len(times), " snapshots for game ", gameNumber, " player ", playerID)
+		}
+
+		// stats may outlive pruned snapshots, so they are copied as well as rebuilt
+		allSeries, err := from.ListStats(gameNumber)
+		if err != nil && err != ErrMatchNotFound {
+			return err
+		}
+		for _, series := range allSeries {
+			if err := to.SaveStats(gameNumber, series.Rows); err != nil {
+				return err
+			}
+		}
+
+		report, err := from.FindReport(gameNumber)
+		if err == nil {
+			err = to.SaveReport(gameNumber, report)
+		}
+		if err != nil && err != ErrReportNotFound {
+			return err
+		}
+
+		log("copied game ", gameNumber)
+	}
+
+	return nil
+}
@@ -0,0 +1,415 @@
+package matchstore
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"strconv"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// KeyframeInterval is how many deltas may follow a keyframe before a new keyframe is stored
+const KeyframeInterval = 48
+
+// deltaPrefix marks delta values, keyframes are plain gzipped JSON
+var deltaPrefix = []byte("npd1")
+
+var ErrDeltaBaseNotFound = errors.New("delta base keyframe not found")
+
+// delta is stored against the nearest keyframe before it, never against another delta,
+// so any snapshot can be rebuilt from at most two values
+type delta struct {
+	Base  int64       `json:"base"`
+	Patch interface{} `json:"patch"`
+}
+
+func isDelta(value []byte) bool {
+	return bytes.HasPrefix(value, deltaPrefix)
+}
+
+func gzipBytes(data []byte) ([]byte, error) {
+	buffer := &bytes.Buffer{}
+	writer := gzip.NewWriter(buffer)
+	if _, err := writer.Write(data); err != nil {
+		writer.Close()
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func gunzipBytes(data []byte) ([]byte, error) {
+	unzipper, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(unzipper)
+}
+
+func decodeJSON(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	// keep numbers exactly as they were
+	decoder.UseNumber()
+
+	var decoded interface{}
+	err := decoder.Decode(&decoded)
+	return decoded, err
+}
+
+// diffJSON builds a JSON merge patch (RFC 7386) turning from into to.
+// Merge patches can't set null, so nulls are rebuilt as missing keys:
+// both decode to the same Go zero values.
+func diffJSON(from interface{}, to interface{}) (interface{}, bool) {
+	fromObject, fromOK := from.(map[string]interface{})
+	toObject, toOK := to.(map[string]interface{})
+	if !fromOK || !toOK {
+		fromSerialized, _ := json.Marshal(from)
+		toSerialized, _ := json.Marshal(to)
+		if bytes.Equal(fromSerialized, toSerialized) {
+			return nil, false
+		}
+		// a null patch removes the key, which decodes to the same zero value as null
+		return to, true
+	}
+
+	patch := map[string]interface{}{}
+	for key, fromValue := range fromObject {
+		toValue, ok := toObject[key]
+		if !ok {
+			patch[key] = nil
+			continue
+		}
+		if _, isObject := toValue.(map[string]interface{}); isObject {
+			if _, wasObject := fromValue.(map[string]interface{}); !wasObject {
+				patch[key] = toValue
+				continue
+			}
+		}
+		if valuePatch, changed := diffJSON(fromValue, toValue); changed {
+			patch[key] = valuePatch
+		}
+	}
+	for key, toValue := range toObject {
+		if _, ok := fromObject[key]; !ok {
+			patch[key] = toValue
+		}
+	}
+
+	return patch, len(patch) > 0
+}
+
+// applyPatch applies a JSON merge patch (RFC 7386)
+func applyPatch(target interface{}, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+
+	for key, patchValue := range patchObject {
+		if patchValue == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = applyPatch(targetObject[key], patchValue)
+	}
+
+	return targetObject
+}
+
+// encodeDelta stores target as a patch against the base keyframe's JSON
+func encodeDelta(baseTime int64, base []byte, target []byte) ([]byte, error) {
+	baseDecoded, err := decodeJSON(base)
+	if err != nil {
+		return nil, err
+	}
+	targetDecoded, err := decodeJSON(target)
+	if err != nil {
+		return nil, err
+	}
+
+	patch, _ := diffJSON(baseDecoded, targetDecoded)
+	if patch == nil {
+		patch = map[string]interface{}{}
+	}
+
+	serialized, err := json.Marshal(delta{Base: baseTime, Patch: patch})
+	if err != nil {
+		return nil, err
+	}
+
+	compressed, err := gzipBytes(serialized)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(append([]byte{}, deltaPrefix...), compressed...), nil
+}
+
+// decodeDelta rebuilds a snapshot's JSON from its delta and the base keyframe's JSON
+func decodeDelta(value []byte, base []byte) ([]byte, error) {
+	decompressed, err := gunzipBytes(value[len(deltaPrefix):])
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(decompressed))
+	decoder.UseNumber()
+	decoded := delta{}
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, err
+	}
+
+	baseDecoded, err := decodeJSON(base)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(applyPatch(baseDecoded, decoded.Patch))
+}
+
+// deltaBase reads which keyframe a delta was stored against
+func deltaBase(value []byte) (int64, error) {
+	decompressed, err := gunzipBytes(value[len(deltaPrefix):])
+	if err != nil {
+		return 0, err
+	}
+
+	decoded := delta{}
+	if err := json.Unmarshal(decompressed, &decoded); err != nil {
+		return 0, err
+	}
+	return decoded.Base, nil
+}
+
+func snapshotKey(time int64) []byte {
+	return []byte(strconv.FormatInt(time, 10))
+}
+
+// readSnapshotJSON rebuilds a stored snapshot's JSON, whether it is a keyframe or a delta.
+// Must be called inside a transaction.
+func readSnapshotJSON(bucket snapshotBucket, time int64) ([]byte, error) {
+	value := bucket.Get(snapshotKey(time))
+	if value == nil {
+		return nil, ErrSnapshotNotFound
+	}
+
+	if isAlias(value) {
+		return readAliasJSON(bucket, value)
+	}
+
+	if !isDelta(value) {
+		return gunzipBytes(value)
+	}
+
+	baseTime, err := deltaBase(value)
+	if err != nil {
+		return nil, err
+	}
+
+	baseValue := bucket.Get(snapshotKey(baseTime))
+	if baseValue == nil || !isKeyframe(baseValue) {
+		return nil, ErrDeltaBaseNotFound
+	}
+
+	base, err := gunzipBytes(baseValue)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeDelta(value, base)
+}
+
+// encodeSnapshot picks between storing a keyframe or a delta for a snapshot at the given time.
+// Must be called inside a writable transaction, before the snapshot is put.
+func encodeSnapshot(bucket snapshotBucket, time int64, serialized []byte) ([]byte, error) {
+	keyframe, err := gzipBytes(serialized)
+	if err != nil {
+		return nil, err
+	}
+
+	key := snapshotKey(time)
+	if existing := bucket.Get(key); existing != nil && isKeyframe(existing) {
+		// other deltas may be stored against this keyframe, keep it one
+		return keyframe, nil
+	}
+
+	// find the nearest keyframe before this snapshot, counting the deltas in between
+	c := bucket.Cursor()
+	k, v := c.Seek(key)
+	if k == nil {
+		k, v = c.Last()
+	} else {
+		k, v = c.Prev()
+	}
+
+	deltas := 0
+	for ; k != nil; k, v = c.Prev() {
+		if isKeyframe(v) {
+			break
+		}
+		if isDelta(v) {
+			deltas++
+		}
+	}
+
+	if k == nil || deltas+1 >= KeyframeInterval {
+		return keyframe, nil
+	}
+
+	baseTime, err := strconv.ParseInt(string(k), 10, 64)
+	if err != nil {
+		return keyframe, nil
+	}
+
+	base, err := gunzipBytes(v)
+	if err != nil {
+		return nil, err
+	}
+
+	encodedDelta, err := encodeDelta(baseTime, base, serialized)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(encodedDelta) > len(keyframe)/2 {
+		// too much changed, a fresh keyframe keeps later deltas small
+		return keyframe, nil
+	}
+
+	return encodedDelta, nil
+}
+
+type snapshotBucketPath struct {
+	gameNumber []byte
+	playerID   []byte
+}
+
+// deltaEncodeBucket rewrites a player's full snapshots as deltas against periodic keyframes.
+// Keyframes that existing deltas are stored against are left alone.
+func deltaEncodeBucket(bucket snapshotBucket) (int, error) {
+	keys := [][]byte{}
+	pinned := map[string]bool{}
+
+	err := bucket.ForEach(func(k, v []byte) error {
+		keys = append(keys, append([]byte{}, k...))
+		if isDelta(v) {
+			baseTime, err := deltaBase(v)
+			if err != nil {
+				return err
+			}
+			pinned[strconv.FormatInt(baseTime, 10)] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	converted := 0
+	keyframeTime := int64(0)
+	var keyframe []byte
+	deltas := 0
+
+	for _, k := range keys {
+		value := bucket.Get(k)
+		if !isKeyframe(value) {
+			continue
+		}
+
+		time, err := strconv.ParseInt(string(k), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		full, err := gunzipBytes(value)
+		if err != nil {
+			return converted, err
+		}
+
+		if keyframe == nil || pinned[string(k)] || deltas+1 >= KeyframeInterval {
+			keyframeTime, keyframe, deltas = time, full, 0
+			continue
+		}
+
+		encodedDelta, err := encodeDelta(keyframeTime, keyframe, full)
+		if err != nil {
+			return converted, err
+		}
+
+		if len(encodedDelta) > len(value)/2 {
+			keyframeTime, keyframe, deltas = time, full, 0
+			continue
+		}
+
+		if err := bucket.Put(k, encodedDelta); err != nil {
+			return converted, err
+		}
+		deltas++
+		converted++
+	}
+
+	return converted, nil
+}
+
+// snapshotBucketPaths lists every game and player with stored snapshots
+func (store *boltMatchStore) snapshotBucketPaths() ([]snapshotBucketPath, error) {
+	paths := []snapshotBucketPath{}
+
+	err := store.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("gz-snapshots")).ForEach(func(gameNumber, v []byte) error {
+			bucket := tx.Bucket([]byte("gz-snapshots")).Bucket(gameNumber)
+			if bucket == nil {
+				return nil
+			}
+
+			return bucket.ForEach(func(playerID, v []byte) error {
+				if bucket.Bucket(playerID) != nil {
+					paths = append(paths, snapshotBucketPath{
+						gameNumber: append([]byte{}, gameNumber...),
+						playerID:   append([]byte{}, playerID...),
+					})
+				}
+				return nil
+			})
+		})
+	})
+
+	return paths, err
+}
+
+func (store *boltMatchStore) DeltaEncodeSnapshots(log func(v ...interface{})) error {
+	paths, err := store.snapshotBucketPaths()
+	if err != nil {
+		return err
+	}
+
+	// one transaction per player keeps memory use down on big databases
+	for _, path := range paths {
+		err := store.db.Update(func(tx *bolt.Tx) error {
+			bucket := tx.Bucket([]byte("gz-snapshots")).Bucket(path.gameNumber).Bucket(path.playerID)
+
+			converted, err := deltaEncodeBucket(boltSnapshotBucket{bucket})
+			if err != nil {
+				return err
+			}
+
+			log("delta encoded ", converted, " snapshots for game ", string(path.gameNumber), " player ", string(path.playerID))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package matchstore
+
+import (
+	"encoding/json"
+	"testing"
+
+	"go.albinodrought.com/neptunes-pride/internal/fixtures"
+	"go.albinodrought.com/neptunes-pride/internal/types"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestDeltaEncoding(t *testing.T) {
+	store := openTestStore(t)
+	boltStore := store.(*boltMatchStore)
+
+	// a few polls where fleets move, stars change hands and orders disappear
+	expected := []*types.APIResponse{}
+	for i := 0; i < 5; i++ {
+		snapshot := fixtures.Load(t, "../opsec/aburrido.json")
+		snapshot.ScanningData.Now += int64(i) * 60 * 1000
+		snapshot.ScanningData.Tick += i
+		for fleetIndex, fleet := range snapshot.ScanningData.Fleets {
+			fleet.Strength += i
+			if i%2 == 1 {
+				fleet.Orders = nil
+			}
+			snapshot.ScanningData.Fleets[fleetIndex] = fleet
+		}
+		if i == 3 {
+			delete(snapshot.ScanningData.Stars, "1")
+		}
+		expected = append(expected, snapshot)
+
+		if err := store.SaveSnapshot("123", snapshot); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	checkAll := func() {
+		for _, snapshot := range expected {
+			found, err := store.FindSnapshot("123", snapshot.ScanningData.PlayerUID, snapshot.ScanningData.Now)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expectedJSON, _ := json.Marshal(snapshot)
+			foundJSON, _ := json.Marshal(found)
+			if string(expectedJSON) != string(foundJSON) {
+				t.Errorf("snapshot at %v did not rebuild to the same data", snapshot.ScanningData.Now)
+			}
+		}
+	}
+
+	countDeltas := func() int {
+		deltas := 0
+		boltStore.db.View(func(tx *bolt.Tx) error {
+			bucket := tx.Bucket([]byte("gz-snapshots")).Bucket([]byte("123")).Bucket([]byte("4"))
+			return bucket.ForEach(func(k, v []byte) error {
+				if isDelta(v) {
+					deltas++
+				}
+				return nil
+			})
+		})
+		return deltas
+	}
+
+	checkAll()
+	if deltas := countDeltas(); deltas != len(expected)-1 {
+		t.Errorf("expected every snapshot after the first to be a delta, got %v deltas", deltas)
+	}
+
+	// pretend these were stored before deltas existed, then migrate them
+	boltStore.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("gz-snapshots")).Bucket([]byte("123")).Bucket([]byte("4"))
+		for _, snapshot := range expected {
+			serialized, _ := json.Marshal(snapshot)
+			keyframe, _ := gzipBytes(serialized)
+			bucket.Put(snapshotKey(snapshot.ScanningData.Now), keyframe)
+		}
+		return nil
+	})
+	if deltas := countDeltas(); deltas != 0 {
+		t.Fatalf("expected only keyframes before migrating, got %v deltas", deltas)
+	}
+
+	if err := store.DeltaEncodeSnapshots(func(v ...interface{}) {}); err != nil {
+		t.Fatal(err)
+	}
+
+	checkAll()
+	if deltas := countDeltas(); deltas != len(expected)-1 {
+		t.Errorf("expected migration to delta encode every snapshot after the first, got %v deltas", deltas)
+	}
+}
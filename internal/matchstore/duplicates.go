@@ -0,0 +1,159 @@
+package matchstore
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+)
+
+// aliasPrefix marks snapshots that were identical to an earlier one, apart from volatile fields.
+// They extend the earlier snapshot's validity instead of storing it again.
+var aliasPrefix = []byte("npa1")
+
+// volatileSettings change between polls even when nothing happened in the game
+var volatileSettings = []string{"now", "tick_fragment"}
+
+type alias struct {
+	Base int64 `json:"base"`
+	// Volatile holds the snapshot's own volatile values
+	Volatile map[string]interface{} `json:"volatile"`
+}
+
+func isAlias(value []byte) bool {
+	return bytes.HasPrefix(value, aliasPrefix)
+}
+
+// isKeyframe returns true for full snapshots, which deltas can be stored against
+func isKeyframe(value []byte) bool {
+	return !isDelta(value) && !isAlias(value)
+}
+
+func encodeAlias(baseTime int64, decoded interface{}) ([]byte, error) {
+	volatile := map[string]interface{}{}
+	if data, ok := scanningData(decoded); ok {
+		for _, key := range volatileSettings {
+			if value, ok := data[key]; ok {
+				volatile[key] = value
+			}
+		}
+	}
+
+	serialized, err := json.Marshal(alias{Base: baseTime, Volatile: volatile})
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte{}, aliasPrefix...), serialized...), nil
+}
+
+func decodeAlias(value []byte) (alias, error) {
+	decoder := json.NewDecoder(bytes.NewReader(value[len(aliasPrefix):]))
+	decoder.UseNumber()
+
+	decoded := alias{}
+	err := decoder.Decode(&decoded)
+	return decoded, err
+}
+
+// readAliasJSON rebuilds an aliased snapshot from its base, with its own volatile values
+func readAliasJSON(bucket snapshotBucket, value []byte) ([]byte, error) {
+	decodedAlias, err := decodeAlias(value)
+	if err != nil {
+		return nil, err
+	}
+
+	baseValue := bucket.Get(snapshotKey(decodedAlias.Base))
+	if baseValue == nil || isAlias(baseValue) {
+		return nil, ErrSnapshotNotFound
+	}
+
+	base, err := readSnapshotJSON(bucket, decodedAlias.Base)
+	if err != nil {
+		return nil, err
+	}
+
+	decoded, err := decodeJSON(base)
+	if err != nil {
+		return nil, err
+	}
+
+	if data, ok := scanningData(decoded); ok {
+		for key, value := range decodedAlias.Volatile {
+			data[key] = value
+		}
+	}
+
+	return json.Marshal(decoded)
+}
+
+func withoutVolatile(decoded interface{}) interface{} {
+	if data, ok := scanningData(decoded); ok {
+		for _, key := range volatileSettings {
+			delete(data, key)
+		}
+	}
+	return decoded
+}
+
+// encodeDuplicate returns an alias if the snapshot matches the previous one stored for the player,
+// nil if it should be stored normally.
+// Must be called inside a transaction, with the snapshot's JSON already stripped of static data.
+func encodeDuplicate(bucket snapshotBucket, time int64, stripped []byte) ([]byte, error) {
+	key := snapshotKey(time)
+	if bucket.Get(key) != nil {
+		// overwriting, other entries may depend on this one
+		return nil, nil
+	}
+
+	c := bucket.Cursor()
+	k, v := c.Seek(key)
+	if k == nil {
+		k, v = c.Last()
+	} else {
+		k, v = c.Prev()
+	}
+	if k == nil {
+		return nil, nil
+	}
+
+	previousTime, err := strconv.ParseInt(string(k), 10, 64)
+	if err != nil {
+		return nil, nil
+	}
+
+	baseTime := previousTime
+	if isAlias(v) {
+		previousAlias, err := decodeAlias(v)
+		if err != nil {
+			return nil, err
+		}
+		baseTime = previousAlias.Base
+	}
+
+	previous, err := readSnapshotJSON(bucket, previousTime)
+	if err == ErrSnapshotNotFound || err == ErrDeltaBaseNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	previousDecoded, err := decodeJSON(previous)
+	if err != nil {
+		return nil, err
+	}
+	decoded, err := decodeJSON(stripped)
+	if err != nil {
+		return nil, err
+	}
+
+	if !sameJSON(withoutVolatile(previousDecoded), withoutVolatile(decoded)) {
+		return nil, nil
+	}
+
+	// decoded lost its volatile values for the comparison
+	decoded, err = decodeJSON(stripped)
+	if err != nil {
+		return nil, err
+	}
+	return encodeAlias(baseTime, decoded)
+}
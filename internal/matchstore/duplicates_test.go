@@ -0,0 +1,69 @@
+package matchstore
+
+import (
+	"encoding/json"
+	"testing"
+
+	"go.albinodrought.com/neptunes-pride/internal/fixtures"
+	"go.albinodrought.com/neptunes-pride/internal/types"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestDuplicateSnapshots(t *testing.T) {
+	store := openTestStore(t)
+	boltStore := store.(*boltMatchStore)
+
+	snapshots := []*types.APIResponse{}
+	for i := 0; i < 4; i++ {
+		snapshot := fixtures.Load(t, "../opsec/aburrido.json")
+		snapshot.ScanningData.Now += int64(i) * 60 * 1000
+		snapshot.ScanningData.TickFragment += float64(i) / 10
+		if i == 3 {
+			// something finally happened
+			snapshot.ScanningData.Tick++
+		}
+		snapshots = append(snapshots, snapshot)
+
+		if err := store.SaveSnapshot("123", snapshot); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	times, err := store.ListSnapshotTimes("123", 4, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(times) != len(snapshots) {
+		t.Errorf("expected every poll time to be listed, got %v", times)
+	}
+
+	for _, snapshot := range snapshots {
+		found, err := store.FindSnapshot("123", 4, snapshot.ScanningData.Now)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expectedJSON, _ := json.Marshal(snapshot)
+		foundJSON, _ := json.Marshal(found)
+		if string(expectedJSON) != string(foundJSON) {
+			t.Errorf("snapshot at %v did not rebuild to the same data", snapshot.ScanningData.Now)
+		}
+	}
+
+	boltStore.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("gz-snapshots")).Bucket([]byte("123")).Bucket([]byte("4"))
+		for i, snapshot := range snapshots {
+			value := bucket.Get(snapshotKey(snapshot.ScanningData.Now))
+			if (i == 1 || i == 2) != isAlias(value) {
+				t.Errorf("expected only the unchanged polls to be aliases, snapshot %v alias: %v", i, isAlias(value))
+			}
+			if isAlias(value) {
+				decoded, _ := decodeAlias(value)
+				if decoded.Base != snapshots[0].ScanningData.Now {
+					t.Errorf("expected aliases to point at the first snapshot, got %v", decoded.Base)
+				}
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,41 @@
+package matchstore
+
+import (
+	"io"
+	"path/filepath"
+	"testing"
+)
+
+// openTestStore opens an empty bolt store, it's closed and removed after the test
+func openTestStore(t *testing.T) MatchStore {
+	t.Helper()
+
+	store, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.(*boltMatchStore).db.Close() })
+
+	return store
+}
+
+// openTestBackend opens the backend's store at path, it's closed after the test.
+// Opening the same path twice shows what another process would see.
+func openTestBackend(t *testing.T, backend string, path string) MatchStore {
+	t.Helper()
+
+	store, err := OpenBackend(backend, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		switch store := store.(type) {
+		case *boltMatchStore:
+			store.db.Close()
+		case io.Closer:
+			store.Close()
+		}
+	})
+
+	return store
+}
@@ -5,16 +5,20 @@ import (
 	"compress/gzip"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io/ioutil"
+	"sort"
 	"strconv"
 
 	"go.albinodrought.com/neptunes-pride/internal/matches"
+	"go.albinodrought.com/neptunes-pride/internal/stats"
 	"go.albinodrought.com/neptunes-pride/internal/types"
 	bolt "go.etcd.io/bbolt"
 )
 
 var ErrMatchNotFound = errors.New("match not found")
 var ErrSnapshotNotFound = errors.New("snapshot not found")
+var ErrReportNotFound = errors.New("report not found")
 
 type MatchStore interface {
 	Matches() ([]string, error)
@@ -23,25 +27,86 @@ type MatchStore interface {
 	FindMatchOrFail(gameNumber string) (*matches.Match, error)
 	FindOrCreateMatch(gameNumber string) (*matches.Match, error)
 
+	ListSnapshotPlayers(gameNumber string) ([]int, error)
 	ListSnapshotTimes(gameNumber string, playerID int, limit int) ([]int64, error)
 	FindSnapshot(gameNumber string, playerID int, time int64) (*types.APIResponse, error)
 	SaveSnapshot(gameNumber string, snapshot *types.APIResponse) error
 	CompressSnapshots(log func(v ...interface{})) error
+	DeltaEncodeSnapshots(log func(v ...interface{})) error
+	PruneSnapshots(policy RetentionPolicy, dryRun bool, log func(v ...interface{})) ([]PruneResult, error)
+
+	FindReport(gameNumber string) ([]byte, error)
+	SaveReport(gameNumber string, report []byte) error
+
+	ListStats(gameNumber string) ([]stats.Series, error)
+	SaveStats(gameNumber string, rows []stats.Row) error
+
+	// ListAliasGames finds every game an alias played, ignoring case, sorted by game number
+	ListAliasGames(alias string) ([]AliasGame, error)
+	SaveAliases(gameNumber string, aliases map[int]string) error
 }
 
 func Open(path string) (MatchStore, error) {
+	return OpenBolt(path, nil)
+}
+
+func OpenBolt(path string, options *BoltOptions) (MatchStore, error) {
+	if options == nil {
+		options = &DefaultBoltOptions
+	}
+
 	db, err := bolt.Open(path, 0600, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := boot(db); err != nil {
+	db, err = openSchema(db, options)
+	if err != nil {
+		if db != nil {
+			db.Close()
+		}
 		return nil, err
 	}
 
 	return &boltMatchStore{db}, nil
 }
 
+// openSchema creates missing buckets and brings the schema up to date.
+// The returned database replaces db, migrating can compact it into a new file.
+func openSchema(db *bolt.DB, options *BoltOptions) (*bolt.DB, error) {
+	fresh, err := isFresh(db)
+	if err != nil {
+		return db, err
+	}
+
+	if err := boot(db); err != nil {
+		return db, err
+	}
+
+	if fresh {
+		return db, writeSchemaVersion(db, SchemaVersion)
+	}
+
+	version, err := readSchemaVersion(db)
+	if err != nil {
+		return db, err
+	}
+
+	if version > SchemaVersion {
+		return db, ErrSchemaTooNew
+	}
+
+	if version < SchemaVersion {
+		if !options.AutoMigrate {
+			return db, ErrSchemaOutdated
+		}
+		db, _, err = migrate(db, options)
+		return db, err
+	}
+
+	return db, nil
+}
+
 func boot(db *bolt.DB) error {
 	return db.Update(func(tx *bolt.Tx) error {
 		if _, err := tx.CreateBucketIfNotExists([]byte("matches")); err != nil {
@@ -50,6 +115,18 @@ func boot(db *bolt.DB) error {
 		if _, err := tx.CreateBucketIfNotExists([]byte("gz-snapshots")); err != nil {
 			return err
 		}
+		if _, err := tx.CreateBucketIfNotExists([]byte("gz-reports")); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucketIfNotExists([]byte("stats")); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucketIfNotExists([]byte("galaxies")); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucketIfNotExists([]byte("aliases")); err != nil {
+			return err
+		}
 		return nil
 	})
 }
@@ -60,10 +137,11 @@ type boltMatchStore struct {
 
 func (store *boltMatchStore) EachMatch(decode bool, callback func(gameNumber string, match *matches.Match)) error {
 	return store.db.View(func(tx *bolt.Tx) error {
-		match := &matches.Match{}
 		c := tx.Bucket([]byte("matches")).Cursor()
 
 		for k, v := c.First(); k != nil; k, v = c.Next() {
+			// a fresh match each time, fields left out of the JSON must not carry over
+			match := &matches.Match{}
 			if decode {
 				err := json.Unmarshal(v, match)
 				if err != nil {
@@ -143,6 +221,35 @@ func (store *boltMatchStore) FindOrCreateMatch(gameNumber string) (*matches.Matc
 	return foundMatch, nil
 }
 
+func (store *boltMatchStore) ListSnapshotPlayers(gameNumber string) ([]int, error) {
+	playerIDs := []int{}
+
+	err := store.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("gz-snapshots")).Bucket([]byte(gameNumber))
+		if bucket == nil {
+			return ErrMatchNotFound
+		}
+
+		return bucket.ForEach(func(k, v []byte) error {
+			if bucket.Bucket(k) == nil {
+				return nil
+			}
+			playerID, err := strconv.Atoi(string(k))
+			if err == nil {
+				playerIDs = append(playerIDs, playerID)
+			}
+			return nil
+		})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Ints(playerIDs)
+	return playerIDs, nil
+}
+
 func (store *boltMatchStore) ListSnapshotTimes(gameNumber string, playerID int, limit int) ([]int64, error) {
 	snapshotTimes := []int64{}
 
@@ -181,7 +288,7 @@ func (store *boltMatchStore) ListSnapshotTimes(gameNumber string, playerID int,
 func (store *boltMatchStore) FindSnapshot(gameNumber string, playerID int, time int64) (*types.APIResponse, error) {
 	var foundSnapshotSerialized []byte
 
-	err := store.db.Update(func(tx *bolt.Tx) error {
+	err := store.db.View(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte("gz-snapshots")).Bucket([]byte(gameNumber))
 		if bucket == nil {
 			return ErrMatchNotFound
@@ -192,30 +299,21 @@ func (store *boltMatchStore) FindSnapshot(gameNumber string, playerID int, time
 			return ErrSnapshotNotFound
 		}
 
-		foundSnapshotSerialized = bucket.Get([]byte(strconv.FormatInt(time, 10)))
-		if foundSnapshotSerialized == nil {
-			return ErrSnapshotNotFound
+		stripped, err := readSnapshotJSON(boltSnapshotBucket{bucket}, time)
+		if err != nil {
+			return err
 		}
 
-		return nil
+		foundSnapshotSerialized, err = fillStatic(tx.Bucket([]byte("galaxies")), gameNumber, stripped)
+		return err
 	})
 
 	if err != nil {
 		return nil, err
 	}
 
-	unzipper, err := gzip.NewReader(bytes.NewReader(foundSnapshotSerialized))
-	if err != nil {
-		return nil, err
-	}
-
-	decompressedSnapshot, err := ioutil.ReadAll(unzipper)
-	if err != nil {
-		return nil, err
-	}
-
 	foundSnapshot := &types.APIResponse{}
-	err = json.Unmarshal(decompressedSnapshot, foundSnapshot)
+	err = json.Unmarshal(foundSnapshotSerialized, foundSnapshot)
 	if err != nil {
 		return nil, err
 	}
@@ -229,44 +327,264 @@ func (store *boltMatchStore) SaveSnapshot(gameNumber string, snapshot *types.API
 		return err
 	}
 
-	buffer := &bytes.Buffer{}
-	writer := gzip.NewWriter(buffer)
-	if _, err = writer.Write(serialized); err != nil {
-		writer.Close()
-		return err
-	}
-	if err = writer.Close(); err != nil {
+	return store.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.Bucket([]byte("gz-snapshots")).CreateBucketIfNotExists([]byte(gameNumber))
+		if err != nil {
+			return err
+		}
+
+		playerBucket, err := bucket.CreateBucketIfNotExists([]byte(strconv.Itoa(snapshot.ScanningData.PlayerUID)))
+		if err != nil {
+			return err
+		}
+		snapshots := boltSnapshotBucket{playerBucket}
+
+		stripped, err := stripStatic(tx.Bucket([]byte("galaxies")), gameNumber, serialized)
+		if err != nil {
+			return err
+		}
+
+		// nothing happened since the last poll, just extend the previous snapshot
+		encodedSnapshot, err := encodeDuplicate(snapshots, snapshot.ScanningData.Now, stripped)
+		if err != nil {
+			return err
+		}
+
+		if encodedSnapshot == nil {
+			encodedSnapshot, err = encodeSnapshot(snapshots, snapshot.ScanningData.Now, stripped)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = snapshots.Put(snapshotKey(snapshot.ScanningData.Now), encodedSnapshot)
+		if err != nil {
+			return err
+		}
+
+		if err := saveStats(tx, gameNumber, stats.Extract(snapshot)); err != nil {
+			return err
+		}
+		return saveAliases(tx, gameNumber, snapshotAliases(snapshot))
+	})
+}
+
+// statsKey zero-pads ticks so bolt keeps rows in tick order
+func statsKey(tick int) []byte {
+	return []byte(fmt.Sprintf("%010d", tick))
+}
+
+func saveStats(tx *bolt.Tx, gameNumber string, rows []stats.Row) error {
+	bucket, err := tx.Bucket([]byte("stats")).CreateBucketIfNotExists([]byte(gameNumber))
+	if err != nil {
 		return err
 	}
 
-	compressedSnapshot := buffer.Bytes()
+	for _, row := range rows {
+		playerBucket, err := bucket.CreateBucketIfNotExists([]byte(strconv.Itoa(row.PlayerUID)))
+		if err != nil {
+			return err
+		}
 
-	return store.db.Update(func(tx *bolt.Tx) error {
-		bucket, err := tx.Bucket([]byte("gz-snapshots")).CreateBucketIfNotExists([]byte(gameNumber))
+		key := statsKey(row.Tick)
+		if existingSerialized := playerBucket.Get(key); existingSerialized != nil {
+			existing := stats.Row{}
+			if err := json.Unmarshal(existingSerialized, &existing); err != nil {
+				return err
+			}
+			row = stats.Combine(existing, row)
+		}
+
+		serialized, err := json.Marshal(row)
 		if err != nil {
 			return err
 		}
 
-		bucket, err = bucket.CreateBucketIfNotExists([]byte(strconv.Itoa(snapshot.ScanningData.PlayerUID)))
+		if err := playerBucket.Put(key, serialized); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (store *boltMatchStore) SaveStats(gameNumber string, rows []stats.Row) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
+		return saveStats(tx, gameNumber, rows)
+	})
+}
+
+func (store *boltMatchStore) ListStats(gameNumber string) ([]stats.Series, error) {
+	allSeries := []stats.Series{}
+
+	err := store.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("stats")).Bucket([]byte(gameNumber))
+		if bucket == nil {
+			return ErrMatchNotFound
+		}
+
+		return bucket.ForEach(func(k, v []byte) error {
+			// k = player IDs
+			playerBucket := bucket.Bucket(k)
+			if playerBucket == nil {
+				return nil
+			}
+
+			playerUID, err := strconv.Atoi(string(k))
+			if err != nil {
+				return nil
+			}
+
+			series := stats.Series{
+				PlayerUID: playerUID,
+				Rows:      []stats.Row{},
+			}
+
+			err = playerBucket.ForEach(func(k, v []byte) error {
+				// k = tick, v = row
+				row := stats.Row{}
+				if err := json.Unmarshal(v, &row); err != nil {
+					return err
+				}
+				series.PlayerAlias = row.PlayerAlias
+				series.Rows = append(series.Rows, row)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			allSeries = append(allSeries, series)
+			return nil
+		})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(allSeries, func(i, j int) bool {
+		return allSeries[i].PlayerUID < allSeries[j].PlayerUID
+	})
+
+	return allSeries, nil
+}
+
+func saveAliases(tx *bolt.Tx, gameNumber string, aliases map[int]string) error {
+	for playerUID, alias := range aliases {
+		bucket, err := tx.Bucket([]byte("aliases")).CreateBucketIfNotExists([]byte(aliasKey(alias)))
 		if err != nil {
 			return err
 		}
+		if err := bucket.Put([]byte(gameNumber), []byte(strconv.Itoa(playerUID))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (store *boltMatchStore) SaveAliases(gameNumber string, aliases map[int]string) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
+		return saveAliases(tx, gameNumber, aliases)
+	})
+}
+
+func (store *boltMatchStore) ListAliasGames(alias string) ([]AliasGame, error) {
+	games := []AliasGame{}
+
+	err := store.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("aliases")).Bucket([]byte(aliasKey(alias)))
+		if bucket == nil {
+			return nil
+		}
+
+		// k = game number, v = player UID
+		return bucket.ForEach(func(k, v []byte) error {
+			playerUID, err := strconv.Atoi(string(v))
+			if err != nil {
+				return err
+			}
+			games = append(games, AliasGame{GameNumber: string(k), PlayerUID: playerUID})
+			return nil
+		})
+	})
+
+	return games, err
+}
+
+func (store *boltMatchStore) FindReport(gameNumber string) ([]byte, error) {
+	var compressedReport []byte
+
+	err := store.db.View(func(tx *bolt.Tx) error {
+		found := tx.Bucket([]byte("gz-reports")).Get([]byte(gameNumber))
+		if found == nil {
+			return ErrReportNotFound
+		}
+
+		// bolt values are only valid during the transaction
+		compressedReport = append([]byte{}, found...)
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	unzipper, err := gzip.NewReader(bytes.NewReader(compressedReport))
+	if err != nil {
+		return nil, err
+	}
+
+	return ioutil.ReadAll(unzipper)
+}
+
+func (store *boltMatchStore) SaveReport(gameNumber string, report []byte) error {
+	buffer := &bytes.Buffer{}
+	writer := gzip.NewWriter(buffer)
+	if _, err := writer.Write(report); err != nil {
+		writer.Close()
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
 
-		return bucket.Put([]byte(strconv.FormatInt(snapshot.ScanningData.Now, 10)), compressedSnapshot)
+	compressedReport := buffer.Bytes()
+
+	return store.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("gz-reports")).Put([]byte(gameNumber), compressedReport)
 	})
 }
 
 func (store *boltMatchStore) CompressSnapshots(log func(v ...interface{})) error {
 	return store.db.Update(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket([]byte("snapshots"))
+		return compressSnapshots(tx, log)
+	})
+}
+
+func compressSnapshots(tx *bolt.Tx, log func(v ...interface{})) error {
+	bucket := tx.Bucket([]byte("snapshots"))
+	if bucket == nil {
+		return errors.New("no snapshots bucket found - already compressed?")
+	}
+
+	targetBucket := tx.Bucket([]byte("gz-snapshots"))
+
+	err := bucket.ForEach(func(k, v []byte) error {
+		// k = game numbers
+		bucket := bucket.Bucket(k)
 		if bucket == nil {
-			return errors.New("no snapshots bucket found - already compressed?")
+			return nil
 		}
 
-		targetBucket := tx.Bucket([]byte("gz-snapshots"))
+		targetBucket, err := targetBucket.CreateBucketIfNotExists(k)
+		if err != nil {
+			return err
+		}
+		log("compressing for game ", string(k))
 
-		err := bucket.ForEach(func(k, v []byte) error {
-			// k = game numbers
+		return bucket.ForEach(func(k, v []byte) error {
+			// k = player IDs
 			bucket := bucket.Bucket(k)
 			if bucket == nil {
 				return nil
@@ -276,45 +594,31 @@ func (store *boltMatchStore) CompressSnapshots(log func(v ...interface{})) error
 			if err != nil {
 				return err
 			}
-			log("compressing for game ", string(k))
+			log("compressing for player ", string(k))
 
 			return bucket.ForEach(func(k, v []byte) error {
-				// k = player IDs
-				bucket := bucket.Bucket(k)
-				if bucket == nil {
-					return nil
+				// k = snapshot time, v = uncompressed snapshot
+				log("compressing ", string(k))
+				buffer := &bytes.Buffer{}
+				writer := gzip.NewWriter(buffer)
+				if _, err = writer.Write(v); err != nil {
+					writer.Close()
+					return err
 				}
-
-				targetBucket, err := targetBucket.CreateBucketIfNotExists(k)
-				if err != nil {
+				if err = writer.Close(); err != nil {
 					return err
 				}
-				log("compressing for player ", string(k))
-
-				return bucket.ForEach(func(k, v []byte) error {
-					// k = snapshot time, v = uncompressed snapshot
-					log("compressing ", string(k))
-					buffer := &bytes.Buffer{}
-					writer := gzip.NewWriter(buffer)
-					if _, err = writer.Write(v); err != nil {
-						writer.Close()
-						return err
-					}
-					if err = writer.Close(); err != nil {
-						return err
-					}
-
-					compressedSnapshot := buffer.Bytes()
-					return targetBucket.Put(k, compressedSnapshot)
-				})
+
+				compressedSnapshot := buffer.Bytes()
+				return targetBucket.Put(k, compressedSnapshot)
 			})
 		})
+	})
 
-		if err != nil {
-			return err
-		}
+	if err != nil {
+		return err
+	}
 
-		log("removing old bucket")
-		return tx.DeleteBucket([]byte("snapshots"))
-	})
+	log("removing old bucket")
+	return tx.DeleteBucket([]byte("snapshots"))
 }
@@ -0,0 +1,563 @@
+package matchstore
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+
+	"go.albinodrought.com/neptunes-pride/internal/matches"
+	"go.albinodrought.com/neptunes-pride/internal/stats"
+	"go.albinodrought.com/neptunes-pride/internal/types"
+)
+
+// memoryMatchStore keeps everything in memory with the same encodings as the other stores,
+// for tests and demos that shouldn't leave a database behind
+type memoryMatchStore struct {
+	mutex sync.RWMutex
+
+	// matches are kept serialized so callers can't change them without saving
+	matches   map[string][]byte
+	snapshots map[string]map[int]*memorySnapshotBucket
+	reports   map[string][]byte
+	stats     map[string]map[int]map[int]stats.Row
+	galaxies  memoryValueBucket
+	// aliases map alias keys to game numbers to player UIDs
+	aliases map[string]map[string]int
+}
+
+func OpenMemory() MatchStore {
+	return &memoryMatchStore{
+		matches:   map[string][]byte{},
+		snapshots: map[string]map[int]*memorySnapshotBucket{},
+		reports:   map[string][]byte{},
+		stats:     map[string]map[int]map[int]stats.Row{},
+		galaxies:  memoryValueBucket{},
+		aliases:   map[string]map[string]int{},
+	}
+}
+
+type memoryValueBucket map[string][]byte
+
+func (bucket memoryValueBucket) Get(key []byte) []byte {
+	return bucket[string(key)]
+}
+
+func (bucket memoryValueBucket) Put(key []byte, value []byte) error {
+	bucket[string(key)] = append([]byte{}, value...)
+	return nil
+}
+
+// memorySnapshotBucket keeps a player's snapshot times sorted, like bolt keeps its keys
+type memorySnapshotBucket struct {
+	times  []int64
+	values map[int64][]byte
+}
+
+func newMemorySnapshotBucket() *memorySnapshotBucket {
+	return &memorySnapshotBucket{
+		times:  []int64{},
+		values: map[int64][]byte{},
+	}
+}
+
+// search returns the index of the first time at or after the given one
+func (bucket *memorySnapshotBucket) search(time int64) int {
+	return sort.Search(len(bucket.times), func(i int) bool {
+		return bucket.times[i] >= time
+	})
+}
+
+func (bucket *memorySnapshotBucket) Get(key []byte) []byte {
+	time, err := strconv.ParseInt(string(key), 10, 64)
+	if err != nil {
+		return nil
+	}
+	return bucket.values[time]
+}
+
+func (bucket *memorySnapshotBucket) Put(key []byte, value []byte) error {
+	time, err := strconv.ParseInt(string(key), 10, 64)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := bucket.values[time]; !ok {
+		i := bucket.search(time)
+		bucket.times = append(bucket.times, 0)
+		copy(bucket.times[i+1:], bucket.times[i:])
+		bucket.times[i] = time
+	}
+	bucket.values[time] = append([]byte{}, value...)
+	return nil
+}
+
+func (bucket *memorySnapshotBucket) Delete(key []byte) error {
+	time, err := strconv.ParseInt(string(key), 10, 64)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := bucket.values[time]; !ok {
+		return nil
+	}
+	i := bucket.search(time)
+	bucket.times = append(bucket.times[:i], bucket.times[i+1:]...)
+	delete(bucket.values, time)
+	return nil
+}
+
+func (bucket *memorySnapshotBucket) ForEach(fn func(k, v []byte) error) error {
+	times := append([]int64{}, bucket.times...)
+	for _, time := range times {
+		if err := fn(snapshotKey(time), bucket.values[time]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (bucket *memorySnapshotBucket) Cursor() snapshotCursor {
+	return &memorySnapshotCursor{bucket: bucket, i: -1}
+}
+
+type memorySnapshotCursor struct {
+	bucket *memorySnapshotBucket
+	// i is the current position, out of range once the cursor ran off either end
+	i int
+}
+
+func (c *memorySnapshotCursor) current() ([]byte, []byte) {
+	if c.i < 0 || c.i >= len(c.bucket.times) {
+		return nil, nil
+	}
+	time := c.bucket.times[c.i]
+	return snapshotKey(time), c.bucket.values[time]
+}
+
+func (c *memorySnapshotCursor) Seek(seek []byte) ([]byte, []byte) {
+	time, err := strconv.ParseInt(string(seek), 10, 64)
+	if err != nil {
+		c.i = len(c.bucket.times)
+		return nil, nil
+	}
+	c.i = c.bucket.search(time)
+	return c.current()
+}
+
+func (c *memorySnapshotCursor) Last() ([]byte, []byte) {
+	c.i = len(c.bucket.times) - 1
+	return c.current()
+}
+
+func (c *memorySnapshotCursor) Prev() ([]byte, []byte) {
+	if c.i < 0 || c.i >= len(c.bucket.times) {
+		return nil, nil
+	}
+	c.i--
+	return c.current()
+}
+
+func (store *memoryMatchStore) EachMatch(decode bool, callback func(gameNumber string, match *matches.Match)) error {
+	store.mutex.RLock()
+	gameNumbers := make([]string, 0, len(store.matches))
+	for gameNumber := range store.matches {
+		gameNumbers = append(gameNumbers, gameNumber)
+	}
+	sort.Strings(gameNumbers)
+
+	serializedMatches := make([][]byte, len(gameNumbers))
+	for i, gameNumber := range gameNumbers {
+		serializedMatches[i] = store.matches[gameNumber]
+	}
+	store.mutex.RUnlock()
+
+	for i, gameNumber := range gameNumbers {
+		match := &matches.Match{}
+		if decode {
+			if err := json.Unmarshal(serializedMatches[i], match); err != nil {
+				return err
+			}
+		}
+		callback(gameNumber, match)
+	}
+
+	return nil
+}
+
+func (store *memoryMatchStore) Matches() ([]string, error) {
+	gameNumbers := []string{}
+
+	err := store.EachMatch(false, func(gameNumber string, match *matches.Match) {
+		gameNumbers = append(gameNumbers, gameNumber)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return gameNumbers, nil
+}
+
+func (store *memoryMatchStore) SaveMatch(match *matches.Match) error {
+	serialized, err := json.Marshal(match)
+	if err != nil {
+		return err
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.matches[match.GameNumber] = serialized
+	return nil
+}
+
+func (store *memoryMatchStore) FindMatchOrFail(gameNumber string) (*matches.Match, error) {
+	store.mutex.RLock()
+	foundMatchSerialized, ok := store.matches[gameNumber]
+	store.mutex.RUnlock()
+
+	if !ok {
+		return nil, ErrMatchNotFound
+	}
+
+	foundMatch := &matches.Match{}
+	err := json.Unmarshal(foundMatchSerialized, foundMatch)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return foundMatch, nil
+}
+
+func (store *memoryMatchStore) FindOrCreateMatch(gameNumber string) (*matches.Match, error) {
+	foundMatch, err := store.FindMatchOrFail(gameNumber)
+
+	if err == ErrMatchNotFound {
+		foundMatch = matches.NewMatch(gameNumber)
+		err = store.SaveMatch(foundMatch)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return foundMatch, nil
+}
+
+// snapshotBucket finds a player's snapshots, with the same errors as bolt's missing buckets.
+// Must be called with the mutex held.
+func (store *memoryMatchStore) snapshotBucket(gameNumber string, playerID int) (*memorySnapshotBucket, error) {
+	players, ok := store.snapshots[gameNumber]
+	if !ok {
+		return nil, ErrMatchNotFound
+	}
+
+	bucket, ok := players[playerID]
+	if !ok {
+		return nil, ErrSnapshotNotFound
+	}
+
+	return bucket, nil
+}
+
+func (store *memoryMatchStore) ListSnapshotPlayers(gameNumber string) ([]int, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	players, ok := store.snapshots[gameNumber]
+	if !ok {
+		return nil, ErrMatchNotFound
+	}
+
+	playerIDs := make([]int, 0, len(players))
+	for playerID := range players {
+		playerIDs = append(playerIDs, playerID)
+	}
+	sort.Ints(playerIDs)
+
+	return playerIDs, nil
+}
+
+func (store *memoryMatchStore) ListSnapshotTimes(gameNumber string, playerID int, limit int) ([]int64, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	bucket, err := store.snapshotBucket(gameNumber, playerID)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshotTimes := []int64{}
+	for i := len(bucket.times) - 1; i >= 0 && len(snapshotTimes) < limit; i-- {
+		snapshotTimes = append(snapshotTimes, bucket.times[i])
+	}
+
+	return snapshotTimes, nil
+}
+
+func (store *memoryMatchStore) FindSnapshot(gameNumber string, playerID int, time int64) (*types.APIResponse, error) {
+	store.mutex.RLock()
+	foundSnapshotSerialized, err := func() ([]byte, error) {
+		bucket, err := store.snapshotBucket(gameNumber, playerID)
+		if err != nil {
+			return nil, err
+		}
+
+		stripped, err := readSnapshotJSON(bucket, time)
+		if err != nil {
+			return nil, err
+		}
+
+		return fillStatic(store.galaxies, gameNumber, stripped)
+	}()
+	store.mutex.RUnlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	foundSnapshot := &types.APIResponse{}
+	err = json.Unmarshal(foundSnapshotSerialized, foundSnapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	return foundSnapshot, nil
+}
+
+func (store *memoryMatchStore) SaveSnapshot(gameNumber string, snapshot *types.APIResponse) error {
+	serialized, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	players, ok := store.snapshots[gameNumber]
+	if !ok {
+		players = map[int]*memorySnapshotBucket{}
+		store.snapshots[gameNumber] = players
+	}
+
+	snapshots, ok := players[snapshot.ScanningData.PlayerUID]
+	if !ok {
+		snapshots = newMemorySnapshotBucket()
+		players[snapshot.ScanningData.PlayerUID] = snapshots
+	}
+
+	stripped, err := stripStatic(store.galaxies, gameNumber, serialized)
+	if err != nil {
+		return err
+	}
+
+	// nothing happened since the last poll, just extend the previous snapshot
+	encodedSnapshot, err := encodeDuplicate(snapshots, snapshot.ScanningData.Now, stripped)
+	if err != nil {
+		return err
+	}
+
+	if encodedSnapshot == nil {
+		encodedSnapshot, err = encodeSnapshot(snapshots, snapshot.ScanningData.Now, stripped)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = snapshots.Put(snapshotKey(snapshot.ScanningData.Now), encodedSnapshot)
+	if err != nil {
+		return err
+	}
+
+	store.saveStats(gameNumber, stats.Extract(snapshot))
+	store.saveAliases(gameNumber, snapshotAliases(snapshot))
+	return nil
+}
+
+// saveStats must be called with the mutex held
+func (store *memoryMatchStore) saveStats(gameNumber string, rows []stats.Row) {
+	players, ok := store.stats[gameNumber]
+	if !ok {
+		players = map[int]map[int]stats.Row{}
+		store.stats[gameNumber] = players
+	}
+
+	for _, row := range rows {
+		ticks, ok := players[row.PlayerUID]
+		if !ok {
+			ticks = map[int]stats.Row{}
+			players[row.PlayerUID] = ticks
+		}
+
+		if existing, ok := ticks[row.Tick]; ok {
+			row = stats.Combine(existing, row)
+		}
+		ticks[row.Tick] = row
+	}
+}
+
+func (store *memoryMatchStore) SaveStats(gameNumber string, rows []stats.Row) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.saveStats(gameNumber, rows)
+	return nil
+}
+
+func (store *memoryMatchStore) ListStats(gameNumber string) ([]stats.Series, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	players, ok := store.stats[gameNumber]
+	if !ok {
+		return nil, ErrMatchNotFound
+	}
+
+	allSeries := []stats.Series{}
+	for playerUID, ticks := range players {
+		series := stats.Series{
+			PlayerUID: playerUID,
+			Rows:      make([]stats.Row, 0, len(ticks)),
+		}
+		for _, row := range ticks {
+			series.Rows = append(series.Rows, row)
+		}
+		sort.Slice(series.Rows, func(i, j int) bool {
+			return series.Rows[i].Tick < series.Rows[j].Tick
+		})
+		if len(series.Rows) > 0 {
+			series.PlayerAlias = series.Rows[len(series.Rows)-1].PlayerAlias
+		}
+		allSeries = append(allSeries, series)
+	}
+
+	sort.Slice(allSeries, func(i, j int) bool {
+		return allSeries[i].PlayerUID < allSeries[j].PlayerUID
+	})
+
+	return allSeries, nil
+}
+
+func (store *memoryMatchStore) FindReport(gameNumber string) ([]byte, error) {
+	store.mutex.RLock()
+	report, ok := store.reports[gameNumber]
+	store.mutex.RUnlock()
+
+	if !ok {
+		return nil, ErrReportNotFound
+	}
+
+	return append([]byte{}, report...), nil
+}
+
+func (store *memoryMatchStore) SaveReport(gameNumber string, report []byte) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.reports[gameNumber] = append([]byte{}, report...)
+	return nil
+}
+
+// CompressSnapshots has nothing to do, memory stores were never written uncompressed
+func (store *memoryMatchStore) CompressSnapshots(log func(v ...interface{})) error {
+	return errors.New("no uncompressed snapshots in memory stores - already compressed")
+}
+
+type memorySnapshotPath struct {
+	gameNumber string
+	playerUID  int
+}
+
+// snapshotPaths must be called with the mutex held
+func (store *memoryMatchStore) snapshotPaths() []memorySnapshotPath {
+	paths := []memorySnapshotPath{}
+	for gameNumber, players := range store.snapshots {
+		for playerUID := range players {
+			paths = append(paths, memorySnapshotPath{gameNumber, playerUID})
+		}
+	}
+
+	sort.Slice(paths, func(i, j int) bool {
+		if paths[i].gameNumber != paths[j].gameNumber {
+			return paths[i].gameNumber < paths[j].gameNumber
+		}
+		return paths[i].playerUID < paths[j].playerUID
+	})
+
+	return paths
+}
+
+func (store *memoryMatchStore) DeltaEncodeSnapshots(log func(v ...interface{})) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	for _, path := range store.snapshotPaths() {
+		converted, err := deltaEncodeBucket(store.snapshots[path.gameNumber][path.playerUID])
+		if err != nil {
+			return err
+		}
+
+		log("delta encoded ", converted, " snapshots for game ", path.gameNumber, " player ", path.playerUID)
+	}
+
+	return nil
+}
+
+func (store *memoryMatchStore) PruneSnapshots(policy RetentionPolicy, dryRun bool, log func(v ...interface{})) ([]PruneResult, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	results := []PruneResult{}
+	for _, path := range store.snapshotPaths() {
+		result, err := pruneBucket(store.snapshots[path.gameNumber][path.playerUID], policy, dryRun)
+		if err != nil {
+			return results, err
+		}
+
+		result.GameNumber = path.gameNumber
+		result.PlayerID = strconv.Itoa(path.playerUID)
+		results = append(results, result)
+
+		logPruneResult(log, result)
+	}
+
+	return results, nil
+}
+
+func (store *memoryMatchStore) saveAliases(gameNumber string, aliases map[int]string) {
+	for playerUID, alias := range aliases {
+		games, ok := store.aliases[aliasKey(alias)]
+		if !ok {
+			games = map[string]int{}
+			store.aliases[aliasKey(alias)] = games
+		}
+		games[gameNumber] = playerUID
+	}
+}
+
+func (store *memoryMatchStore) SaveAliases(gameNumber string, aliases map[int]string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.saveAliases(gameNumber, aliases)
+	return nil
+}
+
+func (store *memoryMatchStore) ListAliasGames(alias string) ([]AliasGame, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	games := []AliasGame{}
+	for gameNumber, playerUID := range store.aliases[aliasKey(alias)] {
+		games = append(games, AliasGame{GameNumber: gameNumber, PlayerUID: playerUID})
+	}
+
+	sort.Slice(games, func(i, j int) bool {
+		return games[i].GameNumber < games[j].GameNumber
+	})
+
+	return games, nil
+}
@@ -0,0 +1,317 @@
+package matchstore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"go.albinodrought.com/neptunes-pride/internal/matches"
+	"go.albinodrought.com/neptunes-pride/internal/secrets"
+	bolt "go.etcd.io/bbolt"
+)
+
+var ErrSchemaOutdated = errors.New("database schema is outdated, run `np-scanner migrate` first")
+var ErrSchemaTooNew = errors.New("database schema is newer than this version of np-scanner supports")
+
+var metaBucket = []byte("meta")
+var schemaVersionKey = []byte("schema-version")
+
+// migration upgrades a bolt database by one schema version.
+// Steps manage their own transactions and must be safe to run again if interrupted.
+type migration struct {
+	name string
+	up   func(db *bolt.DB, options *BoltOptions) error
+	// seals secrets when a key is configured: the plain text is left behind in freed pages
+	// and in the backup, so the database gets compacted and the backup removed
+	seals bool
+}
+
+// migrations run in order, the schema version is how many have run.
+// Append new steps, never reorder or remove them.
+var migrations = []migration{
+	{"compress snapshots", migrateCompressSnapshots, false},
+	{"move old access codes into access profiles", migrateOldAccessCodes, false},
+	{"delta encode snapshots", migrateDeltaEncodeSnapshots, false},
+	{"index player aliases", migrateIndexAliases, false},
+	{"encrypt match secrets", migrateEncryptSecrets, true},
+}
+
+// SchemaVersion is the version a bolt database has once every migration ran
+var SchemaVersion = len(migrations)
+
+type BoltOptions struct {
+	// AutoMigrate upgrades an outdated schema when opening, otherwise ErrSchemaOutdated is returned
+	AutoMigrate bool
+	// Backup copies the database next to itself before migrating.
+	// The backup is removed again if migrating encrypted plain text secrets.
+	Backup bool
+	// Secrets encrypts existing match secrets while migrating, nil leaves them as they are
+	Secrets *secrets.Keyring
+	Log     func(v ...interface{})
+}
+
+var DefaultBoltOptions = BoltOptions{
+	AutoMigrate: true,
+	Backup:      true,
+	Log:         log.Println,
+}
+
+func readSchemaVersion(db *bolt.DB) (int, error) {
+	version := 0
+
+	err := db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(metaBucket)
+		if bucket == nil {
+			return nil
+		}
+
+		serialized := bucket.Get(schemaVersionKey)
+		if serialized == nil {
+			return nil
+		}
+
+		var err error
+		version, err = strconv.Atoi(string(serialized))
+		return err
+	})
+
+	return version, err
+}
+
+func writeSchemaVersion(db *bolt.DB, version int) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(metaBucket)
+		if err != nil {
+			return err
+		}
+		return bucket.Put(schemaVersionKey, []byte(strconv.Itoa(version)))
+	})
+}
+
+// isFresh returns true for databases that were just created, they need no migrations
+func isFresh(db *bolt.DB) (bool, error) {
+	fresh := false
+	err := db.View(func(tx *bolt.Tx) error {
+		fresh = tx.Bucket([]byte("matches")) == nil && tx.Bucket([]byte("snapshots")) == nil
+		return nil
+	})
+	return fresh, err
+}
+
+// backup copies the database to a timestamped file next to it
+func backup(db *bolt.DB, version int) (string, error) {
+	backupPath := fmt.Sprintf("%v.schema-v%v-%v.bak", db.Path(), version, time.Now().Format("20060102-150405"))
+
+	err := db.View(func(tx *bolt.Tx) error {
+		return tx.CopyFile(backupPath, 0600)
+	})
+
+	return backupPath, err
+}
+
+// compactTxMaxSize bounds how much is copied per transaction while compacting
+const compactTxMaxSize = 64 << 20
+
+// compact rewrites the database into a fresh file, leaving nothing behind in freed pages.
+// The returned database replaces db, which is closed.
+func compact(db *bolt.DB) (*bolt.DB, error) {
+	path := db.Path()
+	compactPath := path + ".compact"
+
+	dst, err := bolt.Open(compactPath, 0600, nil)
+	if err != nil {
+		return db, err
+	}
+	if err := bolt.Compact(dst, db, compactTxMaxSize); err != nil {
+		dst.Close()
+		os.Remove(compactPath)
+		return db, err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(compactPath)
+		return db, err
+	}
+
+	if err := db.Close(); err != nil {
+		os.Remove(compactPath)
+		return db, err
+	}
+	renameErr := os.Rename(compactPath, path)
+
+	db, err = bolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, err
+	}
+	return db, renameErr
+}
+
+// migrate runs every pending migration, returning the names of the ones that ran.
+// The returned database replaces db, which is closed if the database had to be compacted.
+func migrate(db *bolt.DB, options *BoltOptions) (*bolt.DB, []string, error) {
+	applied := []string{}
+
+	version, err := readSchemaVersion(db)
+	if err != nil {
+		return db, applied, err
+	}
+
+	if version > SchemaVersion {
+		return db, applied, ErrSchemaTooNew
+	}
+	if version == SchemaVersion {
+		return db, applied, nil
+	}
+
+	backupPath := ""
+	if options.Backup {
+		backupPath, err = backup(db, version)
+		if err != nil {
+			return db, applied, err
+		}
+		options.Log("backed up database to ", backupPath)
+	}
+
+	sealed := false
+	for ; version < SchemaVersion; version++ {
+		step := migrations[version]
+		options.Log("migrating to schema version ", version+1, ": ", step.name)
+
+		if err := step.up(db, options); err != nil {
+			return db, applied, fmt.Errorf("migration to schema version %v failed: %w", version+1, err)
+		}
+		if err := writeSchemaVersion(db, version+1); err != nil {
+			return db, applied, err
+		}
+
+		sealed = sealed || (step.seals && options.Secrets != nil)
+		applied = append(applied, step.name)
+	}
+
+	if !sealed {
+		return db, applied, nil
+	}
+
+	options.Log("compacting database to drop plain text secrets")
+	db, err = compact(db)
+	if err != nil {
+		return db, applied, err
+	}
+
+	if backupPath != "" {
+		if err := os.Remove(backupPath); err != nil {
+			return db, applied, err
+		}
+		options.Log("removed backup ", backupPath, ", it held match secrets in plain text")
+	}
+
+	return db, applied, nil
+}
+
+// Migrate upgrades the bolt database at path to the latest schema, returning the names of the migrations that ran
+func Migrate(path string, options *BoltOptions) ([]string, error) {
+	if options == nil {
+		options = &DefaultBoltOptions
+	}
+
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	fresh, err := isFresh(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if err := boot(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if fresh {
+		defer db.Close()
+		return []string{}, writeSchemaVersion(db, SchemaVersion)
+	}
+
+	db, applied, err := migrate(db, options)
+	if db != nil {
+		db.Close()
+	}
+	return applied, err
+}
+
+func migrateCompressSnapshots(db *bolt.DB, options *BoltOptions) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket([]byte("snapshots")) == nil {
+			return nil
+		}
+		return compressSnapshots(tx, options.Log)
+	})
+}
+
+func migrateOldAccessCodes(db *bolt.DB, options *BoltOptions) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("matches"))
+
+		updated := map[string][]byte{}
+		err := bucket.ForEach(func(k, v []byte) error {
+			match := &matches.Match{}
+			if err := json.Unmarshal(v, match); err != nil {
+				return err
+			}
+
+			if len(match.OldAccessCode) == 0 {
+				return nil
+			}
+
+			// old access codes could view everything, and were checked first
+			accessProfile := matches.PermissiveAccessProfile()
+			accessProfile.Code = match.OldAccessCode
+			match.AccessProfiles = append([]matches.AccessProfile{accessProfile}, match.AccessProfiles...)
+			match.OldAccessCode = nil
+
+			serialized, err := json.Marshal(match)
+			if err != nil {
+				return err
+			}
+			updated[string(k)] = serialized
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for gameNumber, serialized := range updated {
+			options.Log("moved old access code into an access profile for game ", gameNumber)
+			if err := bucket.Put([]byte(gameNumber), serialized); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func migrateDeltaEncodeSnapshots(db *bolt.DB, options *BoltOptions) error {
+	store := &boltMatchStore{db}
+	return store.DeltaEncodeSnapshots(options.Log)
+}
+
+func migrateEncryptSecrets(db *bolt.DB, options *BoltOptions) error {
+	if options.Secrets == nil {
+		options.Log("no secret key configured, match secrets stay in plain text until `np-scanner rotate-secret-key` runs with one")
+		return nil
+	}
+
+	_, err := ResealSecrets(&boltMatchStore{db}, options.Secrets, options.Log)
+	return err
+}
+
+func migrateIndexAliases(db *bolt.DB, options *BoltOptions) error {
+	return IndexAliases(&boltMatchStore{db}, options.Log)
+}
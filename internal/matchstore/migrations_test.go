@@ -0,0 +1,159 @@
+package matchstore
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"go.albinodrought.com/neptunes-pride/internal/matches"
+	"go.albinodrought.com/neptunes-pride/internal/secrets"
+	"go.albinodrought.com/neptunes-pride/internal/types"
+	bolt "go.etcd.io/bbolt"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestMigrations(t *testing.T) {
+	dir, err := ioutil.TempDir("", "matchstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	snapshotSerialized, err := ioutil.ReadFile("../opsec/aburrido.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot := &types.APIResponse{}
+	if err := json.Unmarshal(snapshotSerialized, snapshot); err != nil {
+		t.Fatal(err)
+	}
+
+	accessCode, err := bcrypt.GenerateFromPassword([]byte("hunter2"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	match := matches.NewMatch("123")
+	match.OldAccessCode = accessCode
+	match.PlayerCreds[4] = matches.PlayerCreds{PlayerUID: 4, APIKey: "plaintext-api-key"}
+	matchSerialized, _ := json.Marshal(match)
+
+	// a database from before schema versions, with uncompressed snapshots and an old access code
+	path := filepath.Join(dir, "test.db")
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucket([]byte("matches"))
+		if err != nil {
+			return err
+		}
+		if err := bucket.Put([]byte("123"), matchSerialized); err != nil {
+			return err
+		}
+
+		bucket, err = tx.CreateBucket([]byte("snapshots"))
+		if err != nil {
+			return err
+		}
+		bucket, err = bucket.CreateBucket([]byte("123"))
+		if err != nil {
+			return err
+		}
+		bucket, err = bucket.CreateBucket([]byte("4"))
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(strconv.FormatInt(snapshot.ScanningData.Now, 10)), snapshotSerialized)
+	})
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secretKey, _ := secrets.GenerateKey()
+	parsedKey, _ := secrets.ParseKey(secretKey)
+	keyring, err := secrets.NewKeyring(parsedKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	options := &BoltOptions{
+		AutoMigrate: false,
+		Backup:      true,
+		Secrets:     keyring,
+		Log:         func(v ...interface{}) {},
+	}
+	if _, err := OpenBolt(path, options); err != ErrSchemaOutdated {
+		t.Fatalf("expected the outdated schema to be refused, got %v", err)
+	}
+
+	applied, err := Migrate(path, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != SchemaVersion {
+		t.Errorf("expected every migration to run, got %v", applied)
+	}
+
+	// the backup and freed pages would still hold the plain text API key
+	backups, _ := filepath.Glob(path + ".schema-v0-*.bak")
+	if len(backups) != 0 {
+		t.Errorf("expected the backup to be removed, got %v", backups)
+	}
+	if raw, _ := ioutil.ReadFile(path); bytes.Contains(raw, []byte("plaintext-api-key")) {
+		t.Error("expected the plain text API key to be compacted away")
+	}
+
+	if applied, _ := Migrate(path, options); len(applied) != 0 {
+		t.Errorf("expected nothing left to migrate, got %v", applied)
+	}
+
+	store, err := OpenBolt(path, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.(*boltMatchStore).db.Close()
+
+	version, err := readSchemaVersion(store.(*boltMatchStore).db)
+	if err != nil || version != SchemaVersion {
+		t.Errorf("expected schema version %v, got %v (%v)", SchemaVersion, version, err)
+	}
+
+	found, err := store.FindSnapshot("123", 4, snapshot.ScanningData.Now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.ScanningData.Tick != snapshot.ScanningData.Tick {
+		t.Errorf("expected the compressed snapshot to be readable")
+	}
+
+	migratedMatch, err := store.FindMatchOrFail("123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if migratedMatch.OldAccessCode != nil {
+		t.Error("expected the old access code to be moved")
+	}
+	accessProfile, err := migratedMatch.CheckAccessCode([]byte("hunter2"))
+	if err != nil || !accessProfile.CanViewEveryPlayer {
+		t.Errorf("expected the old access code to still view everything, got %+v (%v)", accessProfile, err)
+	}
+
+	if !secrets.IsSealed(migratedMatch.PlayerCreds[4].APIKey) {
+		t.Error("expected the API key to be encrypted")
+	}
+	openedMatch, err := WithSecrets(store, keyring).FindMatchOrFail("123")
+	if err != nil || openedMatch.PlayerCreds[4].APIKey != "plaintext-api-key" {
+		t.Errorf("expected the API key to open, got %+v (%v)", openedMatch, err)
+	}
+
+	games, err := store.ListAliasGames(snapshot.ScanningData.Players["4"].Alias)
+	if err != nil || len(games) != 1 || games[0].GameNumber != "123" || games[0].PlayerUID != 4 {
+		t.Errorf("expected the old snapshot's aliases to be indexed, got %+v (%v)", games, err)
+	}
+}
@@ -0,0 +1,252 @@
+package matchstore
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// RetentionPolicy decides which snapshots are kept as they age:
+// everything is kept for KeepAll, then one per tick for PerTick, then one per production cycle.
+// The newest snapshot of every player is always kept.
+type RetentionPolicy struct {
+	KeepAll time.Duration
+	PerTick time.Duration
+	// Now is when snapshot ages are measured from, zero for the current time
+	Now time.Time
+}
+
+var DefaultRetentionPolicy = RetentionPolicy{
+	KeepAll: 3 * 24 * time.Hour,
+	PerTick: 14 * 24 * time.Hour,
+}
+
+type PruneResult struct {
+	GameNumber string `json:"game_number"`
+	PlayerID   string `json:"player_id"`
+	Total      int    `json:"total"`
+	Removed    int    `json:"removed"`
+	// Rewritten counts kept snapshots that were stored against a removed one
+	Rewritten int `json:"rewritten"`
+}
+
+type storedEntry struct {
+	key         []byte
+	time        int64
+	tick        int
+	productions int
+	// base is the key this entry is stored against, nil for keyframes
+	base []byte
+}
+
+// snapshotMeta is the little of a snapshot that retention needs
+type snapshotMeta struct {
+	ScanningData struct {
+		Tick        int `json:"tick"`
+		Productions int `json:"productions"`
+	} `json:"scanning_data"`
+}
+
+func readEntries(bucket snapshotBucket) ([]storedEntry, error) {
+	entries := []storedEntry{}
+
+	err := bucket.ForEach(func(k, v []byte) error {
+		time, err := strconv.ParseInt(string(k), 10, 64)
+		if err != nil {
+			return nil
+		}
+
+		entry := storedEntry{
+			key:  append([]byte{}, k...),
+			time: time,
+		}
+
+		if isDelta(v) {
+			baseTime, err := deltaBase(v)
+			if err != nil {
+				return err
+			}
+			entry.base = snapshotKey(baseTime)
+		} else if isAlias(v) {
+			decoded, err := decodeAlias(v)
+			if err != nil {
+				return err
+			}
+			entry.base = snapshotKey(decoded.Base)
+		}
+
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range entries {
+		serialized, err := readSnapshotJSON(bucket, entries[i].time)
+		if err != nil {
+			return nil, err
+		}
+
+		meta := snapshotMeta{}
+		if err := json.Unmarshal(serialized, &meta); err != nil {
+			return nil, err
+		}
+		entries[i].tick = meta.ScanningData.Tick
+		entries[i].productions = meta.ScanningData.Productions
+	}
+
+	return entries, nil
+}
+
+// selectRemovals applies the policy to entries sorted oldest first
+func selectRemovals(entries []storedEntry, policy RetentionPolicy) map[string]bool {
+	now := policy.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	keepAllAfter := now.Add(-policy.KeepAll).UnixNano() / int64(time.Millisecond)
+	perTickAfter := now.Add(-policy.KeepAll-policy.PerTick).UnixNano() / int64(time.Millisecond)
+
+	removals := map[string]bool{}
+	keptTicks := map[int]bool{}
+	keptProductions := map[int]bool{}
+
+	// newest first, so the last snapshot of each tick or production is the one kept
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		if i == len(entries)-1 || entry.time >= keepAllAfter {
+			keptTicks[entry.tick] = true
+			keptProductions[entry.productions] = true
+			continue
+		}
+
+		if entry.time >= perTickAfter {
+			if keptTicks[entry.tick] {
+				removals[string(entry.key)] = true
+			}
+			keptTicks[entry.tick] = true
+			keptProductions[entry.productions] = true
+			continue
+		}
+
+		if keptProductions[entry.productions] {
+			removals[string(entry.key)] = true
+		}
+		keptProductions[entry.productions] = true
+	}
+
+	return removals
+}
+
+// pruneBucket removes a player's snapshots according to the policy.
+// Kept snapshots stored against a removed one are re-encoded first.
+func pruneBucket(bucket snapshotBucket, policy RetentionPolicy, dryRun bool) (PruneResult, error) {
+	result := PruneResult{}
+
+	entries, err := readEntries(bucket)
+	if err != nil {
+		return result, err
+	}
+	result.Total = len(entries)
+
+	removals := selectRemovals(entries, policy)
+	result.Removed = len(removals)
+
+	rewrites := []storedEntry{}
+	for _, entry := range entries {
+		if entry.base != nil && !removals[string(entry.key)] && removals[string(entry.base)] {
+			rewrites = append(rewrites, entry)
+		}
+	}
+	result.Rewritten = len(rewrites)
+
+	if dryRun || len(removals) == 0 {
+		return result, nil
+	}
+
+	// rebuild before anything they depend on is gone
+	rebuilt := make([][]byte, len(rewrites))
+	for i, entry := range rewrites {
+		rebuilt[i], err = readSnapshotJSON(bucket, entry.time)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	for _, entry := range rewrites {
+		if err := bucket.Delete(entry.key); err != nil {
+			return result, err
+		}
+	}
+
+	removedKeys := make([]string, 0, len(removals))
+	for key := range removals {
+		removedKeys = append(removedKeys, key)
+	}
+	sort.Strings(removedKeys)
+	for _, key := range removedKeys {
+		if err := bucket.Delete([]byte(key)); err != nil {
+			return result, err
+		}
+	}
+
+	// oldest first, so later rewrites can be stored against earlier ones
+	for i, entry := range rewrites {
+		encoded, err := encodeSnapshot(bucket, entry.time, rebuilt[i])
+		if err != nil {
+			return result, err
+		}
+		if err := bucket.Put(entry.key, encoded); err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+func logPruneResult(log func(v ...interface{}), result PruneResult) {
+	if result.Removed > 0 {
+		log("game ", result.GameNumber, " player ", result.PlayerID, ": removing ", result.Removed, " of ", result.Total, " snapshots, rewriting ", result.Rewritten)
+	}
+}
+
+func (store *boltMatchStore) PruneSnapshots(policy RetentionPolicy, dryRun bool, log func(v ...interface{})) ([]PruneResult, error) {
+	paths, err := store.snapshotBucketPaths()
+	if err != nil {
+		return nil, err
+	}
+
+	results := []PruneResult{}
+	for _, path := range paths {
+		var result PruneResult
+		prune := func(tx *bolt.Tx) error {
+			bucket := tx.Bucket([]byte("gz-snapshots")).Bucket(path.gameNumber).Bucket(path.playerID)
+
+			var err error
+			result, err = pruneBucket(boltSnapshotBucket{bucket}, policy, dryRun)
+			return err
+		}
+
+		// one transaction per player keeps memory use down on big databases
+		if dryRun {
+			err = store.db.View(prune)
+		} else {
+			err = store.db.Update(prune)
+		}
+		if err != nil {
+			return results, err
+		}
+
+		result.GameNumber = string(path.gameNumber)
+		result.PlayerID = string(path.playerID)
+		results = append(results, result)
+
+		logPruneResult(log, result)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,89 @@
+package matchstore
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"go.albinodrought.com/neptunes-pride/internal/fixtures"
+	"go.albinodrought.com/neptunes-pride/internal/types"
+)
+
+func TestPruneSnapshots(t *testing.T) {
+	store := openTestStore(t)
+
+	// two polls per tick, 4 ticks per production, one tick per hour over 2 days
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	saved := []*types.APIResponse{}
+	for i := 0; i < 96; i++ {
+		snapshot := fixtures.Load(t, "../opsec/aburrido.json")
+		snapshot.ScanningData.Now = start.Add(time.Duration(i)*30*time.Minute).UnixNano() / int64(time.Millisecond)
+		snapshot.ScanningData.Tick = i / 2
+		snapshot.ScanningData.Productions = i / 8
+		for fleetIndex, fleet := range snapshot.ScanningData.Fleets {
+			fleet.Strength += i
+			snapshot.ScanningData.Fleets[fleetIndex] = fleet
+		}
+		saved = append(saved, snapshot)
+
+		if err := store.SaveSnapshot("123", snapshot); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the last 12 hours are kept in full, the 12 before that one per tick, the rest one per production
+	policy := RetentionPolicy{
+		KeepAll: 12 * time.Hour,
+		PerTick: 12 * time.Hour,
+		Now:     start.Add(48 * time.Hour),
+	}
+
+	dryRun, err := store.PruneSnapshots(policy, true, func(v ...interface{}) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	times, _ := store.ListSnapshotTimes("123", 4, 1000)
+	if len(times) != len(saved) {
+		t.Errorf("expected a dry run to remove nothing, %v left", len(times))
+	}
+
+	results, err := store.PruneSnapshots(policy, false, func(v ...interface{}) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0] != dryRun[0] {
+		t.Errorf("expected the dry run to match, got %+v and %+v", dryRun, results)
+	}
+
+	// 24 kept in full, 12 ticks, 6 productions
+	expectedKept := 24 + 12 + 6
+	if results[0].Removed != len(saved)-expectedKept {
+		t.Errorf("expected %v removed, got %+v", len(saved)-expectedKept, results[0])
+	}
+
+	times, _ = store.ListSnapshotTimes("123", 4, 1000)
+	if len(times) != expectedKept {
+		t.Errorf("expected %v snapshots left, got %v", expectedKept, len(times))
+	}
+
+	kept := map[int64]bool{}
+	for _, snapshotTime := range times {
+		kept[snapshotTime] = true
+	}
+	for _, snapshot := range saved {
+		if !kept[snapshot.ScanningData.Now] {
+			continue
+		}
+
+		found, err := store.FindSnapshot("123", 4, snapshot.ScanningData.Now)
+		if err != nil {
+			t.Fatalf("failed rebuilding kept snapshot at %v: %v", snapshot.ScanningData.Now, err)
+		}
+
+		expectedJSON, _ := json.Marshal(snapshot)
+		foundJSON, _ := json.Marshal(found)
+		if string(expectedJSON) != string(foundJSON) {
+			t.Errorf("kept snapshot at %v did not rebuild to the same data", snapshot.ScanningData.Now)
+		}
+	}
+}
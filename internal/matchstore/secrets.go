@@ -0,0 +1,225 @@
+package matchstore
+
+import (
+	"errors"
+
+	"go.albinodrought.com/neptunes-pride/internal/matches"
+	"go.albinodrought.com/neptunes-pride/internal/secrets"
+)
+
+var ErrPlaintextSecrets = errors.New("match secrets are stored in plain text, run `np-scanner rotate-secret-key` to encrypt them")
+
+// secretsMatchStore seals API keys and Discord user IDs before matches are stored,
+// and opens them again when matches are read. Everything else passes through.
+type secretsMatchStore struct {
+	MatchStore
+	keyring *secrets.Keyring
+}
+
+// WithSecrets encrypts match secrets at rest with the keyring.
+// A nil keyring stores new secrets in plain text, but still refuses to return sealed ones.
+func WithSecrets(store MatchStore, keyring *secrets.Keyring) MatchStore {
+	return &secretsMatchStore{store, keyring}
+}
+
+// mapSecrets returns a copy of the match with every secret passed through fn
+func mapSecrets(match *matches.Match, fn func(value string) (string, error)) (*matches.Match, error) {
+	mapped := *match
+
+	if match.PlayerCreds != nil {
+		mapped.PlayerCreds = make(map[int]matches.PlayerCreds, len(match.PlayerCreds))
+		for playerUID, creds := range match.PlayerCreds {
+			if creds.APIKey != "" {
+				apiKey, err := fn(creds.APIKey)
+				if err != nil {
+					return nil, err
+				}
+				creds.APIKey = apiKey
+			}
+			mapped.PlayerCreds[playerUID] = creds
+		}
+	}
+
+	if match.DiscordUserIDs != nil {
+		mapped.DiscordUserIDs = make(map[int]string, len(match.DiscordUserIDs))
+		for playerUID, discordUserID := range match.DiscordUserIDs {
+			if discordUserID != "" {
+				var err error
+				discordUserID, err = fn(discordUserID)
+				if err != nil {
+					return nil, err
+				}
+			}
+			mapped.DiscordUserIDs[playerUID] = discordUserID
+		}
+	}
+
+	return &mapped, nil
+}
+
+func sealMatch(keyring *secrets.Keyring, match *matches.Match) (*matches.Match, error) {
+	if keyring == nil {
+		return match, nil
+	}
+	return mapSecrets(match, keyring.Seal)
+}
+
+func openMatch(keyring *secrets.Keyring, match *matches.Match) (*matches.Match, error) {
+	return mapSecrets(match, keyring.Open)
+}
+
+// resealMatch makes sure every secret of the match is sealed with the keyring's primary key
+func resealMatch(keyring *secrets.Keyring, match *matches.Match) (*matches.Match, bool, error) {
+	changed := false
+	resealed, err := mapSecrets(match, func(value string) (string, error) {
+		if keyring.SealedWithPrimary(value) {
+			return value, nil
+		}
+		changed = true
+		return keyring.Reseal(value)
+	})
+	return resealed, changed, err
+}
+
+func (store *secretsMatchStore) SaveMatch(match *matches.Match) error {
+	sealed, err := sealMatch(store.keyring, match)
+	if err != nil {
+		return err
+	}
+	return store.MatchStore.SaveMatch(sealed)
+}
+
+func (store *secretsMatchStore) FindMatchOrFail(gameNumber string) (*matches.Match, error) {
+	match, err := store.MatchStore.FindMatchOrFail(gameNumber)
+	if err != nil {
+		return nil, err
+	}
+	return openMatch(store.keyring, match)
+}
+
+func (store *secretsMatchStore) FindOrCreateMatch(gameNumber string) (*matches.Match, error) {
+	foundMatch, err := store.FindMatchOrFail(gameNumber)
+
+	if err == ErrMatchNotFound {
+		foundMatch = matches.NewMatch(gameNumber)
+		err = store.SaveMatch(foundMatch)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return foundMatch, nil
+}
+
+func (store *secretsMatchStore) EachMatch(decode bool, callback func(gameNumber string, match *matches.Match)) error {
+	if !decode {
+		return store.MatchStore.EachMatch(decode, callback)
+	}
+
+	var openErr error
+	err := store.MatchStore.EachMatch(decode, func(gameNumber string, match *matches.Match) {
+		if openErr != nil {
+			return
+		}
+
+		opened, err := openMatch(store.keyring, match)
+		if err != nil {
+			openErr = err
+			return
+		}
+		callback(gameNumber, opened)
+	})
+
+	if err != nil {
+		return err
+	}
+	return openErr
+}
+
+// ResealSecrets seals every match's secrets with the keyring's primary key.
+// Secrets sealed with the keyring's old keys are rotated, plain text ones are encrypted.
+func ResealSecrets(store MatchStore, keyring *secrets.Keyring, log func(v ...interface{})) (int, error) {
+	// work on the stored values, not opened ones
+	if wrapped, ok := store.(*secretsMatchStore); ok {
+		store = wrapped.MatchStore
+	}
+
+	gameNumbers, err := store.Matches()
+	if err != nil {
+		return 0, err
+	}
+
+	resealed := 0
+	for _, gameNumber := range gameNumbers {
+		match, err := store.FindMatchOrFail(gameNumber)
+		if err != nil {
+			return resealed, err
+		}
+
+		resealedMatch, changed, err := resealMatch(keyring, match)
+		if err != nil {
+			return resealed, err
+		}
+		if !changed {
+			continue
+		}
+
+		if err := store.SaveMatch(resealedMatch); err != nil {
+			return resealed, err
+		}
+		resealed++
+		log("resealed secrets for game ", gameNumber)
+	}
+
+	return resealed, nil
+}
+
+// CheckSecretsSealed returns ErrPlaintextSecrets if any match has secrets in plain text,
+// like ones stored before a secret key was configured
+func CheckSecretsSealed(store MatchStore) error {
+	if wrapped, ok := store.(*secretsMatchStore); ok {
+		store = wrapped.MatchStore
+	}
+
+	plaintext := false
+	err := store.EachMatch(true, func(gameNumber string, match *matches.Match) {
+		mapSecrets(match, func(value string) (string, error) {
+			plaintext = plaintext || !secrets.IsSealed(value)
+			return value, nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	if plaintext {
+		return ErrPlaintextSecrets
+	}
+	return nil
+}
+
+// CompactSecrets rewrites the store's files so secrets replaced by ResealSecrets
+// don't linger in free space. Stores without files are left as they are.
+func CompactSecrets(store MatchStore) error {
+	if wrapped, ok := store.(*secretsMatchStore); ok {
+		store = wrapped.MatchStore
+	}
+
+	switch store := store.(type) {
+	case *boltMatchStore:
+		db, err := compact(store.db)
+		if db != nil {
+			store.db = db
+		}
+		return err
+	case *sqliteMatchStore:
+		if _, err := store.writer.Exec("VACUUM"); err != nil {
+			return err
+		}
+		_, err := store.writer.Exec("PRAGMA wal_checkpoint(TRUNCATE)")
+		return err
+	}
+
+	return nil
+}
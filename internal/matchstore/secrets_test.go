@@ -0,0 +1,132 @@
+package matchstore
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"go.albinodrought.com/neptunes-pride/internal/matches"
+	"go.albinodrought.com/neptunes-pride/internal/secrets"
+)
+
+func TestSecrets(t *testing.T) {
+	newKey := func() []byte {
+		encoded, _ := secrets.GenerateKey()
+		key, err := secrets.ParseKey(encoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return key
+	}
+
+	oldKey := newKey()
+	oldKeyring, _ := secrets.NewKeyring(oldKey)
+
+	raw := OpenMemory()
+	store := WithSecrets(raw, oldKeyring)
+
+	match := matches.NewMatch("123")
+	match.PlayerCreds[4] = matches.PlayerCreds{PlayerUID: 4, PlayerAlias: "Aburrido", APIKey: "abc"}
+	match.DiscordUserIDs = map[int]string{4: "1234567890"}
+	if err := store.SaveMatch(match); err != nil {
+		t.Fatal(err)
+	}
+	if match.PlayerCreds[4].APIKey != "abc" {
+		t.Error("expected saving to leave the caller's match alone")
+	}
+
+	stored, _ := raw.FindMatchOrFail("123")
+	if !secrets.IsSealed(stored.PlayerCreds[4].APIKey) || !secrets.IsSealed(stored.DiscordUserIDs[4]) {
+		t.Errorf("expected secrets to be sealed at rest, got %+v", stored)
+	}
+	if stored.PlayerCreds[4].PlayerAlias != "Aburrido" {
+		t.Error("expected everything else to be stored as is")
+	}
+
+	checkOpened := func(store MatchStore) {
+		found, err := store.FindMatchOrFail("123")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found.PlayerCreds[4].APIKey != "abc" || found.DiscordUserIDs[4] != "1234567890" {
+			t.Errorf("expected secrets to be opened, got %+v", found)
+		}
+
+		err = store.EachMatch(true, func(gameNumber string, match *matches.Match) {
+			if match.PlayerCreds[4].APIKey != "abc" {
+				t.Errorf("expected secrets to be opened while iterating, got %+v", match)
+			}
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkOpened(store)
+
+	if _, err := WithSecrets(raw, nil).FindMatchOrFail("123"); err != secrets.ErrNoKey {
+		t.Errorf("expected sealed secrets to need a key, got %v", err)
+	}
+
+	// rotate to a new key, keeping the old one around to open existing secrets
+	newKeyring, _ := secrets.NewKeyring(newKey(), oldKey)
+	resealed, err := ResealSecrets(WithSecrets(raw, newKeyring), newKeyring, func(v ...interface{}) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resealed != 1 {
+		t.Errorf("expected one match to be resealed, got %v", resealed)
+	}
+	if resealed, _ := ResealSecrets(raw, newKeyring, func(v ...interface{}) {}); resealed != 0 {
+		t.Errorf("expected nothing left to reseal, got %v", resealed)
+	}
+
+	stored, _ = raw.FindMatchOrFail("123")
+	if !newKeyring.SealedWithPrimary(stored.PlayerCreds[4].APIKey) {
+		t.Error("expected the API key to be sealed with the new key")
+	}
+
+	rotatedKeyring, _ := secrets.NewKeyring(newKey())
+	if _, err := WithSecrets(raw, rotatedKeyring).FindMatchOrFail("123"); err != secrets.ErrUnknownKey {
+		t.Errorf("expected an unrelated key to fail, got %v", err)
+	}
+	checkOpened(WithSecrets(raw, newKeyring))
+}
+
+func TestCheckSecretsSealed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	raw := openTestBackend(t, "bolt", path)
+
+	// stored before a secret key was configured
+	match := matches.NewMatch("123")
+	match.PlayerCreds[4] = matches.PlayerCreds{PlayerUID: 4, APIKey: "plaintext-api-key"}
+	if err := raw.SaveMatch(match); err != nil {
+		t.Fatal(err)
+	}
+
+	encoded, _ := secrets.GenerateKey()
+	key, _ := secrets.ParseKey(encoded)
+	keyring, _ := secrets.NewKeyring(key)
+	store := WithSecrets(raw, keyring)
+
+	if err := CheckSecretsSealed(store); err != ErrPlaintextSecrets {
+		t.Fatalf("expected plain text secrets to be refused, got %v", err)
+	}
+
+	if _, err := ResealSecrets(store, keyring, func(v ...interface{}) {}); err != nil {
+		t.Fatal(err)
+	}
+	if err := CompactSecrets(store); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := CheckSecretsSealed(store); err != nil {
+		t.Errorf("expected resealed secrets to pass, got %v", err)
+	}
+	if data, _ := ioutil.ReadFile(path); bytes.Contains(data, []byte("plaintext-api-key")) {
+		t.Error("expected the plain text API key to be compacted away")
+	}
+	if opened, err := store.FindMatchOrFail("123"); err != nil || opened.PlayerCreds[4].APIKey != "plaintext-api-key" {
+		t.Errorf("expected the compacted store to stay usable, got %+v (%v)", opened, err)
+	}
+}
@@ -0,0 +1,838 @@
+package matchstore
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"go.albinodrought.com/neptunes-pride/internal/matches"
+	"go.albinodrought.com/neptunes-pride/internal/stats"
+	"go.albinodrought.com/neptunes-pride/internal/types"
+
+	// pure Go, so builds without CGO keep working
+	_ "modernc.org/sqlite"
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS matches (
+	game_number TEXT PRIMARY KEY,
+	data BLOB NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS snapshots (
+	game_number TEXT NOT NULL,
+	player_uid INTEGER NOT NULL,
+	time INTEGER NOT NULL,
+	data BLOB NOT NULL,
+	PRIMARY KEY (game_number, player_uid, time)
+) WITHOUT ROWID;
+
+CREATE TABLE IF NOT EXISTS reports (
+	game_number TEXT PRIMARY KEY,
+	data BLOB NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS stats (
+	game_number TEXT NOT NULL,
+	player_uid INTEGER NOT NULL,
+	tick INTEGER NOT NULL,
+	data BLOB NOT NULL,
+	PRIMARY KEY (game_number, player_uid, tick)
+) WITHOUT ROWID;
+
+CREATE TABLE IF NOT EXISTS galaxies (
+	game_number TEXT PRIMARY KEY,
+	data BLOB NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS aliases (
+	alias TEXT NOT NULL,
+	game_number TEXT NOT NULL,
+	player_uid INTEGER NOT NULL,
+	PRIMARY KEY (alias, game_number)
+) WITHOUT ROWID;
+`
+
+// sqliteMatchStore keeps the same encodings as the bolt store, in tables instead of buckets.
+// Unlike bolt, other processes can use the database while it is open.
+type sqliteMatchStore struct {
+	// readers run concurrently thanks to the write-ahead log
+	readers *sql.DB
+	// writer is a single connection that takes the write lock as soon as a transaction starts,
+	// so transactions never fail half way through upgrading a read lock
+	writer *sql.DB
+}
+
+func OpenSQLite(path string) (MatchStore, error) {
+	pragmas := "?_pragma=busy_timeout(10000)&_pragma=journal_mode(wal)"
+
+	writer, err := sql.Open("sqlite", path+pragmas+"&_txlock=immediate")
+	if err != nil {
+		return nil, err
+	}
+	writer.SetMaxOpenConns(1)
+
+	if _, err := writer.Exec(sqliteSchema); err != nil {
+		writer.Close()
+		return nil, err
+	}
+
+	readers, err := sql.Open("sqlite", path+pragmas)
+	if err != nil {
+		writer.Close()
+		return nil, err
+	}
+
+	return &sqliteMatchStore{readers: readers, writer: writer}, nil
+}
+
+func (store *sqliteMatchStore) Close() error {
+	readersErr := store.readers.Close()
+	if err := store.writer.Close(); err != nil {
+		return err
+	}
+	return readersErr
+}
+
+func (store *sqliteMatchStore) view(fn func(tx *sql.Tx) error) error {
+	tx, err := store.readers.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	return fn(tx)
+}
+
+func (store *sqliteMatchStore) update(fn func(tx *sql.Tx) error) error {
+	tx, err := store.writer.Begin()
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// sqliteSnapshotBucket adapts a player's rows in the snapshots table to the bucket the encodings use.
+// Bucket methods can't return errors, so the first one is kept in err for the caller to check.
+type sqliteSnapshotBucket struct {
+	tx         *sql.Tx
+	gameNumber string
+	playerUID  int
+	err        error
+}
+
+func (bucket *sqliteSnapshotBucket) fail(err error) {
+	if bucket.err == nil {
+		bucket.err = err
+	}
+}
+
+func (bucket *sqliteSnapshotBucket) Get(key []byte) []byte {
+	time, err := strconv.ParseInt(string(key), 10, 64)
+	if err != nil {
+		return nil
+	}
+
+	var value []byte
+	err = bucket.tx.QueryRow(
+		"SELECT data FROM snapshots WHERE game_number = ? AND player_uid = ? AND time = ?",
+		bucket.gameNumber, bucket.playerUID, time,
+	).Scan(&value)
+	if err != nil && err != sql.ErrNoRows {
+		bucket.fail(err)
+	}
+
+	return value
+}
+
+func (bucket *sqliteSnapshotBucket) Put(key []byte, value []byte) error {
+	time, err := strconv.ParseInt(string(key), 10, 64)
+	if err != nil {
+		return err
+	}
+
+	_, err = bucket.tx.Exec(
+		"INSERT INTO snapshots (game_number, player_uid, time, data) VALUES (?, ?, ?, ?) "+
+			"ON CONFLICT (game_number, player_uid, time) DO UPDATE SET data = excluded.data",
+		bucket.gameNumber, bucket.playerUID, time, value,
+	)
+	return err
+}
+
+func (bucket *sqliteSnapshotBucket) Delete(key []byte) error {
+	time, err := strconv.ParseInt(string(key), 10, 64)
+	if err != nil {
+		return err
+	}
+
+	_, err = bucket.tx.Exec(
+		"DELETE FROM snapshots WHERE game_number = ? AND player_uid = ? AND time = ?",
+		bucket.gameNumber, bucket.playerUID, time,
+	)
+	return err
+}
+
+func (bucket *sqliteSnapshotBucket) ForEach(fn func(k, v []byte) error) error {
+	rows, err := bucket.tx.Query(
+		"SELECT time, data FROM snapshots WHERE game_number = ? AND player_uid = ? ORDER BY time",
+		bucket.gameNumber, bucket.playerUID,
+	)
+	if err != nil {
+		return err
+	}
+
+	// read everything first, fn may query the same transaction
+	times := []int64{}
+	values := [][]byte{}
+	for rows.Next() {
+		var time int64
+		var value []byte
+		if err := rows.Scan(&time, &value); err != nil {
+			rows.Close()
+			return err
+		}
+		times = append(times, time)
+		values = append(values, value)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i, time := range times {
+		if err := fn(snapshotKey(time), values[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (bucket *sqliteSnapshotBucket) Cursor() snapshotCursor {
+	return &sqliteSnapshotCursor{bucket: bucket}
+}
+
+type sqliteSnapshotCursor struct {
+	bucket *sqliteSnapshotBucket
+	time   int64
+	valid  bool
+}
+
+func (c *sqliteSnapshotCursor) row(condition string, args ...interface{}) ([]byte, []byte) {
+	var time int64
+	var value []byte
+
+	args = append([]interface{}{c.bucket.gameNumber, c.bucket.playerUID}, args...)
+	err := c.bucket.tx.QueryRow(
+		"SELECT time, data FROM snapshots WHERE game_number = ? AND player_uid = ? "+condition,
+		args...,
+	).Scan(&time, &value)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			c.bucket.fail(err)
+		}
+		c.valid = false
+		return nil, nil
+	}
+
+	c.time, c.valid = time, true
+	return snapshotKey(time), value
+}
+
+func (c *sqliteSnapshotCursor) Seek(seek []byte) ([]byte, []byte) {
+	time, err := strconv.ParseInt(string(seek), 10, 64)
+	if err != nil {
+		c.valid = false
+		return nil, nil
+	}
+	return c.row("AND time >= ? ORDER BY time LIMIT 1", time)
+}
+
+func (c *sqliteSnapshotCursor) Last() ([]byte, []byte) {
+	return c.row("ORDER BY time DESC LIMIT 1")
+}
+
+func (c *sqliteSnapshotCursor) Prev() ([]byte, []byte) {
+	if !c.valid {
+		return nil, nil
+	}
+	return c.row("AND time < ? ORDER BY time DESC LIMIT 1", c.time)
+}
+
+// sqliteGalaxies adapts the galaxies table to the bucket the static data helpers use
+type sqliteGalaxies struct {
+	tx  *sql.Tx
+	err error
+}
+
+func (galaxies *sqliteGalaxies) Get(key []byte) []byte {
+	var value []byte
+	err := galaxies.tx.QueryRow("SELECT data FROM galaxies WHERE game_number = ?", string(key)).Scan(&value)
+	if err != nil && err != sql.ErrNoRows && galaxies.err == nil {
+		galaxies.err = err
+	}
+	return value
+}
+
+func (galaxies *sqliteGalaxies) Put(key []byte, value []byte) error {
+	_, err := galaxies.tx.Exec(
+		"INSERT INTO galaxies (game_number, data) VALUES (?, ?) "+
+			"ON CONFLICT (game_number) DO UPDATE SET data = excluded.data",
+		string(key), value,
+	)
+	return err
+}
+
+// sqliteMissing tells apart games without any snapshots from players without snapshots, like bolt's buckets do
+func sqliteMissing(tx *sql.Tx, gameNumber string) error {
+	var count int
+	err := tx.QueryRow("SELECT COUNT(*) FROM (SELECT 1 FROM snapshots WHERE game_number = ? LIMIT 1)", gameNumber).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrMatchNotFound
+	}
+	return ErrSnapshotNotFound
+}
+
+func (store *sqliteMatchStore) EachMatch(decode bool, callback func(gameNumber string, match *matches.Match)) error {
+	gameNumbers := []string{}
+	serializedMatches := [][]byte{}
+
+	err := store.view(func(tx *sql.Tx) error {
+		rows, err := tx.Query("SELECT game_number, data FROM matches ORDER BY game_number")
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var gameNumber string
+			var serialized []byte
+			if err := rows.Scan(&gameNumber, &serialized); err != nil {
+				return err
+			}
+			gameNumbers = append(gameNumbers, gameNumber)
+			serializedMatches = append(serializedMatches, serialized)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return err
+	}
+
+	for i, gameNumber := range gameNumbers {
+		match := &matches.Match{}
+		if decode {
+			if err := json.Unmarshal(serializedMatches[i], match); err != nil {
+				return err
+			}
+		}
+		callback(gameNumber, match)
+	}
+
+	return nil
+}
+
+func (store *sqliteMatchStore) Matches() ([]string, error) {
+	gameNumbers := []string{}
+
+	err := store.EachMatch(false, func(gameNumber string, match *matches.Match) {
+		gameNumbers = append(gameNumbers, gameNumber)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return gameNumbers, nil
+}
+
+func (store *sqliteMatchStore) SaveMatch(match *matches.Match) error {
+	serialized, err := json.Marshal(match)
+	if err != nil {
+		return err
+	}
+
+	return store.update(func(tx *sql.Tx) error {
+		_, err := tx.Exec(
+			"INSERT INTO matches (game_number, data) VALUES (?, ?) "+
+				"ON CONFLICT (game_number) DO UPDATE SET data = excluded.data",
+			match.GameNumber, serialized,
+		)
+		return err
+	})
+}
+
+func (store *sqliteMatchStore) FindMatchOrFail(gameNumber string) (*matches.Match, error) {
+	var foundMatchSerialized []byte
+
+	err := store.view(func(tx *sql.Tx) error {
+		return tx.QueryRow("SELECT data FROM matches WHERE game_number = ?", gameNumber).Scan(&foundMatchSerialized)
+	})
+
+	if err == sql.ErrNoRows {
+		return nil, ErrMatchNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	foundMatch := &matches.Match{}
+	err = json.Unmarshal(foundMatchSerialized, foundMatch)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return foundMatch, nil
+}
+
+func (store *sqliteMatchStore) FindOrCreateMatch(gameNumber string) (*matches.Match, error) {
+	foundMatch, err := store.FindMatchOrFail(gameNumber)
+
+	if err == ErrMatchNotFound {
+		foundMatch = matches.NewMatch(gameNumber)
+		err = store.SaveMatch(foundMatch)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return foundMatch, nil
+}
+
+func (store *sqliteMatchStore) ListSnapshotPlayers(gameNumber string) ([]int, error) {
+	playerIDs := []int{}
+
+	err := store.view(func(tx *sql.Tx) error {
+		rows, err := tx.Query("SELECT DISTINCT player_uid FROM snapshots WHERE game_number = ? ORDER BY player_uid", gameNumber)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var playerID int
+			if err := rows.Scan(&playerID); err != nil {
+				return err
+			}
+			playerIDs = append(playerIDs, playerID)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		if len(playerIDs) == 0 {
+			return ErrMatchNotFound
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return playerIDs, nil
+}
+
+func (store *sqliteMatchStore) ListSnapshotTimes(gameNumber string, playerID int, limit int) ([]int64, error) {
+	snapshotTimes := []int64{}
+
+	err := store.view(func(tx *sql.Tx) error {
+		rows, err := tx.Query(
+			"SELECT time FROM snapshots WHERE game_number = ? AND player_uid = ? ORDER BY time DESC LIMIT ?",
+			gameNumber, playerID, limit,
+		)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var snapshotTime int64
+			if err := rows.Scan(&snapshotTime); err != nil {
+				return err
+			}
+			snapshotTimes = append(snapshotTimes, snapshotTime)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		if len(snapshotTimes) == 0 && limit > 0 {
+			return sqliteMissing(tx, gameNumber)
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return snapshotTimes, nil
+}
+
+func (store *sqliteMatchStore) FindSnapshot(gameNumber string, playerID int, time int64) (*types.APIResponse, error) {
+	var foundSnapshotSerialized []byte
+
+	err := store.view(func(tx *sql.Tx) error {
+		bucket := &sqliteSnapshotBucket{tx: tx, gameNumber: gameNumber, playerUID: playerID}
+		galaxies := &sqliteGalaxies{tx: tx}
+
+		stripped, err := readSnapshotJSON(bucket, time)
+		if bucket.err != nil {
+			return bucket.err
+		}
+		if err == ErrSnapshotNotFound {
+			return sqliteMissing(tx, gameNumber)
+		}
+		if err != nil {
+			return err
+		}
+
+		foundSnapshotSerialized, err = fillStatic(galaxies, gameNumber, stripped)
+		if galaxies.err != nil {
+			return galaxies.err
+		}
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	foundSnapshot := &types.APIResponse{}
+	err = json.Unmarshal(foundSnapshotSerialized, foundSnapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	return foundSnapshot, nil
+}
+
+func (store *sqliteMatchStore) SaveSnapshot(gameNumber string, snapshot *types.APIResponse) error {
+	serialized, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	return store.update(func(tx *sql.Tx) error {
+		snapshots := &sqliteSnapshotBucket{tx: tx, gameNumber: gameNumber, playerUID: snapshot.ScanningData.PlayerUID}
+		galaxies := &sqliteGalaxies{tx: tx}
+
+		stripped, err := stripStatic(galaxies, gameNumber, serialized)
+		if galaxies.err != nil {
+			return galaxies.err
+		}
+		if err != nil {
+			return err
+		}
+
+		// nothing happened since the last poll, just extend the previous snapshot
+		encodedSnapshot, err := encodeDuplicate(snapshots, snapshot.ScanningData.Now, stripped)
+		if err != nil {
+			return err
+		}
+
+		if encodedSnapshot == nil {
+			encodedSnapshot, err = encodeSnapshot(snapshots, snapshot.ScanningData.Now, stripped)
+			if err != nil {
+				return err
+			}
+		}
+
+		if snapshots.err != nil {
+			return snapshots.err
+		}
+
+		err = snapshots.Put(snapshotKey(snapshot.ScanningData.Now), encodedSnapshot)
+		if err != nil {
+			return err
+		}
+
+		if err := saveSQLiteStats(tx, gameNumber, stats.Extract(snapshot)); err != nil {
+			return err
+		}
+		return saveSQLiteAliases(tx, gameNumber, snapshotAliases(snapshot))
+	})
+}
+
+func saveSQLiteAliases(tx *sql.Tx, gameNumber string, aliases map[int]string) error {
+	for playerUID, alias := range aliases {
+		_, err := tx.Exec(
+			"INSERT INTO aliases (alias, game_number, player_uid) VALUES (?, ?, ?) "+
+				"ON CONFLICT (alias, game_number) DO UPDATE SET player_uid = excluded.player_uid",
+			aliasKey(alias), gameNumber, playerUID,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (store *sqliteMatchStore) SaveAliases(gameNumber string, aliases map[int]string) error {
+	return store.update(func(tx *sql.Tx) error {
+		return saveSQLiteAliases(tx, gameNumber, aliases)
+	})
+}
+
+func (store *sqliteMatchStore) ListAliasGames(alias string) ([]AliasGame, error) {
+	games := []AliasGame{}
+
+	err := store.view(func(tx *sql.Tx) error {
+		rows, err := tx.Query("SELECT game_number, player_uid FROM aliases WHERE alias = ? ORDER BY game_number", aliasKey(alias))
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			game := AliasGame{}
+			if err := rows.Scan(&game.GameNumber, &game.PlayerUID); err != nil {
+				return err
+			}
+			games = append(games, game)
+		}
+		return rows.Err()
+	})
+
+	return games, err
+}
+
+func saveSQLiteStats(tx *sql.Tx, gameNumber string, rows []stats.Row) error {
+	for _, row := range rows {
+		var existingSerialized []byte
+		err := tx.QueryRow(
+			"SELECT data FROM stats WHERE game_number = ? AND player_uid = ? AND tick = ?",
+			gameNumber, row.PlayerUID, row.Tick,
+		).Scan(&existingSerialized)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+
+		if existingSerialized != nil {
+			existing := stats.Row{}
+			if err := json.Unmarshal(existingSerialized, &existing); err != nil {
+				return err
+			}
+			row = stats.Combine(existing, row)
+		}
+
+		serialized, err := json.Marshal(row)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(
+			"INSERT INTO stats (game_number, player_uid, tick, data) VALUES (?, ?, ?, ?) "+
+				"ON CONFLICT (game_number, player_uid, tick) DO UPDATE SET data = excluded.data",
+			gameNumber, row.PlayerUID, row.Tick, serialized,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (store *sqliteMatchStore) SaveStats(gameNumber string, rows []stats.Row) error {
+	return store.update(func(tx *sql.Tx) error {
+		return saveSQLiteStats(tx, gameNumber, rows)
+	})
+}
+
+func (store *sqliteMatchStore) ListStats(gameNumber string) ([]stats.Series, error) {
+	allSeries := []stats.Series{}
+
+	err := store.view(func(tx *sql.Tx) error {
+		rows, err := tx.Query("SELECT player_uid, data FROM stats WHERE game_number = ? ORDER BY player_uid, tick", gameNumber)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var playerUID int
+			var serialized []byte
+			if err := rows.Scan(&playerUID, &serialized); err != nil {
+				return err
+			}
+
+			row := stats.Row{}
+			if err := json.Unmarshal(serialized, &row); err != nil {
+				return err
+			}
+
+			if len(allSeries) == 0 || allSeries[len(allSeries)-1].PlayerUID != playerUID {
+				allSeries = append(allSeries, stats.Series{
+					PlayerUID: playerUID,
+					Rows:      []stats.Row{},
+				})
+			}
+			series := &allSeries[len(allSeries)-1]
+			series.PlayerAlias = row.PlayerAlias
+			series.Rows = append(series.Rows, row)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		if len(allSeries) == 0 {
+			return ErrMatchNotFound
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return allSeries, nil
+}
+
+func (store *sqliteMatchStore) FindReport(gameNumber string) ([]byte, error) {
+	var compressedReport []byte
+
+	err := store.view(func(tx *sql.Tx) error {
+		return tx.QueryRow("SELECT data FROM reports WHERE game_number = ?", gameNumber).Scan(&compressedReport)
+	})
+
+	if err == sql.ErrNoRows {
+		return nil, ErrReportNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return gunzipBytes(compressedReport)
+}
+
+func (store *sqliteMatchStore) SaveReport(gameNumber string, report []byte) error {
+	compressedReport, err := gzipBytes(report)
+	if err != nil {
+		return err
+	}
+
+	return store.update(func(tx *sql.Tx) error {
+		_, err := tx.Exec(
+			"INSERT INTO reports (game_number, data) VALUES (?, ?) "+
+				"ON CONFLICT (game_number) DO UPDATE SET data = excluded.data",
+			gameNumber, compressedReport,
+		)
+		return err
+	})
+}
+
+// CompressSnapshots has nothing to do, sqlite stores were never written uncompressed
+func (store *sqliteMatchStore) CompressSnapshots(log func(v ...interface{})) error {
+	return errors.New("no uncompressed snapshots in sqlite stores - already compressed")
+}
+
+type sqliteSnapshotPath struct {
+	gameNumber string
+	playerUID  int
+}
+
+func (store *sqliteMatchStore) snapshotPaths() ([]sqliteSnapshotPath, error) {
+	paths := []sqliteSnapshotPath{}
+
+	err := store.view(func(tx *sql.Tx) error {
+		rows, err := tx.Query("SELECT DISTINCT game_number, player_uid FROM snapshots ORDER BY game_number, player_uid")
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			path := sqliteSnapshotPath{}
+			if err := rows.Scan(&path.gameNumber, &path.playerUID); err != nil {
+				return err
+			}
+			paths = append(paths, path)
+		}
+		return rows.Err()
+	})
+
+	return paths, err
+}
+
+func (store *sqliteMatchStore) DeltaEncodeSnapshots(log func(v ...interface{})) error {
+	paths, err := store.snapshotPaths()
+	if err != nil {
+		return err
+	}
+
+	// one transaction per player keeps other writers waiting for less time
+	for _, path := range paths {
+		err := store.update(func(tx *sql.Tx) error {
+			bucket := &sqliteSnapshotBucket{tx: tx, gameNumber: path.gameNumber, playerUID: path.playerUID}
+
+			converted, err := deltaEncodeBucket(bucket)
+			if bucket.err != nil {
+				return bucket.err
+			}
+			if err != nil {
+				return err
+			}
+
+			log("delta encoded ", converted, " snapshots for game ", path.gameNumber, " player ", path.playerUID)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (store *sqliteMatchStore) PruneSnapshots(policy RetentionPolicy, dryRun bool, log func(v ...interface{})) ([]PruneResult, error) {
+	paths, err := store.snapshotPaths()
+	if err != nil {
+		return nil, err
+	}
+
+	results := []PruneResult{}
+	for _, path := range paths {
+		var result PruneResult
+		prune := func(tx *sql.Tx) error {
+			bucket := &sqliteSnapshotBucket{tx: tx, gameNumber: path.gameNumber, playerUID: path.playerUID}
+
+			var err error
+			result, err = pruneBucket(bucket, policy, dryRun)
+			if bucket.err != nil {
+				return bucket.err
+			}
+			return err
+		}
+
+		if dryRun {
+			err = store.view(prune)
+		} else {
+			err = store.update(prune)
+		}
+		if err != nil {
+			return results, err
+		}
+
+		result.GameNumber = path.gameNumber
+		result.PlayerID = strconv.Itoa(path.playerUID)
+		results = append(results, result)
+
+		logPruneResult(log, result)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,117 @@
+package matchstore
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"go.albinodrought.com/neptunes-pride/internal/fixtures"
+)
+
+func TestSQLiteCopy(t *testing.T) {
+	boltStore := openTestStore(t)
+	sqlitePath := filepath.Join(t.TempDir(), "test.sqlite")
+
+	match, err := boltStore.FindOrCreateMatch("123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	match.Name = "Burrito Galaxy"
+	if err := boltStore.SaveMatch(match); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 6; i++ {
+		snapshot := fixtures.Load(t, "../opsec/aburrido.json")
+		snapshot.ScanningData.Now += int64(i) * 60 * 1000
+		// every other poll sees nothing happen
+		snapshot.ScanningData.Tick += i / 2
+		if err := boltStore.SaveSnapshot("123", snapshot); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := boltStore.SaveReport("123", []byte("<html></html>")); err != nil {
+		t.Fatal(err)
+	}
+
+	sqliteStore := openTestBackend(t, "sqlite", sqlitePath)
+
+	if err := Copy(boltStore, sqliteStore, t.Log); err != nil {
+		t.Fatal(err)
+	}
+
+	// a second process can open the database while it is in use
+	otherStore := openTestBackend(t, "sqlite", sqlitePath)
+
+	foundMatch, err := otherStore.FindMatchOrFail("123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if foundMatch.Name != "Burrito Galaxy" {
+		t.Errorf("expected the match to be copied, got %v", foundMatch)
+	}
+
+	players, err := otherStore.ListSnapshotPlayers("123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(players, []int{4}) {
+		t.Errorf("expected snapshots of player 4, got %v", players)
+	}
+
+	expectedTimes, _ := boltStore.ListSnapshotTimes("123", 4, 10)
+	times, err := otherStore.ListSnapshotTimes("123", 4, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(times, expectedTimes) {
+		t.Errorf("expected times %v, got %v", expectedTimes, times)
+	}
+
+	for _, time := range expectedTimes {
+		expected, _ := boltStore.FindSnapshot("123", 4, time)
+		found, err := otherStore.FindSnapshot("123", 4, time)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expectedJSON, _ := json.Marshal(expected)
+		foundJSON, _ := json.Marshal(found)
+		if string(expectedJSON) != string(foundJSON) {
+			t.Errorf("snapshot at %v did not copy to the same data", time)
+		}
+	}
+
+	if _, err := otherStore.FindSnapshot("123", 5, expectedTimes[0]); err != ErrSnapshotNotFound {
+		t.Errorf("expected a missing player to have no snapshot, got %v", err)
+	}
+	if _, err := otherStore.FindSnapshot("456", 4, expectedTimes[0]); err != ErrMatchNotFound {
+		t.Errorf("expected a missing game to not be found, got %v", err)
+	}
+
+	expectedStats, _ := boltStore.ListStats("123")
+	foundStats, err := otherStore.ListStats("123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(foundStats, expectedStats) {
+		t.Errorf("expected stats %v, got %v", expectedStats, foundStats)
+	}
+
+	aliasGames, err := otherStore.ListAliasGames("expansive brain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(aliasGames, []AliasGame{{GameNumber: "123", PlayerUID: 4}}) {
+		t.Errorf("expected the alias to be indexed, got %v", aliasGames)
+	}
+
+	report, err := otherStore.FindReport("123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(report) != "<html></html>" {
+		t.Errorf("expected the report to be copied, got %v", string(report))
+	}
+}
@@ -0,0 +1,216 @@
+package matchstore
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// staticSettings are scanning_data keys that don't change over a game's lifetime
+var staticSettings = []string{
+	"name",
+	"fleet_speed",
+	"tick_rate",
+	"production_rate",
+	"stars_for_victory",
+	"total_stars",
+	"trade_cost",
+	"trade_scanned",
+	"turn_based",
+}
+
+// staticStarFields are star keys that don't change over a game's lifetime
+var staticStarFields = []string{"n", "x", "y"}
+
+// galaxy is a game's static data, stored once per match instead of in every snapshot.
+// Values are only ever added, so snapshots stripped against an older galaxy still rebuild.
+type galaxy struct {
+	Settings map[string]interface{}            `json:"settings"`
+	Stars    map[string]map[string]interface{} `json:"stars"`
+}
+
+func newGalaxy() *galaxy {
+	return &galaxy{
+		Settings: map[string]interface{}{},
+		Stars:    map[string]map[string]interface{}{},
+	}
+}
+
+func sameJSON(a interface{}, b interface{}) bool {
+	aSerialized, aErr := json.Marshal(a)
+	bSerialized, bErr := json.Marshal(b)
+	return aErr == nil && bErr == nil && bytes.Equal(aSerialized, bSerialized)
+}
+
+func loadGalaxy(galaxies valueBucket, gameNumber string) (*galaxy, error) {
+	serialized := galaxies.Get([]byte(gameNumber))
+	if serialized == nil {
+		return newGalaxy(), nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(serialized))
+	decoder.UseNumber()
+
+	loaded := newGalaxy()
+	if err := decoder.Decode(loaded); err != nil {
+		return nil, err
+	}
+	return loaded, nil
+}
+
+func saveGalaxy(galaxies valueBucket, gameNumber string, g *galaxy) error {
+	serialized, err := json.Marshal(g)
+	if err != nil {
+		return err
+	}
+	return galaxies.Put([]byte(gameNumber), serialized)
+}
+
+// scanningData digs the scanning_data object out of a decoded snapshot
+func scanningData(decoded interface{}) (map[string]interface{}, bool) {
+	root, ok := decoded.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	data, ok := root["scanning_data"].(map[string]interface{})
+	return data, ok
+}
+
+func starsOf(data map[string]interface{}) map[string]interface{} {
+	stars, _ := data["stars"].(map[string]interface{})
+	return stars
+}
+
+// record adds any static data the galaxy doesn't know yet, returns true if anything was added
+func (g *galaxy) record(decoded interface{}) bool {
+	data, ok := scanningData(decoded)
+	if !ok {
+		return false
+	}
+
+	changed := false
+	for _, key := range staticSettings {
+		value, ok := data[key]
+		if _, known := g.Settings[key]; ok && !known {
+			g.Settings[key] = value
+			changed = true
+		}
+	}
+
+	for starIndex, rawStar := range starsOf(data) {
+		star, ok := rawStar.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		staticStar, known := g.Stars[starIndex]
+		if !known {
+			staticStar = map[string]interface{}{}
+		}
+		for _, key := range staticStarFields {
+			value, ok := star[key]
+			if _, knownField := staticStar[key]; ok && !knownField {
+				staticStar[key] = value
+				changed = true
+			}
+		}
+		g.Stars[starIndex] = staticStar
+	}
+
+	return changed
+}
+
+// strip removes values that match the galaxy, anything that differs stays in the snapshot
+func (g *galaxy) strip(decoded interface{}) {
+	data, ok := scanningData(decoded)
+	if !ok {
+		return
+	}
+
+	for key, value := range g.Settings {
+		if current, ok := data[key]; ok && sameJSON(current, value) {
+			delete(data, key)
+		}
+	}
+
+	for starIndex, rawStar := range starsOf(data) {
+		star, ok := rawStar.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for key, value := range g.Stars[starIndex] {
+			if current, ok := star[key]; ok && sameJSON(current, value) {
+				delete(star, key)
+			}
+		}
+	}
+}
+
+// fill puts stripped values back, values still in the snapshot win
+func (g *galaxy) fill(decoded interface{}) {
+	data, ok := scanningData(decoded)
+	if !ok {
+		return
+	}
+
+	for key, value := range g.Settings {
+		if _, ok := data[key]; !ok {
+			data[key] = value
+		}
+	}
+
+	for starIndex, rawStar := range starsOf(data) {
+		star, ok := rawStar.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for key, value := range g.Stars[starIndex] {
+			if _, ok := star[key]; !ok {
+				star[key] = value
+			}
+		}
+	}
+}
+
+// stripStatic records a snapshot's static data in the galaxy and returns the snapshot without it.
+// Must be called inside a writable transaction.
+func stripStatic(galaxies valueBucket, gameNumber string, serialized []byte) ([]byte, error) {
+	g, err := loadGalaxy(galaxies, gameNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	decoded, err := decodeJSON(serialized)
+	if err != nil {
+		return nil, err
+	}
+
+	if g.record(decoded) {
+		if err := saveGalaxy(galaxies, gameNumber, g); err != nil {
+			return nil, err
+		}
+	}
+
+	g.strip(decoded)
+	return json.Marshal(decoded)
+}
+
+// fillStatic puts a game's static data back into a stored snapshot.
+// Snapshots stored before the galaxy existed already have everything and are left as is.
+func fillStatic(galaxies valueBucket, gameNumber string, serialized []byte) ([]byte, error) {
+	if galaxies.Get([]byte(gameNumber)) == nil {
+		return serialized, nil
+	}
+
+	g, err := loadGalaxy(galaxies, gameNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	decoded, err := decodeJSON(serialized)
+	if err != nil {
+		return nil, err
+	}
+
+	g.fill(decoded)
+	return json.Marshal(decoded)
+}
@@ -0,0 +1,57 @@
+package matchstore
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"go.albinodrought.com/neptunes-pride/internal/fixtures"
+	"go.albinodrought.com/neptunes-pride/internal/types"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestStaticGalaxy(t *testing.T) {
+	store := openTestStore(t)
+	boltStore := store.(*boltMatchStore)
+
+	first := fixtures.Load(t, "../opsec/aburrido.json")
+	second := fixtures.Load(t, "../opsec/aburrido.json")
+	second.ScanningData.Now++
+	// settings that do change are kept in the snapshot
+	second.ScanningData.TickRate++
+
+	for _, snapshot := range []*types.APIResponse{first, second} {
+		if err := store.SaveSnapshot("123", snapshot); err != nil {
+			t.Fatal(err)
+		}
+
+		found, err := store.FindSnapshot("123", snapshot.ScanningData.PlayerUID, snapshot.ScanningData.Now)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expectedJSON, _ := json.Marshal(snapshot)
+		foundJSON, _ := json.Marshal(found)
+		if string(expectedJSON) != string(foundJSON) {
+			t.Errorf("snapshot at %v did not rebuild to the same data", snapshot.ScanningData.Now)
+		}
+	}
+
+	someStar := first.ScanningData.Stars["1"]
+	boltStore.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("gz-snapshots")).Bucket([]byte("123")).Bucket([]byte("4"))
+		stored, err := gunzipBytes(bucket.Get(snapshotKey(first.ScanningData.Now)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(stored), someStar.Name) {
+			t.Errorf("expected star names to be stripped from stored snapshots")
+		}
+
+		galaxy := string(tx.Bucket([]byte("galaxies")).Get([]byte("123")))
+		if !strings.Contains(galaxy, someStar.Name) {
+			t.Errorf("expected star names to be stored in the galaxy")
+		}
+		return nil
+	})
+}
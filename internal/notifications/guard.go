@@ -1,6 +1,8 @@
 package notifications
 
 import (
+	"sync"
+
 	bolt "go.etcd.io/bbolt"
 )
 
@@ -47,3 +49,26 @@ func OpenBoltGuard(path string) (Guard, error) {
 
 	return newBoltGuard(db)
 }
+
+// memoryGuard forgets everything it sent when the process exits
+type memoryGuard struct {
+	mutex sync.RWMutex
+	sent  map[string]bool
+}
+
+func (mg *memoryGuard) CheckSent(notifiable Notifiable) (bool, error) {
+	mg.mutex.RLock()
+	defer mg.mutex.RUnlock()
+	return mg.sent[notifiable.ID()], nil
+}
+
+func (mg *memoryGuard) RecordSent(notifiable Notifiable) error {
+	mg.mutex.Lock()
+	defer mg.mutex.Unlock()
+	mg.sent[notifiable.ID()] = true
+	return nil
+}
+
+func NewMemoryGuard() Guard {
+	return &memoryGuard{sent: map[string]bool{}}
+}
@@ -0,0 +1,67 @@
+package notifications
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type testNotifiable string
+
+func (n testNotifiable) ID() string {
+	return string(n)
+}
+
+func (n testNotifiable) Message() string {
+	return "message for " + string(n)
+}
+
+// TestGuardConformance runs every Guard implementation through the same checks
+func TestGuardConformance(t *testing.T) {
+	dir, err := ioutil.TempDir("", "notifications")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	guards := map[string]func() (Guard, error){
+		"bolt": func() (Guard, error) {
+			return OpenBoltGuard(filepath.Join(dir, "test.db"))
+		},
+		"memory": func() (Guard, error) {
+			return NewMemoryGuard(), nil
+		},
+	}
+
+	for name, open := range guards {
+		t.Run(name, func(t *testing.T) {
+			guard, err := open()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			sent, err := guard.CheckSent(testNotifiable("a"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sent {
+				t.Error("expected nothing to be sent yet")
+			}
+
+			for i := 0; i < 2; i++ {
+				// recording twice is harmless
+				if err := guard.RecordSent(testNotifiable("a")); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if sent, _ := guard.CheckSent(testNotifiable("a")); !sent {
+				t.Error("expected a to be sent")
+			}
+			if sent, _ := guard.CheckSent(testNotifiable("b")); sent {
+				t.Error("expected b to not be sent")
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package opsec
+
+import "math"
+
+type BattleResult struct {
+	AttackerWins           bool `json:"attacker_wins"`
+	AttackerShipsRemaining int  `json:"attacker_ships_remaining"`
+	DefenderShipsRemaining int  `json:"defender_ships_remaining"`
+	Rounds                 int  `json:"rounds"`
+	// DefenderShipsNeeded to hold the star, 0 if the defender already wins
+	DefenderShipsNeeded int `json:"defender_ships_needed"`
+}
+
+// GuessBattle estimates the outcome of a carrier attacking a star,
+// same as guessBattle in the UI.
+// Defenders get +1 weapons and win ties.
+func GuessBattle(attackerShips int, attackerWeapons int, defenderShips int, defenderWeapons int) BattleResult {
+	defenderWeapons++ // defenders bonus
+	if attackerWeapons < 1 {
+		attackerWeapons = 1
+	}
+
+	roundsToKillAttacker := float64(attackerShips) / float64(defenderWeapons)
+	roundsToKillDefender := float64(defenderShips) / float64(attackerWeapons)
+	rounds := int(math.Ceil(math.Min(roundsToKillAttacker, roundsToKillDefender)))
+
+	result := BattleResult{
+		Rounds:                 rounds,
+		AttackerShipsRemaining: maxInt(0, attackerShips-rounds*defenderWeapons),
+		DefenderShipsRemaining: maxInt(0, defenderShips-rounds*attackerWeapons),
+	}
+
+	if result.AttackerShipsRemaining == 0 && result.DefenderShipsRemaining == 0 {
+		// defender wins
+		result.DefenderShipsRemaining += attackerWeapons
+	}
+
+	result.AttackerWins = result.AttackerShipsRemaining > 0
+	result.DefenderShipsNeeded = maxInt(0, int(math.Floor(roundsToKillAttacker*float64(attackerWeapons)))-defenderShips)
+
+	return result
+}
+
+// AttackerShipsNeeded is the smallest fleet that would win against the defender
+func AttackerShipsNeeded(attackerWeapons int, defenderShips int, defenderWeapons int) int {
+	if attackerWeapons < 1 {
+		attackerWeapons = 1
+	}
+	rounds := int(math.Ceil(float64(defenderShips) / float64(attackerWeapons)))
+	return rounds*(defenderWeapons+1) + 1
+}
+
+func maxInt(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
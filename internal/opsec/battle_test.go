@@ -0,0 +1,19 @@
+package opsec
+
+import "testing"
+
+func TestGuessBattle(t *testing.T) {
+	// 10 defenders at weapons 1 (+1 bonus) vs attackers at weapons 2: 5 rounds, 10 attackers lost
+	if result := GuessBattle(10, 2, 10, 1); result.AttackerWins {
+		t.Errorf("expected defender to win ties, got %+v", result)
+	}
+	if result := GuessBattle(11, 2, 10, 1); !result.AttackerWins || result.AttackerShipsRemaining != 1 {
+		t.Errorf("expected attacker to win with 1 ship left, got %+v", result)
+	}
+	if needed := AttackerShipsNeeded(2, 10, 1); needed != 11 {
+		t.Errorf("expected 11 ships needed, got %v", needed)
+	}
+	if needed := AttackerShipsNeeded(2, 0, 1); needed != 1 {
+		t.Errorf("expected 1 ship needed for an empty star, got %v", needed)
+	}
+}
@@ -0,0 +1,286 @@
+package opsec
+
+import (
+	"math"
+	"sort"
+	"strconv"
+
+	"go.albinodrought.com/neptunes-pride/internal/types"
+)
+
+// CoverageCandidate is an ally that could start scanning a gap
+type CoverageCandidate struct {
+	PlayerUID   int     `json:"player_uid"`
+	PlayerAlias string  `json:"player_alias"`
+	ScanRange   float64 `json:"scan_range"`
+	Distance    float64 `json:"distance"`
+	// Shortfall is how much further the ally's scanners would need to reach, 0 if already in range
+	Shortfall float64 `json:"shortfall"`
+}
+
+type StarGap struct {
+	StarUID  int    `json:"star_uid"`
+	StarName string `json:"star_name"`
+	OwnerUID int    `json:"owner_uid"`
+	// LastSeen is the snapshot time this star was last visible to an ally, 0 if never seen
+	LastSeen     int64 `json:"last_seen"`
+	LastSeenTick int   `json:"last_seen_tick"`
+	// DarkTicks is how many ticks this star has been out of scanning range, -1 if never seen
+	DarkTicks int                `json:"dark_ticks"`
+	BestAlly  *CoverageCandidate `json:"best_ally"`
+}
+
+// RegionGap is a cluster of neighbouring stars that no ally scans
+type RegionGap struct {
+	StarUIDs  []int              `json:"star_uids"`
+	X         float64            `json:"x"`
+	Y         float64            `json:"y"`
+	DarkTicks int                `json:"dark_ticks"`
+	BestAlly  *CoverageCandidate `json:"best_ally"`
+}
+
+type CoverageReport struct {
+	Now          int64       `json:"now"`
+	Tick         int         `json:"tick"`
+	VisibleStars int         `json:"visible_stars"`
+	TotalStars   int         `json:"total_stars"`
+	Stars        []StarGap   `json:"stars"`
+	Regions      []RegionGap `json:"regions"`
+}
+
+// RecordSighting marks gaps as seen if they are visible in the given snapshot.
+// Snapshots can be passed in any order, the latest sighting wins.
+func (report *CoverageReport) RecordSighting(snapshot *types.APIResponse) {
+	for i, gap := range report.Stars {
+		if snapshot.ScanningData.Now <= gap.LastSeen {
+			continue
+		}
+
+		star, ok := snapshot.ScanningData.Stars[strconv.Itoa(gap.StarUID)]
+		if !ok || !star.IsVisible() {
+			continue
+		}
+
+		gap.LastSeen = snapshot.ScanningData.Now
+		gap.LastSeenTick = snapshot.ScanningData.Tick
+		gap.DarkTicks = report.Tick - gap.LastSeenTick
+		report.Stars[i] = gap
+	}
+
+	report.updateRegionDarkness()
+}
+
+// Seen returns true if every gap was already seen at or after the given time
+func (report *CoverageReport) Seen(time int64) bool {
+	for _, gap := range report.Stars {
+		if gap.LastSeen < time {
+			return false
+		}
+	}
+	return true
+}
+
+func (report *CoverageReport) updateRegionDarkness() {
+	darkTicks := make(map[int]int, len(report.Stars))
+	for _, gap := range report.Stars {
+		darkTicks[gap.StarUID] = gap.DarkTicks
+	}
+
+	for i, region := range report.Regions {
+		// a region is as dark as its most recently seen star
+		region.DarkTicks = -1
+		for _, starUID := range region.StarUIDs {
+			ticks := darkTicks[starUID]
+			if ticks >= 0 && (region.DarkTicks == -1 || ticks < region.DarkTicks) {
+				region.DarkTicks = ticks
+			}
+		}
+		report.Regions[i] = region
+	}
+}
+
+type scanSource struct {
+	x float64
+	y float64
+}
+
+type scanningAlly struct {
+	uid       int
+	alias     string
+	scanRange float64
+	sources   []scanSource
+}
+
+func (ally *scanningAlly) candidate(x, y float64) *CoverageCandidate {
+	if len(ally.sources) == 0 {
+		return nil
+	}
+
+	nearest := math.Inf(1)
+	for _, source := range ally.sources {
+		distance := types.Distance(source.x, source.y, x, y)
+		if distance < nearest {
+			nearest = distance
+		}
+	}
+
+	return &CoverageCandidate{
+		PlayerUID:   ally.uid,
+		PlayerAlias: ally.alias,
+		ScanRange:   ally.scanRange,
+		Distance:    nearest,
+		Shortfall:   math.Max(0, nearest-ally.scanRange),
+	}
+}
+
+func bestAlly(allies []scanningAlly, x, y float64) *CoverageCandidate {
+	var best *CoverageCandidate
+	for i := range allies {
+		candidate := allies[i].candidate(x, y)
+		if candidate == nil {
+			continue
+		}
+		if best == nil || candidate.Shortfall < best.Shortfall || (candidate.Shortfall == best.Shortfall && candidate.Distance < best.Distance) {
+			best = candidate
+		}
+	}
+	return best
+}
+
+// FindCoverageGaps lists the stars that none of the given allies currently scan,
+// grouped into regions of neighbouring stars.
+// The returned gaps are assumed to have never been seen, use RecordSighting to
+// fill in how long they have been dark.
+func FindCoverageGaps(resp *types.APIResponse, allyIDs []int) CoverageReport {
+	report := CoverageReport{
+		Now:        resp.ScanningData.Now,
+		Tick:       resp.ScanningData.Tick,
+		TotalStars: len(resp.ScanningData.Stars),
+		Stars:      []StarGap{},
+		Regions:    []RegionGap{},
+	}
+
+	allies := []scanningAlly{}
+	linkDistance := 0.0
+	for _, allyID := range allyIDs {
+		player, ok := resp.ScanningData.Players[strconv.Itoa(allyID)]
+		if !ok {
+			continue
+		}
+
+		ally := scanningAlly{
+			uid:       allyID,
+			alias:     player.Alias,
+			scanRange: player.Tech.Scanning.Value,
+			sources:   []scanSource{},
+		}
+
+		for _, star := range resp.ScanningData.Stars {
+			if star.PlayerID == allyID {
+				x, y := star.Position()
+				ally.sources = append(ally.sources, scanSource{x, y})
+			}
+		}
+
+		for _, fleet := range resp.ScanningData.Fleets {
+			if fleet.PlayerID == allyID {
+				x, y := fleet.Position()
+				ally.sources = append(ally.sources, scanSource{x, y})
+			}
+		}
+
+		if ally.scanRange > linkDistance {
+			linkDistance = ally.scanRange
+		}
+
+		allies = append(allies, ally)
+	}
+
+	darkStars := []types.Star{}
+	for _, star := range resp.ScanningData.Stars {
+		if star.IsVisible() {
+			report.VisibleStars++
+			continue
+		}
+		darkStars = append(darkStars, star)
+	}
+
+	sort.Slice(darkStars, func(i, j int) bool {
+		return darkStars[i].UID < darkStars[j].UID
+	})
+
+	for _, star := range darkStars {
+		x, y := star.Position()
+		report.Stars = append(report.Stars, StarGap{
+			StarUID:   star.UID,
+			StarName:  star.Name,
+			OwnerUID:  star.PlayerID,
+			DarkTicks: -1,
+			BestAlly:  bestAlly(allies, x, y),
+		})
+	}
+
+	for _, cluster := range clusterStars(darkStars, linkDistance) {
+		region := RegionGap{
+			StarUIDs:  make([]int, len(cluster)),
+			DarkTicks: -1,
+		}
+		for i, star := range cluster {
+			x, y := star.Position()
+			region.StarUIDs[i] = star.UID
+			region.X += x / float64(len(cluster))
+			region.Y += y / float64(len(cluster))
+		}
+		region.BestAlly = bestAlly(allies, region.X, region.Y)
+		report.Regions = append(report.Regions, region)
+	}
+
+	sort.SliceStable(report.Regions, func(i, j int) bool {
+		return len(report.Regions[i].StarUIDs) > len(report.Regions[j].StarUIDs)
+	})
+
+	return report
+}
+
+// clusterStars groups stars that are within linkDistance of each other
+// (single-linkage, so chains of close stars end up in the same cluster)
+func clusterStars(stars []types.Star, linkDistance float64) [][]types.Star {
+	clusterOf := make([]int, len(stars))
+	for i := range clusterOf {
+		clusterOf[i] = -1
+	}
+
+	clusters := [][]types.Star{}
+	for i := range stars {
+		if clusterOf[i] != -1 {
+			continue
+		}
+
+		clusterID := len(clusters)
+		cluster := []types.Star{}
+		queue := []int{i}
+		clusterOf[i] = clusterID
+
+		for len(queue) > 0 {
+			current := queue[0]
+			queue = queue[1:]
+			cluster = append(cluster, stars[current])
+
+			x1, y1 := stars[current].Position()
+			for j := range stars {
+				if clusterOf[j] != -1 {
+					continue
+				}
+				x2, y2 := stars[j].Position()
+				if types.Distance(x1, y1, x2, y2) <= linkDistance {
+					clusterOf[j] = clusterID
+					queue = append(queue, j)
+				}
+			}
+		}
+
+		clusters = append(clusters, cluster)
+	}
+
+	return clusters
+}
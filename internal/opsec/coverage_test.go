@@ -0,0 +1,52 @@
+package opsec
+
+import (
+	"testing"
+
+	"go.albinodrought.com/neptunes-pride/internal/fixtures"
+	"go.albinodrought.com/neptunes-pride/internal/types"
+)
+
+func TestFindCoverageGaps(t *testing.T) {
+	burrito := fixtures.Load(t, "burrito.json")
+
+	report := FindCoverageGaps(burrito, []int{5})
+	if report.VisibleStars != 24 {
+		t.Errorf("expected 24 visible stars but got %v", report.VisibleStars)
+	}
+	if len(report.Stars) != 146 {
+		t.Fatalf("expected 146 dark stars but got %v", len(report.Stars))
+	}
+
+	regionStars := 0
+	for _, region := range report.Regions {
+		regionStars += len(region.StarUIDs)
+	}
+	if regionStars != len(report.Stars) {
+		t.Errorf("expected every dark star to be in a region, got %v of %v", regionStars, len(report.Stars))
+	}
+
+	for _, gap := range report.Stars {
+		if gap.BestAlly == nil || gap.BestAlly.PlayerUID != 5 {
+			t.Fatalf("expected player 5 to be the best ally for star %v, got %+v", gap.StarUID, gap.BestAlly)
+		}
+		if gap.DarkTicks != -1 {
+			t.Fatalf("expected star %v to have never been seen, got %v", gap.StarUID, gap.DarkTicks)
+		}
+	}
+
+	// the same player saw star 1 a few ticks ago
+	earlier := fixtures.Load(t, "burrito.json")
+	earlier.ScanningData.Tick -= 3
+	earlier.ScanningData.Now -= 1
+	sheliak := earlier.ScanningData.Stars["1"]
+	sheliak.Visible = types.StarVisible
+	earlier.ScanningData.Stars["1"] = sheliak
+
+	report.RecordSighting(earlier)
+	for _, gap := range report.Stars {
+		if gap.StarUID == 1 && gap.DarkTicks != 3 {
+			t.Errorf("expected star 1 to be dark for 3 ticks, got %v", gap.DarkTicks)
+		}
+	}
+}
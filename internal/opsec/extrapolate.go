@@ -0,0 +1,140 @@
+package opsec
+
+import (
+	"math"
+	"strconv"
+
+	"go.albinodrought.com/neptunes-pride/internal/forecast"
+	"go.albinodrought.com/neptunes-pride/internal/types"
+)
+
+// MaxExtrapolatedTicks caps how far forward a snapshot will be projected,
+// very old snapshots are not worth simulating in full
+const MaxExtrapolatedTicks = 24 * 7
+
+func formatCoordinate(value float64) string {
+	return strconv.FormatFloat(value, 'f', 8, 64)
+}
+
+// Extrapolate projects a snapshot forward to the given time (unix millis):
+// fleets follow their order queues and known star garrisons keep producing ships.
+// Projected entities are marked as Estimated.
+// Battles and fleet actions (dropping or collecting ships) are not simulated.
+func Extrapolate(resp *types.APIResponse, now int64) *types.APIResponse {
+	data := &resp.ScanningData
+	if !data.Started || data.Paused || data.GameOver == types.GameOverYes || data.TickRate <= 0 || now <= data.Now {
+		return resp
+	}
+
+	tickLength := float64(data.TickRate) * 60 * 1000
+	elapsed := data.TickFragment + float64(now-data.Now)/tickLength
+	ticks := int(math.Floor(elapsed))
+	if ticks > MaxExtrapolatedTicks {
+		ticks = MaxExtrapolatedTicks
+		elapsed = float64(ticks)
+	}
+
+	for i := 0; i < ticks; i++ {
+		extrapolateFleets(data)
+		extrapolateGarrisons(data)
+
+		data.Tick++
+		data.ProductionCounter++
+		if data.ProductionRate > 0 && data.ProductionCounter >= data.ProductionRate {
+			data.ProductionCounter = 0
+			data.Productions++
+		}
+	}
+
+	data.EstimatedFrom = data.Now
+	data.Now = now
+	data.TickFragment = elapsed - float64(ticks)
+
+	return resp
+}
+
+func extrapolateFleets(data *types.ScanningData) {
+	for fleetIndex, fleet := range data.Fleets {
+		if len(fleet.Orders) == 0 {
+			continue
+		}
+
+		order := append([]int{}, fleet.Orders[0]...)
+		if len(order) < 2 {
+			// malformed order, ignore
+			continue
+		}
+
+		target, ok := data.Stars[strconv.Itoa(order[1])]
+		if !ok {
+			continue
+		}
+
+		if fleet.CurrentStar != 0 {
+			if order[0] > 0 {
+				// waiting at the current star
+				order[0]--
+				fleet.Orders = append([][]int{order}, fleet.Orders[1:]...)
+				fleet.Estimated = true
+				data.Fleets[fleetIndex] = fleet
+				continue
+			}
+
+			// departing, warp gates only apply when both ends have one
+			origin := data.Stars[strconv.Itoa(fleet.CurrentStar)]
+			fleet.WarpSpeed = 0
+			if origin.WarpGate > 0 && target.WarpGate > 0 {
+				fleet.WarpSpeed = 1
+			}
+			fleet.CurrentStar = 0
+		}
+
+		speed := data.FleetSpeed
+		if fleet.WarpSpeed != 0 {
+			speed *= 3
+		}
+
+		x, y := fleet.Position()
+		targetX, targetY := target.Position()
+		distance := types.Distance(x, y, targetX, targetY)
+
+		fleet.LastX, fleet.LastY = fleet.CurrentX, fleet.CurrentY
+		if distance <= speed {
+			fleet.CurrentX, fleet.CurrentY = target.X, target.Y
+			fleet.CurrentStar = target.UID
+			fleet.Orders = fleet.Orders[1:]
+		} else {
+			fleet.CurrentX = formatCoordinate(x + (targetX-x)*speed/distance)
+			fleet.CurrentY = formatCoordinate(y + (targetY-y)*speed/distance)
+		}
+
+		fleet.Estimated = true
+		data.Fleets[fleetIndex] = fleet
+	}
+}
+
+func extrapolateGarrisons(data *types.ScanningData) {
+	if data.ProductionRate <= 0 {
+		return
+	}
+
+	for starIndex, star := range data.Stars {
+		if star.PlayerID == -1 || star.Industry <= 0 {
+			continue
+		}
+
+		owner, ok := data.Players[strconv.Itoa(star.PlayerID)]
+		if !ok {
+			continue
+		}
+
+		shipsPerTick := forecast.ShipsPerTick(star.Industry, owner.Tech.Manufacturing.Level, data.ProductionRate)
+
+		// ShipsPerTick is the fractional ship carried over between ticks
+		ships := float64(star.Strength) + star.ShipsPerTick + shipsPerTick
+		star.Strength = int(math.Floor(ships))
+		star.ShipsPerTick = ships - math.Floor(ships)
+		star.Estimated = true
+		data.Stars[starIndex] = star
+	}
+}
@@ -0,0 +1,40 @@
+package opsec
+
+import (
+	"testing"
+
+	"go.albinodrought.com/neptunes-pride/internal/fixtures"
+)
+
+func TestExtrapolate(t *testing.T) {
+	burrito := fixtures.Load(t, "burrito.json")
+	original := fixtures.Load(t, "burrito.json")
+
+	// 3 hours later, 60 minute ticks
+	now := burrito.ScanningData.Now + 3*60*60*1000
+	Extrapolate(burrito, now)
+
+	if burrito.ScanningData.Now != now || burrito.ScanningData.EstimatedFrom != original.ScanningData.Now {
+		t.Errorf("expected snapshot to be moved from %v to %v, got %v from %v", original.ScanningData.Now, now, burrito.ScanningData.Now, burrito.ScanningData.EstimatedFrom)
+	}
+	if burrito.ScanningData.Tick != original.ScanningData.Tick+3 {
+		t.Errorf("expected 3 ticks to pass, got %v", burrito.ScanningData.Tick-original.ScanningData.Tick)
+	}
+
+	// fleet #2 is in flight
+	fleet := burrito.ScanningData.Fleets["2"]
+	if !fleet.Estimated || fleet.CurrentX == original.ScanningData.Fleets["2"].CurrentX {
+		t.Errorf("expected fleet #2 to be moved, got %+v", fleet)
+	}
+
+	// star #5 has 5 industry, 2 manufacturing, 24 tick production
+	star := burrito.ScanningData.Stars["5"]
+	if !star.Estimated || star.Strength != 21+4 {
+		t.Errorf("expected star #5 to produce 4 ships, got %+v", star)
+	}
+
+	// unknown garrisons stay untouched
+	if burrito.ScanningData.Stars["1"].Estimated {
+		t.Errorf("expected star #1 to not be estimated, got %+v", burrito.ScanningData.Stars["1"])
+	}
+}
@@ -6,13 +6,22 @@ import (
 	"go.albinodrought.com/neptunes-pride/internal/types"
 )
 
+// reportedAt is when the API returned a snapshot, before any extrapolation moved its Now
+func reportedAt(response *types.APIResponse) int64 {
+	if response.ScanningData.EstimatedFrom != 0 {
+		return response.ScanningData.EstimatedFrom
+	}
+	return response.ScanningData.Now
+}
+
 func Merge(responses ...*types.APIResponse) *types.APIResponse {
 	if len(responses) == 0 {
 		return nil
 	}
 
-	sort.Slice(responses, func(i, j int) bool {
-		return responses[i].ScanningData.Now < responses[j].ScanningData.Now
+	// fresher data overrides older data, even when both were extrapolated to the same time
+	sort.SliceStable(responses, func(i, j int) bool {
+		return reportedAt(responses[i]) < reportedAt(responses[j])
 	})
 
 	base := responses[0]
@@ -39,6 +48,7 @@ func Merge(responses ...*types.APIResponse) *types.APIResponse {
 				baseStar := base.ScanningData.Stars[starIndex]
 				baseStar.PrivateStar = star.PrivateStar
 				baseStar.Visible = star.Visible
+				baseStar.Estimated = star.Estimated
 				base.ScanningData.Stars[starIndex] = baseStar
 			}
 		}
@@ -65,3 +65,60 @@ func TestMerge(t *testing.T) {
 
 	ioutil.WriteFile("merged.json", data, os.ModePerm)
 }
+
+func TestMergeExtrapolated(t *testing.T) {
+	loadFile := func(path string) *types.APIResponse {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			panic(err)
+		}
+
+		response := &types.APIResponse{}
+		err = json.Unmarshal(data, response)
+		if err != nil {
+			panic(err)
+		}
+
+		return response
+	}
+
+	// a week-old snapshot and a fresh one, both projected to 2 hours after the fresh one
+	load := func() (*types.APIResponse, *types.APIResponse) {
+		stale := loadFile("aburrido.json")
+		stale.ScanningData.Now -= 7 * 24 * 60 * 60 * 1000
+		star := stale.ScanningData.Stars["4"]
+		star.Strength = 1
+		stale.ScanningData.Stars["4"] = star
+		player := stale.ScanningData.Players["4"]
+		player.Researching = "weapons"
+		stale.ScanningData.Players["4"] = player
+
+		fresh := loadFile("aburrido.json")
+		star = fresh.ScanningData.Stars["4"]
+		star.Strength = 5000
+		fresh.ScanningData.Stars["4"] = star
+
+		now := fresh.ScanningData.Now + 2*60*60*1000
+		Extrapolate(stale, now)
+		Extrapolate(fresh, now)
+
+		return stale, fresh
+	}
+
+	stale, fresh := load()
+	mergedOrders := map[string]*types.APIResponse{
+		"stale first": Merge(stale, fresh),
+	}
+	stale, fresh = load()
+	mergedOrders["fresh first"] = Merge(fresh, stale)
+
+	for order, merged := range mergedOrders {
+		star := merged.ScanningData.Stars["4"]
+		if star.Strength < 5000 || !star.Estimated {
+			t.Errorf("%v: expected the fresh garrison, projected forward, got %+v", order, star)
+		}
+		if merged.ScanningData.Players["4"].Researching != "manufacturing" {
+			t.Errorf("%v: expected the fresh research, got %v", order, merged.ScanningData.Players["4"].Researching)
+		}
+	}
+}
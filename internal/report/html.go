@@ -0,0 +1,21 @@
+package report
+
+import (
+	"bytes"
+	_ "embed"
+	"html/template"
+)
+
+//go:embed report.html.tmpl
+var htmlTemplateSource string
+
+var htmlTemplate = template.Must(template.New("report").Parse(htmlTemplateSource))
+
+// HTML renders a self-contained report, charts and maps are inline SVG
+func (report Report) HTML() ([]byte, error) {
+	buffer := &bytes.Buffer{}
+	if err := htmlTemplate.Execute(buffer, report); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,421 @@
+package report
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.albinodrought.com/neptunes-pride/internal/geometry"
+	"go.albinodrought.com/neptunes-pride/internal/research"
+	"go.albinodrought.com/neptunes-pride/internal/types"
+)
+
+// Colors are NP's player colors, by uid modulo the palette size
+var Colors = []string{
+	"#0000ff",
+	"#009fdf",
+	"#40c000",
+	"#ffc000",
+	"#df5f00",
+	"#c00000",
+	"#c000c0",
+	"#6000c0",
+}
+
+// UnownedColor is used for stars nobody owns
+const UnownedColor = "#808080"
+
+// BattlesInReport caps how many battles are listed
+const BattlesInReport = 10
+
+// mapMoments are the fractions of the game shown as territory maps
+var mapMoments = []float64{0, 0.25, 0.5, 0.75, 1}
+
+const (
+	chartWidth  = 800
+	chartHeight = 240
+	mapSize     = 400
+)
+
+func Color(playerUID int) string {
+	if playerUID < 0 {
+		return UnownedColor
+	}
+	return Colors[playerUID%len(Colors)]
+}
+
+type star struct {
+	name string
+	x    float64
+	y    float64
+}
+
+// sample is the little we keep from every tick, whole snapshots are too big to hold onto
+type sample struct {
+	tick      int
+	now       int64
+	stars     map[int]int
+	tech      map[int]map[string]int
+	ranges    map[int]float64
+	owners    map[int]int
+	garrisons map[int]int
+}
+
+// Builder collects snapshots, oldest first, then builds the report
+type Builder struct {
+	samples []sample
+	stars   map[int]star
+	aliases map[int]string
+}
+
+func NewBuilder() *Builder {
+	return &Builder{
+		samples: []sample{},
+		stars:   map[int]star{},
+		aliases: map[int]string{},
+	}
+}
+
+// Record a (merged) snapshot. Snapshots must be recorded oldest first,
+// later snapshots for an already recorded tick are ignored.
+func (builder *Builder) Record(resp *types.APIResponse) {
+	data := &resp.ScanningData
+	if len(builder.samples) > 0 && builder.samples[len(builder.samples)-1].tick >= data.Tick {
+		return
+	}
+
+	current := sample{
+		tick:      data.Tick,
+		now:       data.Now,
+		stars:     map[int]int{},
+		tech:      map[int]map[string]int{},
+		ranges:    map[int]float64{},
+		owners:    map[int]int{},
+		garrisons: map[int]int{},
+	}
+
+	for _, player := range data.Players {
+		builder.aliases[player.UID] = player.Alias
+		current.stars[player.UID] = player.TotalStars
+		current.ranges[player.UID] = player.Tech.Propulsion.Value
+		current.tech[player.UID] = map[string]int{}
+		for _, tech := range research.Techs {
+			status, _ := research.Status(player.Tech, tech)
+			current.tech[player.UID][tech] = status.Level
+		}
+	}
+
+	for _, s := range data.Stars {
+		x, y := s.Position()
+		builder.stars[s.UID] = star{name: s.Name, x: x, y: y}
+		current.owners[s.UID] = s.PlayerID
+		if s.IsVisible() {
+			current.garrisons[s.UID] = s.Strength
+		}
+	}
+
+	builder.samples = append(builder.samples, current)
+}
+
+type PlayerSummary struct {
+	UID        int    `json:"uid"`
+	Alias      string `json:"alias"`
+	Color      string `json:"color"`
+	Rank       int    `json:"rank"`
+	FinalStars int    `json:"final_stars"`
+}
+
+type Line struct {
+	Alias string `json:"alias"`
+	Color string `json:"color"`
+	// Points in SVG coordinates, "x,y x,y ..."
+	Points string `json:"points"`
+}
+
+type Chart struct {
+	Title  string `json:"title"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	MaxY   int    `json:"max_y"`
+	// FirstTick and LastTick label the x axis
+	FirstTick int    `json:"first_tick"`
+	LastTick  int    `json:"last_tick"`
+	Lines     []Line `json:"lines"`
+}
+
+type Battle struct {
+	Tick          int    `json:"tick"`
+	StarUID       int    `json:"star_uid"`
+	StarName      string `json:"star_name"`
+	AttackerUID   int    `json:"attacker_uid"`
+	AttackerAlias string `json:"attacker_alias"`
+	DefenderUID   int    `json:"defender_uid"`
+	DefenderAlias string `json:"defender_alias"`
+	// DefenderShips is the last garrison seen before the star fell
+	DefenderShips int `json:"defender_ships"`
+}
+
+type Cell struct {
+	Color  string `json:"color"`
+	Points string `json:"points"`
+}
+
+type MapStar struct {
+	X     float64 `json:"x"`
+	Y     float64 `json:"y"`
+	Color string  `json:"color"`
+}
+
+type Map struct {
+	Tick  int       `json:"tick"`
+	Size  int       `json:"size"`
+	Cells []Cell    `json:"cells"`
+	Stars []MapStar `json:"stars"`
+}
+
+type Report struct {
+	GameNumber string          `json:"game_number"`
+	Name       string          `json:"name"`
+	FirstTick  int             `json:"first_tick"`
+	LastTick   int             `json:"last_tick"`
+	Players    []PlayerSummary `json:"players"`
+	Stars      Chart           `json:"stars"`
+	Tech       []Chart         `json:"tech"`
+	Battles    []Battle        `json:"battles"`
+	Maps       []Map           `json:"maps"`
+}
+
+func (builder *Builder) playerUIDs() []int {
+	uids := make([]int, 0, len(builder.aliases))
+	for uid := range builder.aliases {
+		uids = append(uids, uid)
+	}
+	sort.Ints(uids)
+	return uids
+}
+
+func (builder *Builder) chart(title string, value func(s sample, playerUID int) int) Chart {
+	first := builder.samples[0].tick
+	last := builder.samples[len(builder.samples)-1].tick
+
+	chart := Chart{
+		Title:     title,
+		Width:     chartWidth,
+		Height:    chartHeight,
+		FirstTick: first,
+		LastTick:  last,
+		Lines:     []Line{},
+	}
+
+	for _, s := range builder.samples {
+		for _, uid := range builder.playerUIDs() {
+			if v := value(s, uid); v > chart.MaxY {
+				chart.MaxY = v
+			}
+		}
+	}
+
+	scaleX := float64(chartWidth) / math.Max(1, float64(last-first))
+	scaleY := float64(chartHeight) / math.Max(1, float64(chart.MaxY))
+
+	for _, uid := range builder.playerUIDs() {
+		points := make([]string, 0, len(builder.samples))
+		for _, s := range builder.samples {
+			x := float64(s.tick-first) * scaleX
+			y := float64(chartHeight) - float64(value(s, uid))*scaleY
+			points = append(points, fmt.Sprintf("%.1f,%.1f", x, y))
+		}
+		chart.Lines = append(chart.Lines, Line{
+			Alias:  builder.aliases[uid],
+			Color:  Color(uid),
+			Points: strings.Join(points, " "),
+		})
+	}
+
+	return chart
+}
+
+// battles infers fights from stars changing hands between samples
+func (builder *Builder) battles() []Battle {
+	battles := []Battle{}
+	garrisons := map[int]int{}
+
+	for i, s := range builder.samples {
+		if i > 0 {
+			previous := builder.samples[i-1]
+			for starUID, owner := range s.owners {
+				previousOwner, ok := previous.owners[starUID]
+				if !ok || previousOwner == owner || previousOwner == -1 || owner == -1 {
+					continue
+				}
+				battles = append(battles, Battle{
+					Tick:          s.tick,
+					StarUID:       starUID,
+					StarName:      builder.stars[starUID].name,
+					AttackerUID:   owner,
+					AttackerAlias: builder.aliases[owner],
+					DefenderUID:   previousOwner,
+					DefenderAlias: builder.aliases[previousOwner],
+					DefenderShips: garrisons[starUID],
+				})
+			}
+		}
+
+		for starUID, garrison := range s.garrisons {
+			garrisons[starUID] = garrison
+		}
+	}
+
+	sort.Slice(battles, func(i, j int) bool {
+		if battles[i].DefenderShips != battles[j].DefenderShips {
+			return battles[i].DefenderShips > battles[j].DefenderShips
+		}
+		if battles[i].Tick != battles[j].Tick {
+			return battles[i].Tick < battles[j].Tick
+		}
+		return battles[i].StarUID < battles[j].StarUID
+	})
+
+	if len(battles) > BattlesInReport {
+		battles = battles[:BattlesInReport]
+	}
+
+	return battles
+}
+
+// territoryMap rebuilds just enough of a snapshot to compute territory at a sample
+func (builder *Builder) territoryMap(s sample) Map {
+	resp := &types.APIResponse{}
+	resp.ScanningData.Stars = map[string]types.Star{}
+	resp.ScanningData.Players = map[string]types.Player{}
+
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for starUID, owner := range s.owners {
+		info := builder.stars[starUID]
+		minX, minY = math.Min(minX, info.x), math.Min(minY, info.y)
+		maxX, maxY = math.Max(maxX, info.x), math.Max(maxY, info.y)
+
+		mapStar := types.Star{}
+		mapStar.UID = starUID
+		mapStar.PlayerID = owner
+		mapStar.X = strconv.FormatFloat(info.x, 'f', -1, 64)
+		mapStar.Y = strconv.FormatFloat(info.y, 'f', -1, 64)
+		resp.ScanningData.Stars[strconv.Itoa(starUID)] = mapStar
+	}
+	for uid, propulsion := range s.ranges {
+		player := types.Player{}
+		player.UID = uid
+		player.Tech.Propulsion.Value = propulsion
+		resp.ScanningData.Players[strconv.Itoa(uid)] = player
+	}
+
+	// leave room around the edges for territory beyond the outermost stars
+	margin := 0.0
+	for _, propulsion := range s.ranges {
+		margin = math.Max(margin, propulsion)
+	}
+	minX, minY, maxX, maxY = minX-margin, minY-margin, maxX+margin, maxY+margin
+	scale := float64(mapSize) / math.Max(1e-9, math.Max(maxX-minX, maxY-minY))
+	project := func(x float64, y float64) (float64, float64) {
+		return (x - minX) * scale, (y - minY) * scale
+	}
+
+	territoryMap := Map{
+		Tick:  s.tick,
+		Size:  mapSize,
+		Cells: []Cell{},
+		Stars: []MapStar{},
+	}
+
+	territory := geometry.ComputeTerritory(resp)
+	starUIDs := make([]int, 0, len(s.owners))
+	for starUID := range s.owners {
+		starUIDs = append(starUIDs, starUID)
+	}
+	sort.Ints(starUIDs)
+
+	for _, starUID := range starUIDs {
+		owner := s.owners[starUID]
+		info := builder.stars[starUID]
+		x, y := project(info.x, info.y)
+		territoryMap.Stars = append(territoryMap.Stars, MapStar{X: x, Y: y, Color: Color(owner)})
+
+		cell, ok := territory.Cells[starUID]
+		if !ok || owner == -1 {
+			continue
+		}
+		points := make([]string, 0, len(cell.Points))
+		for _, point := range cell.Points {
+			px, py := project(point.X, point.Y)
+			points = append(points, fmt.Sprintf("%.1f,%.1f", px, py))
+		}
+		territoryMap.Cells = append(territoryMap.Cells, Cell{
+			Color:  Color(owner),
+			Points: strings.Join(points, " "),
+		})
+	}
+
+	return territoryMap
+}
+
+// Build the report, false if nothing was recorded
+func (builder *Builder) Build(gameNumber string, name string) (Report, bool) {
+	if len(builder.samples) == 0 {
+		return Report{}, false
+	}
+
+	final := builder.samples[len(builder.samples)-1]
+
+	report := Report{
+		GameNumber: gameNumber,
+		Name:       name,
+		FirstTick:  builder.samples[0].tick,
+		LastTick:   final.tick,
+		Players:    []PlayerSummary{},
+		Tech:       []Chart{},
+		Maps:       []Map{},
+	}
+
+	for _, uid := range builder.playerUIDs() {
+		report.Players = append(report.Players, PlayerSummary{
+			UID:        uid,
+			Alias:      builder.aliases[uid],
+			Color:      Color(uid),
+			FinalStars: final.stars[uid],
+		})
+	}
+	sort.SliceStable(report.Players, func(i, j int) bool {
+		return report.Players[i].FinalStars > report.Players[j].FinalStars
+	})
+	for i := range report.Players {
+		report.Players[i].Rank = i + 1
+	}
+
+	report.Stars = builder.chart("Stars", func(s sample, playerUID int) int {
+		return s.stars[playerUID]
+	})
+
+	for _, tech := range research.Techs {
+		tech := tech
+		report.Tech = append(report.Tech, builder.chart(research.NiceName(tech), func(s sample, playerUID int) int {
+			return s.tech[playerUID][tech]
+		}))
+	}
+
+	report.Battles = builder.battles()
+
+	lastMoment := -1
+	for _, moment := range mapMoments {
+		i := int(math.Round(moment * float64(len(builder.samples)-1)))
+		if i == lastMoment {
+			continue
+		}
+		lastMoment = i
+		report.Maps = append(report.Maps, builder.territoryMap(builder.samples[i]))
+	}
+
+	return report, true
+}
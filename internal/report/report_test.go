@@ -0,0 +1,57 @@
+package report
+
+import (
+	"bytes"
+	"testing"
+
+	"go.albinodrought.com/neptunes-pride/internal/fixtures"
+)
+
+func TestBuild(t *testing.T) {
+	before := fixtures.Load(t, "../opsec/aburrido.json")
+	after := fixtures.Load(t, "../opsec/aburrido.json")
+	after.ScanningData.Tick = before.ScanningData.Tick + 1
+
+	// some other player captures one of our visible stars
+	capturedUID := -1
+	for starIndex, star := range after.ScanningData.Stars {
+		if star.PlayerID == after.ScanningData.PlayerUID && star.IsVisible() {
+			star.PlayerID = (after.ScanningData.PlayerUID + 1) % len(after.ScanningData.Players)
+			after.ScanningData.Stars[starIndex] = star
+			capturedUID = star.UID
+			break
+		}
+	}
+
+	builder := NewBuilder()
+	builder.Record(before)
+	builder.Record(before) // same tick, ignored
+	builder.Record(after)
+
+	report, ok := builder.Build("123", "test game")
+	if !ok {
+		t.Fatal("expected a report")
+	}
+
+	if len(report.Battles) != 1 || report.Battles[0].StarUID != capturedUID {
+		t.Errorf("expected star %v to be captured, got %+v", capturedUID, report.Battles)
+	}
+	if len(report.Maps) != 2 {
+		t.Errorf("expected a map for the first and last tick, got %v", len(report.Maps))
+	}
+	if len(report.Tech) != 7 {
+		t.Errorf("expected a chart per tech, got %v", len(report.Tech))
+	}
+
+	html, err := report.HTML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(html, []byte("<polyline")) || !bytes.Contains(html, []byte("<polygon")) {
+		t.Errorf("expected charts and maps in the report")
+	}
+
+	if _, ok := NewBuilder().Build("123", "empty"); ok {
+		t.Errorf("expected no report without snapshots")
+	}
+}
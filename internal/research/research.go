@@ -0,0 +1,181 @@
+package research
+
+import (
+	"math"
+	"sort"
+
+	"go.albinodrought.com/neptunes-pride/internal/forecast"
+	"go.albinodrought.com/neptunes-pride/internal/types"
+)
+
+// DefaultCostPerTechLevel is used when a player's private tech state is unknown
+const DefaultCostPerTechLevel = 144
+
+const (
+	TechScanning      = "scanning"
+	TechPropulsion    = "propulsion"
+	TechTerraforming  = "terraforming"
+	TechResearch      = "research"
+	TechWeapons       = "weapons"
+	TechBanking       = "banking"
+	TechManufacturing = "manufacturing"
+)
+
+var Techs = []string{
+	TechScanning,
+	TechPropulsion,
+	TechTerraforming,
+	TechResearch,
+	TechWeapons,
+	TechBanking,
+	TechManufacturing,
+}
+
+// NiceName is the tech's name as shown in game
+func NiceName(tech string) string {
+	switch tech {
+	case TechScanning:
+		return "Scanning"
+	case TechPropulsion:
+		return "Hyperspace Range"
+	case TechTerraforming:
+		return "Terraforming"
+	case TechResearch:
+		return "Experimentation"
+	case TechWeapons:
+		return "Weapons"
+	case TechBanking:
+		return "Banking"
+	case TechManufacturing:
+		return "Manufacturing"
+	}
+	return tech
+}
+
+// Status of a tech by name, false if the name is unknown
+func Status(tech types.Tech, name string) (types.TechResearchStatus, bool) {
+	switch name {
+	case TechScanning:
+		return tech.Scanning, true
+	case TechPropulsion:
+		return tech.Propulsion, true
+	case TechTerraforming:
+		return tech.Terraforming, true
+	case TechResearch:
+		return tech.Research, true
+	case TechWeapons:
+		return tech.Weapons, true
+	case TechBanking:
+		return tech.Banking, true
+	case TechManufacturing:
+		return tech.Manufacturing, true
+	}
+	return types.TechResearchStatus{}, false
+}
+
+// PointsNeeded to reach a tech level, same as pointsNeededForTechLevel in the UI
+func PointsNeeded(targetLevel int, costPerTechLevel int) int {
+	if costPerTechLevel <= 0 {
+		costPerTechLevel = DefaultCostPerTechLevel
+	}
+	return costPerTechLevel * (targetLevel - 1)
+}
+
+// TicksNeeded to reach a tech level, same as ticksNeededForResearch in the UI.
+// Returns false if research is stalled.
+func TicksNeeded(targetLevel int, currentPoints int, science int, costPerTechLevel int) (int, bool) {
+	if science <= 0 {
+		return 0, false
+	}
+	remaining := PointsNeeded(targetLevel, costPerTechLevel) - currentPoints
+	return int(math.Max(0, math.Ceil(float64(remaining)/float64(science)))), true
+}
+
+type ETA struct {
+	PlayerUID   int    `json:"player_uid"`
+	PlayerAlias string `json:"player_alias"`
+	Tech        string `json:"tech"`
+	TargetLevel int    `json:"target_level"`
+	Ticks       int    `json:"ticks"`
+	// At is the estimated completion time (unix millis)
+	At int64 `json:"at"`
+}
+
+// CurrentETA estimates when a player finishes their current research.
+// Only works for players we have keys for.
+func CurrentETA(resp *types.APIResponse, player types.Player) (ETA, bool) {
+	status, ok := Status(player.Tech, player.Researching)
+	if !ok {
+		return ETA{}, false
+	}
+
+	targetLevel := status.Level + 1
+	ticks, ok := TicksNeeded(targetLevel, status.Research, player.TotalScience, status.CostPerTechLevel)
+	if !ok {
+		return ETA{}, false
+	}
+
+	return ETA{
+		PlayerUID:   player.UID,
+		PlayerAlias: player.Alias,
+		Tech:        player.Researching,
+		TargetLevel: targetLevel,
+		Ticks:       ticks,
+		At:          forecast.TimeOfTick(&resp.ScanningData, ticks),
+	}, true
+}
+
+type LevelUp struct {
+	PlayerUID   int    `json:"player_uid"`
+	PlayerAlias string `json:"player_alias"`
+	Tech        string `json:"tech"`
+	Level       int    `json:"level"`
+}
+
+// LevelUps finds the public tech levels every player gained between two snapshots
+func LevelUps(previous *types.APIResponse, current *types.APIResponse) []LevelUp {
+	levelUps := []LevelUp{}
+
+	for playerIndex, player := range current.ScanningData.Players {
+		previousPlayer, ok := previous.ScanningData.Players[playerIndex]
+		if !ok {
+			continue
+		}
+
+		for _, tech := range Techs {
+			status, _ := Status(player.Tech, tech)
+			previousStatus, _ := Status(previousPlayer.Tech, tech)
+			if status.Level > previousStatus.Level {
+				levelUps = append(levelUps, LevelUp{
+					PlayerUID:   player.UID,
+					PlayerAlias: player.Alias,
+					Tech:        tech,
+					Level:       status.Level,
+				})
+			}
+		}
+	}
+
+	sort.Slice(levelUps, func(i, j int) bool {
+		if levelUps[i].PlayerUID != levelUps[j].PlayerUID {
+			return levelUps[i].PlayerUID < levelUps[j].PlayerUID
+		}
+		return levelUps[i].Tech < levelUps[j].Tech
+	})
+
+	return levelUps
+}
+
+// ETAs for every player with known research, sorted by player
+func ETAs(resp *types.APIResponse) []ETA {
+	etas := []ETA{}
+	for _, player := range resp.ScanningData.Players {
+		if eta, ok := CurrentETA(resp, player); ok {
+			etas = append(etas, eta)
+		}
+	}
+	sort.Slice(etas, func(i, j int) bool {
+		return etas[i].PlayerUID < etas[j].PlayerUID
+	})
+	return etas
+}
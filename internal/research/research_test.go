@@ -0,0 +1,35 @@
+package research
+
+import (
+	"testing"
+
+	"go.albinodrought.com/neptunes-pride/internal/fixtures"
+)
+
+func TestETA(t *testing.T) {
+	burrito := fixtures.Load(t, "../opsec/burrito.json")
+
+	// player 5 is researching weapons 3: 166 of 288 points at 7 science
+	eta, ok := CurrentETA(burrito, burrito.ScanningData.Players["5"])
+	if !ok {
+		t.Fatalf("expected player 5 to have a research ETA")
+	}
+	if eta.Tech != TechWeapons || eta.TargetLevel != 3 || eta.Ticks != 18 {
+		t.Errorf("expected weapons 3 in 18 ticks, got %+v", eta)
+	}
+
+	// public players have no ETA
+	if _, ok := CurrentETA(burrito, burrito.ScanningData.Players["1"]); ok {
+		t.Errorf("expected player 1 to have no research ETA")
+	}
+
+	later := fixtures.Load(t, "../opsec/burrito.json")
+	player := later.ScanningData.Players["1"]
+	player.Tech.Weapons.Level++
+	later.ScanningData.Players["1"] = player
+
+	levelUps := LevelUps(burrito, later)
+	if len(levelUps) != 1 || levelUps[0].PlayerUID != 1 || levelUps[0].Tech != TechWeapons || levelUps[0].Level != player.Tech.Weapons.Level {
+		t.Errorf("expected player 1 to gain a weapons level, got %+v", levelUps)
+	}
+}
@@ -0,0 +1,230 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// KeySize is the length of keys in bytes, for AES-256
+const KeySize = 32
+
+// sealedPrefix marks sealed values, anything else is plain text
+const sealedPrefix = "npe1:"
+
+// dataKeyContext binds wrapped data keys to their purpose
+var dataKeyContext = []byte("np-scanner data key")
+
+var ErrInvalidKey = fmt.Errorf("secret keys must be %v bytes, base64 encoded", KeySize)
+var ErrNoKey = errors.New("value is encrypted but no secret key was configured")
+var ErrUnknownKey = errors.New("value was encrypted with a secret key that isn't configured")
+var ErrMalformed = errors.New("encrypted value is malformed")
+
+// Keyring seals values with its primary key and opens values sealed with any of its keys.
+// Every value gets its own random data key, which is stored wrapped by the primary key:
+// rotating keys only needs the old key to open values once.
+type Keyring struct {
+	primaryID string
+	keys      map[string][]byte
+}
+
+func keyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:4])
+}
+
+// NewKeyring uses the primary key for sealing, old keys are only used for opening
+func NewKeyring(primary []byte, old ...[]byte) (*Keyring, error) {
+	keyring := &Keyring{
+		primaryID: keyID(primary),
+		keys:      map[string][]byte{},
+	}
+
+	for _, key := range append([][]byte{primary}, old...) {
+		if len(key) != KeySize {
+			return nil, ErrInvalidKey
+		}
+		keyring.keys[keyID(key)] = key
+	}
+
+	return keyring, nil
+}
+
+func ParseKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(key) != KeySize {
+		return nil, ErrInvalidKey
+	}
+	return key, nil
+}
+
+func LoadKeyFile(path string) ([]byte, error) {
+	encoded, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseKey(string(encoded))
+}
+
+// GenerateKey returns a new random key, base64 encoded
+func GenerateKey() (string, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+func IsSealed(value string) bool {
+	return strings.HasPrefix(value, sealedPrefix)
+}
+
+// PrimaryKeyID identifies the key new values are sealed with
+func (keyring *Keyring) PrimaryKeyID() string {
+	return keyring.primaryID
+}
+
+// SealedWithPrimary returns true if the value needs no resealing after a rotation
+func (keyring *Keyring) SealedWithPrimary(value string) bool {
+	return strings.HasPrefix(value, sealedPrefix+keyring.primaryID+":")
+}
+
+func seal(key []byte, plaintext []byte, additionalData []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return gcm.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func open(key []byte, sealed []byte, additionalData []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return nil, ErrMalformed
+	}
+
+	return gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], additionalData)
+}
+
+// wrap seals a data key with the primary key and formats the sealed value
+func (keyring *Keyring) wrap(dataKey []byte, ciphertext string) (string, error) {
+	wrappedKey, err := seal(keyring.keys[keyring.primaryID], dataKey, dataKeyContext)
+	if err != nil {
+		return "", err
+	}
+
+	return sealedPrefix + keyring.primaryID + ":" + base64.RawStdEncoding.EncodeToString(wrappedKey) + ":" + ciphertext, nil
+}
+
+// unwrap splits a sealed value and opens its data key, the ciphertext is left encoded
+func (keyring *Keyring) unwrap(value string) ([]byte, string, error) {
+	parts := strings.Split(strings.TrimPrefix(value, sealedPrefix), ":")
+	if len(parts) != 3 {
+		return nil, "", ErrMalformed
+	}
+
+	key, ok := keyring.keys[parts[0]]
+	if !ok {
+		return nil, "", ErrUnknownKey
+	}
+
+	wrappedKey, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, "", ErrMalformed
+	}
+
+	dataKey, err := open(key, wrappedKey, dataKeyContext)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return dataKey, parts[2], nil
+}
+
+// Seal encrypts a value as "npe1:{key id}:{wrapped data key}:{ciphertext}"
+func (keyring *Keyring) Seal(plaintext string) (string, error) {
+	dataKey := make([]byte, KeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+
+	ciphertext, err := seal(dataKey, []byte(plaintext), nil)
+	if err != nil {
+		return "", err
+	}
+
+	return keyring.wrap(dataKey, base64.RawStdEncoding.EncodeToString(ciphertext))
+}
+
+// Open decrypts a sealed value, plain text values are returned as they are.
+// A nil keyring can only open plain text.
+func (keyring *Keyring) Open(value string) (string, error) {
+	if !IsSealed(value) {
+		return value, nil
+	}
+
+	if keyring == nil {
+		return "", ErrNoKey
+	}
+
+	dataKey, encodedCiphertext, err := keyring.unwrap(value)
+	if err != nil {
+		return "", err
+	}
+
+	ciphertext, err := base64.RawStdEncoding.DecodeString(encodedCiphertext)
+	if err != nil {
+		return "", ErrMalformed
+	}
+
+	plaintext, err := open(dataKey, ciphertext, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
+
+// Reseal makes sure a value is sealed with the primary key.
+// Values sealed with an old key only get their data key rewrapped, plain text values are sealed.
+func (keyring *Keyring) Reseal(value string) (string, error) {
+	if !IsSealed(value) {
+		return keyring.Seal(value)
+	}
+
+	if keyring.SealedWithPrimary(value) {
+		return value, nil
+	}
+
+	dataKey, encodedCiphertext, err := keyring.unwrap(value)
+	if err != nil {
+		return "", err
+	}
+
+	return keyring.wrap(dataKey, encodedCiphertext)
+}
@@ -0,0 +1,82 @@
+package secrets
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestKeyring(t *testing.T) {
+	newKey := func() []byte {
+		encoded, err := GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		key, err := ParseKey(encoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return key
+	}
+
+	oldKey := newKey()
+	oldKeyring, err := NewKeyring(oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sealed, err := oldKeyring.Seal("api-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsSealed(sealed) || strings.Contains(sealed, "api-key") {
+		t.Errorf("expected a sealed value, got %v", sealed)
+	}
+	if again, _ := oldKeyring.Seal("api-key"); again == sealed {
+		t.Error("expected every seal to use a new data key")
+	}
+
+	if opened, err := oldKeyring.Open(sealed); err != nil || opened != "api-key" {
+		t.Errorf("expected to open the sealed value, got %v (%v)", opened, err)
+	}
+	if opened, err := oldKeyring.Open("plain"); err != nil || opened != "plain" {
+		t.Errorf("expected plain text to pass through, got %v (%v)", opened, err)
+	}
+
+	var noKeyring *Keyring
+	if _, err := noKeyring.Open(sealed); err != ErrNoKey {
+		t.Errorf("expected sealed values to need a key, got %v", err)
+	}
+
+	tampered := sealed[:len(sealed)-2] + "AA"
+	if _, err := oldKeyring.Open(tampered); err == nil {
+		t.Error("expected tampered values to fail opening")
+	}
+
+	// rotate: the new key seals, the old one still opens
+	newKeyring, err := NewKeyring(newKey(), oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resealed, err := newKeyring.Reseal(sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !newKeyring.SealedWithPrimary(resealed) || oldKeyring.SealedWithPrimary(resealed) {
+		t.Errorf("expected the value to be rewrapped with the new key, got %v", resealed)
+	}
+	if resealed[strings.LastIndex(resealed, ":"):] != sealed[strings.LastIndex(sealed, ":"):] {
+		t.Error("expected rotation to keep the ciphertext and only rewrap the data key")
+	}
+
+	rotatedKeyring, _ := NewKeyring(newKeyring.keys[newKeyring.PrimaryKeyID()])
+	if opened, err := rotatedKeyring.Open(resealed); err != nil || opened != "api-key" {
+		t.Errorf("expected the new key alone to open the resealed value, got %v (%v)", opened, err)
+	}
+	if _, err := rotatedKeyring.Open(sealed); err != ErrUnknownKey {
+		t.Errorf("expected values sealed with the old key to need it, got %v", err)
+	}
+
+	if _, err := NewKeyring([]byte("short")); err != ErrInvalidKey {
+		t.Errorf("expected short keys to be refused, got %v", err)
+	}
+}
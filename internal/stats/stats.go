@@ -0,0 +1,83 @@
+package stats
+
+import (
+	"sort"
+
+	"go.albinodrought.com/neptunes-pride/internal/research"
+	"go.albinodrought.com/neptunes-pride/internal/types"
+)
+
+// Row is one player's stats at one tick
+type Row struct {
+	Tick        int            `json:"tick"`
+	Now         int64          `json:"now"`
+	PlayerUID   int            `json:"player_uid"`
+	PlayerAlias string         `json:"player_alias"`
+	Stars       int            `json:"stars"`
+	Ships       int            `json:"ships"`
+	Fleets      int            `json:"fleets"`
+	Economy     int            `json:"economy"`
+	Industry    int            `json:"industry"`
+	Science     int            `json:"science"`
+	Tech        map[string]int `json:"tech"`
+	// CashKnown is only set when the row came from the player's own key
+	CashKnown bool `json:"cash_known"`
+	Cash      int  `json:"cash,omitempty"`
+}
+
+// Series is every row stored for a player, oldest first
+type Series struct {
+	PlayerUID   int    `json:"player_uid"`
+	PlayerAlias string `json:"player_alias"`
+	Rows        []Row  `json:"rows"`
+}
+
+// Extract a row for every player in a snapshot, sorted by player
+func Extract(resp *types.APIResponse) []Row {
+	data := &resp.ScanningData
+	rows := make([]Row, 0, len(data.Players))
+
+	for _, player := range data.Players {
+		row := Row{
+			Tick:        data.Tick,
+			Now:         data.Now,
+			PlayerUID:   player.UID,
+			PlayerAlias: player.Alias,
+			Stars:       player.TotalStars,
+			Ships:       player.TotalStrength,
+			Fleets:      player.TotalFleets,
+			Economy:     player.TotalEconomy,
+			Industry:    player.TotalIndustry,
+			Science:     player.TotalScience,
+			Tech:        map[string]int{},
+		}
+
+		for _, tech := range research.Techs {
+			status, _ := research.Status(player.Tech, tech)
+			row.Tech[tech] = status.Level
+		}
+
+		if player.PrivatePlayer.Useful() {
+			row.CashKnown = true
+			row.Cash = player.Cash
+		}
+
+		rows = append(rows, row)
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i].PlayerUID < rows[j].PlayerUID
+	})
+
+	return rows
+}
+
+// Combine two rows for the same player and tick, seen through different keys.
+// Public stats are the same from every key, cash is kept from whichever row knows it.
+func Combine(existing Row, incoming Row) Row {
+	if !incoming.CashKnown && existing.CashKnown {
+		incoming.CashKnown = true
+		incoming.Cash = existing.Cash
+	}
+	return incoming
+}
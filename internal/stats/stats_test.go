@@ -0,0 +1,40 @@
+package stats
+
+import (
+	"strconv"
+	"testing"
+
+	"go.albinodrought.com/neptunes-pride/internal/fixtures"
+	"go.albinodrought.com/neptunes-pride/internal/research"
+)
+
+func TestExtractAndCombine(t *testing.T) {
+	aburrido := fixtures.Load(t, "../opsec/aburrido.json")
+	burrito := fixtures.Load(t, "../opsec/burrito.json")
+
+	rows := Extract(aburrido)
+	if len(rows) != len(aburrido.ScanningData.Players) {
+		t.Fatalf("expected a row per player, got %v", len(rows))
+	}
+
+	for _, row := range rows {
+		player := aburrido.ScanningData.Players[strconv.Itoa(row.PlayerUID)]
+		if row.Stars != player.TotalStars || row.Ships != player.TotalStrength {
+			t.Errorf("expected row to match player %v, got %+v", player.Alias, row)
+		}
+		if row.Tech[research.TechWeapons] != player.Tech.Weapons.Level {
+			t.Errorf("expected weapons level %v, got %v", player.Tech.Weapons.Level, row.Tech[research.TechWeapons])
+		}
+		if row.CashKnown != (row.PlayerUID == aburrido.ScanningData.PlayerUID) {
+			t.Errorf("expected cash to only be known for the key's own player, got %+v", row)
+		}
+	}
+
+	// the same player seen from someone else's key keeps the cash we already knew
+	own := rows[aburrido.ScanningData.PlayerUID]
+	fromOtherKey := Extract(burrito)[aburrido.ScanningData.PlayerUID]
+	combined := Combine(own, fromOtherKey)
+	if !combined.CashKnown || combined.Cash != own.Cash {
+		t.Errorf("expected cash to be kept, got %+v", combined)
+	}
+}
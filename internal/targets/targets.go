@@ -0,0 +1,165 @@
+package targets
+
+import (
+	"sort"
+	"strconv"
+
+	"go.albinodrought.com/neptunes-pride/internal/forecast"
+	"go.albinodrought.com/neptunes-pride/internal/graph"
+	"go.albinodrought.com/neptunes-pride/internal/opsec"
+	"go.albinodrought.com/neptunes-pride/internal/types"
+)
+
+type Weights struct {
+	NaturalResources float64
+	Economy          float64
+	Industry         float64
+	Science          float64
+	WarpGate         float64
+	// ShipCost and TickCost discount a target's value by the effort needed to take it
+	ShipCost float64
+	TickCost float64
+}
+
+var DefaultWeights = Weights{
+	NaturalResources: 1,
+	Economy:          2,
+	Industry:         3,
+	Science:          4,
+	WarpGate:         10,
+	ShipCost:         0.05,
+	TickCost:         0.1,
+}
+
+type Options struct {
+	Weights Weights
+	// MaxTicks ignores targets further away than this, 0 for no limit
+	MaxTicks int
+	// Limit the number of returned targets, 0 for no limit
+	Limit int
+}
+
+var DefaultOptions = Options{
+	Weights:  DefaultWeights,
+	MaxTicks: 0,
+	Limit:    50,
+}
+
+type Target struct {
+	StarUID    int    `json:"star_uid"`
+	StarName   string `json:"star_name"`
+	OwnerUID   int    `json:"owner_uid"`
+	OwnerAlias string `json:"owner_alias"`
+	// Scanned is false when the star's resources, infrastructure and garrison are unknown
+	Scanned          bool        `json:"scanned"`
+	NaturalResources int         `json:"natural_resources"`
+	Economy          int         `json:"economy"`
+	Industry         int         `json:"industry"`
+	Science          int         `json:"science"`
+	WarpGate         bool        `json:"warp_gate"`
+	Route            graph.Route `json:"route"`
+	// ProjectedGarrison is the star's garrison plus orbiting carriers when our fleet arrives
+	ProjectedGarrison int     `json:"projected_garrison"`
+	DefenderWeapons   int     `json:"defender_weapons"`
+	ShipsNeeded       int     `json:"ships_needed"`
+	Value             float64 `json:"value"`
+	Score             float64 `json:"score"`
+}
+
+// Find ranks the stars a player could capture, best first.
+// Stars owned by the player or their allies are never targets.
+func Find(resp *types.APIResponse, playerGraph *graph.Graph, allyIDs []int, options *Options) []Target {
+	if options == nil {
+		options = &DefaultOptions
+	}
+
+	playerUID := playerGraph.PlayerUID
+	player, ok := resp.ScanningData.Players[strconv.Itoa(playerUID)]
+	if !ok {
+		return []Target{}
+	}
+
+	friendly := map[int]bool{playerUID: true}
+	for _, allyID := range allyIDs {
+		friendly[allyID] = true
+	}
+
+	origins := []int{}
+	for _, star := range resp.ScanningData.Stars {
+		if star.PlayerID == playerUID {
+			origins = append(origins, star.UID)
+		}
+	}
+
+	routes := playerGraph.FastestRoutes(origins, options.MaxTicks)
+
+	// carriers sitting at a star help defend it
+	orbitingStrength := map[int]int{}
+	for _, fleet := range resp.ScanningData.Fleets {
+		if fleet.CurrentStar != 0 {
+			star, ok := resp.ScanningData.Stars[strconv.Itoa(fleet.CurrentStar)]
+			if ok && star.PlayerID == fleet.PlayerID {
+				orbitingStrength[fleet.CurrentStar] += fleet.Strength
+			}
+		}
+	}
+
+	weights := options.Weights
+	targets := []Target{}
+	for _, star := range resp.ScanningData.Stars {
+		if friendly[star.PlayerID] {
+			continue
+		}
+
+		route, ok := routes.To(star.UID)
+		if !ok {
+			continue
+		}
+
+		target := Target{
+			StarUID:          star.UID,
+			StarName:         star.Name,
+			OwnerUID:         star.PlayerID,
+			Scanned:          star.IsVisible() || star.PrivateStar.Useful(),
+			NaturalResources: star.NaturalResources,
+			Economy:          star.Economy,
+			Industry:         star.Industry,
+			Science:          star.Science,
+			WarpGate:         star.WarpGate > 0,
+			Route:            route,
+		}
+
+		garrison := float64(star.Strength + orbitingStrength[star.UID])
+		if owner, ok := resp.ScanningData.Players[strconv.Itoa(star.PlayerID)]; ok {
+			target.OwnerAlias = owner.Alias
+			target.DefenderWeapons = owner.Tech.Weapons.Level
+			garrison += forecast.ShipsPerTick(star.Industry, owner.Tech.Manufacturing.Level, resp.ScanningData.ProductionRate) * float64(route.Ticks)
+		}
+		target.ProjectedGarrison = int(garrison)
+		target.ShipsNeeded = opsec.AttackerShipsNeeded(player.Tech.Weapons.Level, target.ProjectedGarrison, target.DefenderWeapons)
+
+		target.Value = weights.NaturalResources*float64(star.NaturalResources) +
+			weights.Economy*float64(star.Economy) +
+			weights.Industry*float64(star.Industry) +
+			weights.Science*float64(star.Science)
+		if target.WarpGate {
+			target.Value += weights.WarpGate
+		}
+		target.Score = target.Value / (1 + weights.ShipCost*float64(target.ShipsNeeded) + weights.TickCost*float64(route.Ticks))
+
+		targets = append(targets, target)
+	}
+
+	sort.Slice(targets, func(i, j int) bool {
+		if targets[i].Score != targets[j].Score {
+			return targets[i].Score > targets[j].Score
+		}
+		return targets[i].StarUID < targets[j].StarUID
+	})
+
+	if options.Limit > 0 && len(targets) > options.Limit {
+		targets = targets[:options.Limit]
+	}
+
+	return targets
+}
@@ -0,0 +1,135 @@
+package targets
+
+import (
+	"reflect"
+	"testing"
+
+	"go.albinodrought.com/neptunes-pride/internal/fixtures"
+	"go.albinodrought.com/neptunes-pride/internal/graph"
+	"go.albinodrought.com/neptunes-pride/internal/types"
+)
+
+func TestFind(t *testing.T) {
+	player := func(uid int, weapons int) types.Player {
+		return types.Player{PublicPlayer: types.PublicPlayer{
+			UID:   uid,
+			Alias: "player",
+			Tech: types.Tech{
+				Propulsion: types.TechResearchStatus{PublicTechResearchStatus: types.PublicTechResearchStatus{Value: 1}},
+				Weapons:    types.TechResearchStatus{PublicTechResearchStatus: types.PublicTechResearchStatus{Level: weapons}},
+			},
+		}}
+	}
+	star := func(uid int, owner int, x string, y string, naturalResources int, strength int) types.Star {
+		return types.Star{
+			PublicStar:  types.PublicStar{UID: uid, PlayerID: owner, X: x, Y: y, Visible: types.StarVisible},
+			PrivateStar: types.PrivateStar{NaturalResources: naturalResources, Strength: strength},
+		}
+	}
+
+	// player 1 at the origin, every other star is a 5 tick jump away except star 4
+	resp := &types.APIResponse{
+		ScanningData: types.ScanningData{
+			FleetSpeed: 0.1,
+			Stars: map[string]types.Star{
+				"1": star(1, 1, "0", "0", 10, 10),
+				"2": star(2, 2, "0.5", "0", 50, 0),
+				"3": star(3, -1, "-0.5", "0", 10, 0),
+				"4": star(4, -1, "5", "5", 100, 0),
+				"5": star(5, 2, "0", "0.5", 50, 40),
+				"6": star(6, 3, "0", "-0.5", 50, 0),
+			},
+			Fleets: map[string]types.Fleet{
+				"1": {UID: 1, PlayerID: 2, CurrentStar: 5, Strength: 10},
+			},
+			Players: map[string]types.Player{
+				"1": player(1, 1),
+				"2": player(2, 1),
+				"3": player(3, 1),
+			},
+		},
+	}
+	playerGraph := graph.New(graph.NewDistanceMatrix(resp.ScanningData.Stars), resp, 1)
+
+	starUIDs := func(targets []Target) []int {
+		uids := []int{}
+		for _, target := range targets {
+			uids = append(uids, target.StarUID)
+		}
+		return uids
+	}
+
+	// undefended 50 beats defended 50 beats undefended 10,
+	// star 4 is out of range and star 6 belongs to an ally
+	targets := Find(resp, playerGraph, []int{3}, nil)
+	if !reflect.DeepEqual(starUIDs(targets), []int{2, 5, 3}) {
+		t.Fatalf("expected targets 2, 5, 3, got %+v", targets)
+	}
+
+	defended := targets[1]
+	if defended.ProjectedGarrison != 50 || defended.ShipsNeeded != 101 || defended.Route.Ticks != 5 {
+		t.Errorf("expected the garrison and orbiting carrier to be attacked after 5 ticks, got %+v", defended)
+	}
+	if targets[0].Score <= defended.Score || defended.Score <= targets[2].Score {
+		t.Errorf("expected scores to fall, got %v, %v, %v", targets[0].Score, defended.Score, targets[2].Score)
+	}
+
+	// without the ally, their star ranks with the undefended one
+	targets = Find(resp, playerGraph, []int{}, nil)
+	if !reflect.DeepEqual(starUIDs(targets), []int{2, 6, 5, 3}) {
+		t.Errorf("expected targets 2, 6, 5, 3, got %v", starUIDs(targets))
+	}
+
+	options := DefaultOptions
+	options.MaxTicks = 4
+	if targets := Find(resp, playerGraph, []int{3}, &options); len(targets) != 0 {
+		t.Errorf("expected nothing within 4 ticks, got %v", starUIDs(targets))
+	}
+
+	options = DefaultOptions
+	options.Limit = 1
+	if targets := Find(resp, playerGraph, []int{3}, &options); !reflect.DeepEqual(starUIDs(targets), []int{2}) {
+		t.Errorf("expected only the best target, got %v", starUIDs(targets))
+	}
+}
+
+func TestFindFixture(t *testing.T) {
+	resp := fixtures.Load(t, "../opsec/burrito.json")
+	playerUID := resp.ScanningData.PlayerUID
+	playerGraph := graph.New(graph.NewDistanceMatrix(resp.ScanningData.Stars), resp, playerUID)
+
+	options := DefaultOptions
+	options.Limit = 0
+	options.MaxTicks = 20
+	targets := Find(resp, playerGraph, []int{}, &options)
+	if len(targets) == 0 || len(targets) == len(resp.ScanningData.Stars) {
+		t.Fatalf("expected some but not all stars to be targets, got %v", len(targets))
+	}
+
+	found := map[int]bool{}
+	for i, target := range targets {
+		found[target.StarUID] = true
+		if target.Route.Ticks > options.MaxTicks {
+			t.Errorf("expected star %v to be out of range, it takes %v ticks", target.StarUID, target.Route.Ticks)
+		}
+		if i > 0 && targets[i-1].Score < target.Score {
+			t.Errorf("expected star %v to rank below star %v", target.StarUID, targets[i-1].StarUID)
+		}
+	}
+
+	// every other star is a target exactly when it can be reached in time
+	origins := []int{}
+	for _, star := range resp.ScanningData.Stars {
+		if star.PlayerID == playerUID {
+			origins = append(origins, star.UID)
+		}
+	}
+	routes := playerGraph.FastestRoutes(origins, options.MaxTicks)
+	for _, star := range resp.ScanningData.Stars {
+		_, reachable := routes.To(star.UID)
+		expected := reachable && star.PlayerID != playerUID
+		if found[star.UID] != expected {
+			t.Errorf("expected star %v (owner %v, reachable %v) to be a target: %v", star.UID, star.PlayerID, reachable, expected)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package trades
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.albinodrought.com/neptunes-pride/internal/opsec"
+	"go.albinodrought.com/neptunes-pride/internal/research"
+	"go.albinodrought.com/neptunes-pride/internal/types"
+)
+
+// DefaultWeights rank techs by how much they matter in a fight
+var DefaultWeights = map[string]float64{
+	research.TechWeapons:       5,
+	research.TechManufacturing: 3,
+	research.TechPropulsion:    2,
+	research.TechBanking:       2,
+	research.TechScanning:      1.5,
+	research.TechTerraforming:  1.5,
+	research.TechResearch:      1,
+}
+
+type Options struct {
+	Weights map[string]float64
+	// ThreatenedWeaponsMultiplier boosts weapons for allies with carriers inbound
+	ThreatenedWeaponsMultiplier float64
+	// ResearchingMultiplier boosts the tech an ally is already researching
+	ResearchingMultiplier float64
+}
+
+var DefaultOptions = Options{
+	Weights:                     DefaultWeights,
+	ThreatenedWeaponsMultiplier: 2,
+	ResearchingMultiplier:       1.5,
+}
+
+type Trade struct {
+	FromUID   int    `json:"from_uid"`
+	FromAlias string `json:"from_alias"`
+	ToUID     int    `json:"to_uid"`
+	ToAlias   string `json:"to_alias"`
+	Tech      string `json:"tech"`
+	// Level the receiver ends up with
+	Level int     `json:"level"`
+	Cost  int     `json:"cost"`
+	Score float64 `json:"score"`
+	// Affordable is false when the sender would run out of cash before this trade,
+	// or before any lower level of the same tech
+	Affordable bool `json:"affordable"`
+	// Reason lists why the receiver needs the tech, the most urgent first
+	Reason string `json:"reason"`
+}
+
+type Plan struct {
+	Productions  int     `json:"productions"`
+	TradeCost    int     `json:"trade_cost"`
+	TradeScanned bool    `json:"trade_scanned"`
+	Trades       []Trade `json:"trades"`
+}
+
+// Cost to send one tech level
+func Cost(level int, tradeCost int) int {
+	return level * tradeCost
+}
+
+// canScan returns true if any of the receiver's stars are within the sender's scanning range
+func canScan(resp *types.APIResponse, from types.Player, to types.Player) bool {
+	scanRange := from.Tech.Scanning.Value
+	for _, fromStar := range resp.ScanningData.Stars {
+		if fromStar.PlayerID != from.UID {
+			continue
+		}
+		x1, y1 := fromStar.Position()
+		for _, toStar := range resp.ScanningData.Stars {
+			if toStar.PlayerID != to.UID {
+				continue
+			}
+			x2, y2 := toStar.Position()
+			if types.Distance(x1, y1, x2, y2) <= scanRange {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Find suggests which tech levels allies should send each other this production.
+// Only players we have keys for are considered, since only they expose their cash.
+// Trades are sorted best first; each level of a tech is a separate trade, as NP sends one level at a time.
+func Find(resp *types.APIResponse, allyIDs []int, options *Options) Plan {
+	if options == nil {
+		options = &DefaultOptions
+	}
+
+	data := &resp.ScanningData
+	plan := Plan{
+		Productions:  data.Productions,
+		TradeCost:    data.TradeCost,
+		TradeScanned: data.TradeScanned == 1,
+		Trades:       []Trade{},
+	}
+
+	allies := []types.Player{}
+	for _, allyID := range allyIDs {
+		player, ok := data.Players[strconv.Itoa(allyID)]
+		if ok && player.PrivatePlayer.Useful() {
+			allies = append(allies, player)
+		}
+	}
+
+	threatened := map[int]bool{}
+	for _, threat := range opsec.FindThreats(resp) {
+		threatened[threat.TargetStar.PlayerID] = true
+	}
+
+	for _, from := range allies {
+		for _, to := range allies {
+			if from.UID == to.UID {
+				continue
+			}
+			if plan.TradeScanned && !canScan(resp, from, to) {
+				continue
+			}
+
+			for _, tech := range research.Techs {
+				fromStatus, _ := research.Status(from.Tech, tech)
+				toStatus, _ := research.Status(to.Tech, tech)
+
+				weight := options.Weights[tech]
+				// every reason that applies, the most urgent first
+				reasons := []string{}
+				if tech == research.TechWeapons && threatened[to.UID] {
+					weight *= options.ThreatenedWeaponsMultiplier
+					reasons = append(reasons, "carriers inbound")
+				}
+				if tech == to.Researching {
+					weight *= options.ResearchingMultiplier
+					reasons = append(reasons, "already researching")
+				}
+				if len(reasons) == 0 {
+					reasons = append(reasons, "tech gap")
+				}
+				reason := strings.Join(reasons, ", ")
+
+				for level := toStatus.Level + 1; level <= fromStatus.Level; level++ {
+					cost := Cost(level, data.TradeCost)
+					score := weight * 100
+					if cost > 0 {
+						score = weight * 100 / float64(cost)
+					}
+
+					plan.Trades = append(plan.Trades, Trade{
+						FromUID:   from.UID,
+						FromAlias: from.Alias,
+						ToUID:     to.UID,
+						ToAlias:   to.Alias,
+						Tech:      tech,
+						Level:     level,
+						Cost:      cost,
+						Score:     score,
+						Reason:    reason,
+					})
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(plan.Trades, func(i, j int) bool {
+		a, b := plan.Trades[i], plan.Trades[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.ToUID != b.ToUID {
+			return a.ToUID < b.ToUID
+		}
+		if a.Tech != b.Tech {
+			return a.Tech < b.Tech
+		}
+		// lower levels have to be sent first
+		return a.Level < b.Level
+	})
+
+	// only one sender needs to send each level, and senders can only spend what they have
+	cash := map[int]int{}
+	for _, ally := range allies {
+		cash[ally.UID] = ally.Cash
+	}
+
+	type received struct {
+		to    int
+		tech  string
+		level int
+	}
+	keys := []received{}
+	candidates := map[received][]Trade{}
+	for _, trade := range plan.Trades {
+		key := received{trade.ToUID, trade.Tech, trade.Level}
+		if _, ok := candidates[key]; !ok {
+			keys = append(keys, key)
+		}
+		candidates[key] = append(candidates[key], trade)
+	}
+
+	findFrom := func(trades []Trade, fromUID int) (Trade, bool) {
+		for _, trade := range trades {
+			if trade.FromUID == fromUID {
+				return trade, true
+			}
+		}
+		return Trade{}, false
+	}
+
+	// levels of a tech are received one after another, so each chain is paid for by one sender,
+	// chains are paid for in the order their best level ranks
+	type chain struct {
+		to   int
+		tech string
+	}
+	chains := []chain{}
+	chainLevels := map[chain][]int{}
+	for _, key := range keys {
+		c := chain{key.to, key.tech}
+		if _, ok := chainLevels[c]; !ok {
+			chains = append(chains, c)
+		}
+		chainLevels[c] = append(chainLevels[c], key.level)
+	}
+
+	chosen := make(map[received]Trade, len(keys))
+	for _, c := range chains {
+		levels := chainLevels[c]
+		sort.Ints(levels)
+
+		// the sender who can afford the most levels in a row sends them all
+		sender, affordable := 0, 0
+		for _, candidate := range candidates[received{c.to, c.tech, levels[0]}] {
+			spent, count := 0, 0
+			for _, level := range levels {
+				trade, ok := findFrom(candidates[received{c.to, c.tech, level}], candidate.FromUID)
+				if !ok || spent+trade.Cost > cash[candidate.FromUID] {
+					break
+				}
+				spent += trade.Cost
+				count++
+			}
+			if count > affordable {
+				sender, affordable = candidate.FromUID, count
+			}
+		}
+
+		for i, level := range levels {
+			key := received{c.to, c.tech, level}
+			trade := candidates[key][0]
+			if i < affordable {
+				trade, _ = findFrom(candidates[key], sender)
+				trade.Affordable = true
+				cash[sender] -= trade.Cost
+			}
+			chosen[key] = trade
+		}
+	}
+
+	plan.Trades = make([]Trade, 0, len(keys))
+	for _, key := range keys {
+		plan.Trades = append(plan.Trades, chosen[key])
+	}
+
+	return plan
+}
@@ -0,0 +1,117 @@
+package trades
+
+import (
+	"testing"
+
+	"go.albinodrought.com/neptunes-pride/internal/fixtures"
+	"go.albinodrought.com/neptunes-pride/internal/opsec"
+	"go.albinodrought.com/neptunes-pride/internal/research"
+	"go.albinodrought.com/neptunes-pride/internal/types"
+)
+
+func TestFind(t *testing.T) {
+	merged := opsec.Merge(fixtures.Load(t, "../opsec/aburrido.json"), fixtures.Load(t, "../opsec/burrito.json"))
+
+	// give player 4 an edge in weapons
+	player := merged.ScanningData.Players["4"]
+	player.Tech.Weapons.Level = merged.ScanningData.Players["5"].Tech.Weapons.Level + 2
+	player.Cash = 1000
+	merged.ScanningData.Players["4"] = player
+
+	plan := Find(merged, []int{4, 5}, nil)
+
+	weaponsTrades := 0
+	for _, trade := range plan.Trades {
+		if trade.Tech != research.TechWeapons {
+			continue
+		}
+		weaponsTrades++
+		if trade.FromUID != 4 || trade.ToUID != 5 {
+			t.Errorf("expected weapons to go from 4 to 5, got %+v", trade)
+		}
+		if trade.Cost != trade.Level*merged.ScanningData.TradeCost {
+			t.Errorf("expected cost to scale with level, got %+v", trade)
+		}
+	}
+	if weaponsTrades != 2 {
+		t.Errorf("expected 2 weapons levels to be traded, got %v", weaponsTrades)
+	}
+}
+
+func TestFindReasons(t *testing.T) {
+	merged := opsec.Merge(fixtures.Load(t, "../opsec/aburrido.json"), fixtures.Load(t, "../opsec/burrito.json"))
+
+	player := merged.ScanningData.Players["4"]
+	player.Tech.Weapons.Level = merged.ScanningData.Players["5"].Tech.Weapons.Level + 1
+	player.Cash = 1000
+	merged.ScanningData.Players["4"] = player
+
+	// player 5 researches weapons while an enemy carrier heads for one of their stars
+	receiver := merged.ScanningData.Players["5"]
+	receiver.Researching = research.TechWeapons
+	merged.ScanningData.Players["5"] = receiver
+	for _, star := range merged.ScanningData.Stars {
+		if star.PlayerID == 5 {
+			merged.ScanningData.Fleets["999999"] = types.Fleet{UID: 999999, PlayerID: 1, Orders: [][]int{{0, star.UID, 0, 0}}}
+			break
+		}
+	}
+
+	weaponsTrades := 0
+	for _, trade := range Find(merged, []int{4, 5}, nil).Trades {
+		if trade.Tech != research.TechWeapons {
+			continue
+		}
+		weaponsTrades++
+		if trade.Reason != "carriers inbound, already researching" {
+			t.Errorf("expected the threat to be listed before research, got %+v", trade)
+		}
+	}
+	if weaponsTrades != 1 {
+		t.Errorf("expected a weapons trade, got %v", weaponsTrades)
+	}
+}
+
+func TestFindLevelChains(t *testing.T) {
+	merged := opsec.Merge(fixtures.Load(t, "../opsec/aburrido.json"), fixtures.Load(t, "../opsec/burrito.json"))
+	receiver := merged.ScanningData.Players["5"]
+	receiver.Cash = 0
+	merged.ScanningData.Players["5"] = receiver
+	weapons := receiver.Tech.Weapons.Level
+	tradeCost := merged.ScanningData.TradeCost
+
+	// only weapons differ: player 1 is one level ahead with plenty of cash,
+	// player 4 is three levels ahead but can only afford the next two
+	ahead := func(uid string, levels int, cash int) {
+		player := merged.ScanningData.Players[uid]
+		player.Tech = receiver.Tech
+		player.Tech.Weapons.Level = weapons + levels
+		player.Cash = cash
+		merged.ScanningData.Players[uid] = player
+	}
+	ahead("1", 1, 10000)
+	ahead("4", 3, Cost(weapons+1, tradeCost)+Cost(weapons+2, tradeCost))
+
+	plan := Find(merged, []int{1, 4, 5}, nil)
+	received := []Trade{}
+	for _, trade := range plan.Trades {
+		if trade.ToUID == 5 {
+			received = append(received, trade)
+		}
+	}
+	if len(received) != 3 {
+		t.Fatalf("expected 3 weapons levels for player 5, got %+v", received)
+	}
+
+	for i, trade := range received {
+		if trade.Tech != research.TechWeapons || trade.Level != weapons+1+i {
+			t.Errorf("expected weapons levels in order, got %+v", trade)
+		}
+		if trade.FromUID != 4 {
+			t.Errorf("expected player 4 to send the whole chain, got %+v", trade)
+		}
+		if trade.Affordable != (i < 2) {
+			t.Errorf("expected only the first 2 levels to be affordable, got %+v", trade)
+		}
+	}
+}
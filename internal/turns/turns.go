@@ -0,0 +1,73 @@
+package turns
+
+import (
+	"sort"
+
+	"go.albinodrought.com/neptunes-pride/internal/types"
+)
+
+// IsTurnBased returns true if the game waits for every player to submit their turn
+func IsTurnBased(data *types.ScanningData) bool {
+	return data.TurnBased != 0
+}
+
+// Deadline is when the current turn resolves (unix millis), 0 if unknown or not turn-based
+func Deadline(data *types.ScanningData) int64 {
+	if !IsTurnBased(data) || data.TurnBasedTimeOut <= 0 {
+		return 0
+	}
+	return int64(data.TurnBasedTimeOut)
+}
+
+// NotReady lists the given players who have not submitted their turn yet, sorted by uid
+func NotReady(resp *types.APIResponse, playerUIDs []int) []types.Player {
+	notReady := []types.Player{}
+
+	for _, player := range resp.ScanningData.Players {
+		if player.Ready != 0 || player.Conceded != 0 {
+			continue
+		}
+		for _, playerUID := range playerUIDs {
+			if player.UID == playerUID {
+				notReady = append(notReady, player)
+				break
+			}
+		}
+	}
+
+	sort.Slice(notReady, func(i, j int) bool {
+		return notReady[i].UID < notReady[j].UID
+	})
+
+	return notReady
+}
+
+type MissedTurns struct {
+	PlayerUID   int    `json:"player_uid"`
+	PlayerAlias string `json:"player_alias"`
+	MissedTurns int    `json:"missed_turns"`
+}
+
+// MissedTurnIncreases finds the players whose missed turn count went up between two snapshots
+func MissedTurnIncreases(previous *types.APIResponse, current *types.APIResponse) []MissedTurns {
+	increases := []MissedTurns{}
+
+	for playerIndex, player := range current.ScanningData.Players {
+		previousPlayer, ok := previous.ScanningData.Players[playerIndex]
+		if !ok || player.MissedTurns <= previousPlayer.MissedTurns {
+			continue
+		}
+
+		increases = append(increases, MissedTurns{
+			PlayerUID:   player.UID,
+			PlayerAlias: player.Alias,
+			MissedTurns: player.MissedTurns,
+		})
+	}
+
+	sort.Slice(increases, func(i, j int) bool {
+		return increases[i].PlayerUID < increases[j].PlayerUID
+	})
+
+	return increases
+}
@@ -0,0 +1,47 @@
+package turns
+
+import (
+	"testing"
+
+	"go.albinodrought.com/neptunes-pride/internal/types"
+)
+
+func TestNotReadyAndMissedTurns(t *testing.T) {
+	snapshot := func(ready map[string]int, missed map[string]int) *types.APIResponse {
+		resp := &types.APIResponse{}
+		resp.ScanningData.TurnBased = 1
+		resp.ScanningData.TurnBasedTimeOut = 1000
+		resp.ScanningData.Players = map[string]types.Player{}
+		for i, uid := range []string{"0", "1", "2"} {
+			player := types.Player{}
+			player.UID = i
+			player.Alias = "player " + uid
+			player.Ready = ready[uid]
+			player.MissedTurns = missed[uid]
+			resp.ScanningData.Players[uid] = player
+		}
+		return resp
+	}
+
+	previous := snapshot(map[string]int{}, map[string]int{"1": 1})
+	current := snapshot(map[string]int{"0": 1}, map[string]int{"1": 2, "2": 0})
+
+	if Deadline(&current.ScanningData) != 1000 {
+		t.Errorf("expected deadline 1000, got %v", Deadline(&current.ScanningData))
+	}
+
+	notReady := NotReady(current, []int{0, 1})
+	if len(notReady) != 1 || notReady[0].UID != 1 {
+		t.Errorf("expected only player 1 to not be ready, got %+v", notReady)
+	}
+
+	increases := MissedTurnIncreases(previous, current)
+	if len(increases) != 1 || increases[0].PlayerUID != 1 || increases[0].MissedTurns != 2 {
+		t.Errorf("expected player 1 to have missed a second turn, got %+v", increases)
+	}
+
+	current.ScanningData.TurnBased = 0
+	if Deadline(&current.ScanningData) != 0 {
+		t.Errorf("expected no deadline for real-time games")
+	}
+}
@@ -1,5 +1,24 @@
 package types
 
+import (
+	"math"
+	"strconv"
+)
+
+// ParseCoordinate parses an API coordinate string, returning 0 for malformed values
+func ParseCoordinate(coordinate string) float64 {
+	value, err := strconv.ParseFloat(coordinate, 64)
+	if err != nil {
+		return 0
+	}
+	return value
+}
+
+// Distance between two points, in API units
+func Distance(x1, y1, x2, y2 float64) float64 {
+	return math.Hypot(x2-x1, y2-y1)
+}
+
 type Fleet struct {
 	UID         int     `json:"uid"`
 	Unknown     int     `json:"l"`
@@ -13,6 +32,12 @@ type Fleet struct {
 	Strength    int     `json:"st"`
 	LastX       string  `json:"lx"`
 	LastY       string  `json:"ly"`
+	// Estimated is set when the position was projected by np-scanner instead of reported by the API
+	Estimated bool `json:"estimated,omitempty"`
+}
+
+func (f Fleet) Position() (float64, float64) {
+	return ParseCoordinate(f.CurrentX), ParseCoordinate(f.CurrentY)
 }
 
 type PublicStar struct {
@@ -24,6 +49,16 @@ type PublicStar struct {
 	Y        string `json:"y"`
 }
 
+const StarVisible = "1"
+
+func (ps PublicStar) IsVisible() bool {
+	return ps.Visible == StarVisible
+}
+
+func (ps PublicStar) Position() (float64, float64) {
+	return ParseCoordinate(ps.X), ParseCoordinate(ps.Y)
+}
+
 type PrivateStar struct {
 	ShipsPerTick     float64 `json:"c"`
 	Economy          int     `json:"e"`
@@ -42,6 +77,8 @@ func (ps PrivateStar) Useful() bool {
 type Star struct {
 	PublicStar
 	PrivateStar
+	// Estimated is set when the garrison was projected by np-scanner instead of reported by the API
+	Estimated bool `json:"estimated,omitempty"`
 }
 
 type PublicTechResearchStatus struct {
@@ -151,6 +188,8 @@ type ScanningData struct {
 	War               int               `json:"war"`
 	Players           map[string]Player `json:"players"`
 	TurnBasedTimeOut  int               `json:"turn_based_time_out"`
+	// EstimatedFrom is the original Now of a snapshot that was projected forward by np-scanner
+	EstimatedFrom int64 `json:"estimated_from,omitempty"`
 }
 
 type APIResponse struct {
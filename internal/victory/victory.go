@@ -0,0 +1,228 @@
+package victory
+
+import (
+	"math"
+	"sort"
+
+	"go.albinodrought.com/neptunes-pride/internal/forecast"
+	"go.albinodrought.com/neptunes-pride/internal/stats"
+	"go.albinodrought.com/neptunes-pride/internal/types"
+)
+
+// DefaultThresholds are the percentages of stars_for_victory worth warning about
+var DefaultThresholds = []int{75, 90}
+
+type Options struct {
+	// RateWindowTicks is how far back capture rates are measured
+	RateWindowTicks int
+}
+
+var DefaultOptions = Options{
+	RateWindowTicks: 72,
+}
+
+type Point struct {
+	Tick  int   `json:"tick"`
+	Now   int64 `json:"now"`
+	Stars int   `json:"stars"`
+}
+
+type PlayerProgress struct {
+	PlayerUID   int     `json:"player_uid"`
+	PlayerAlias string  `json:"player_alias"`
+	Stars       int     `json:"stars"`
+	Percent     float64 `json:"percent"`
+	// StarsPerTick is the net capture rate over the rate window
+	StarsPerTick float64 `json:"stars_per_tick"`
+	// TicksToVictory is -1 if the player is not gaining stars
+	TicksToVictory int `json:"ticks_to_victory"`
+	// VictoryAt is the projected victory time (unix millis), 0 if never
+	VictoryAt int64   `json:"victory_at"`
+	History   []Point `json:"history"`
+}
+
+type Progress struct {
+	Tick            int `json:"tick"`
+	StarsForVictory int `json:"stars_for_victory"`
+	TotalStars      int `json:"total_stars"`
+	// ProjectedWinnerUID is -1 if nobody is on track to win
+	ProjectedWinnerUID   int              `json:"projected_winner_uid"`
+	ProjectedWinnerAlias string           `json:"projected_winner_alias"`
+	Players              []PlayerProgress `json:"players"`
+}
+
+type sample struct {
+	now   int64
+	stars map[int]int
+}
+
+// History of every player's star count, one sample per tick
+type History struct {
+	samples map[int]sample
+}
+
+func NewHistory() *History {
+	return &History{
+		samples: map[int]sample{},
+	}
+}
+
+// Record a snapshot's star counts, snapshots for an already recorded tick are ignored
+func (history *History) Record(resp *types.APIResponse) {
+	data := &resp.ScanningData
+	if _, ok := history.samples[data.Tick]; ok {
+		return
+	}
+
+	stars := map[int]int{}
+	for _, player := range data.Players {
+		stars[player.UID] = player.TotalStars
+	}
+
+	history.samples[data.Tick] = sample{
+		now:   data.Now,
+		stars: stars,
+	}
+}
+
+// RecordStats records star counts from stored stats rows, players already recorded for a tick are ignored
+func (history *History) RecordStats(allSeries []stats.Series) {
+	for _, series := range allSeries {
+		for _, row := range series.Rows {
+			tickSample, ok := history.samples[row.Tick]
+			if !ok {
+				tickSample = sample{
+					now:   row.Now,
+					stars: map[int]int{},
+				}
+				history.samples[row.Tick] = tickSample
+			}
+
+			if _, ok := tickSample.stars[row.PlayerUID]; !ok {
+				tickSample.stars[row.PlayerUID] = row.Stars
+			}
+		}
+	}
+}
+
+func (history *History) ticks() []int {
+	ticks := make([]int, 0, len(history.samples))
+	for tick := range history.samples {
+		ticks = append(ticks, tick)
+	}
+	sort.Ints(ticks)
+	return ticks
+}
+
+// Percent of stars_for_victory
+func Percent(stars int, starsForVictory int) float64 {
+	if starsForVictory <= 0 {
+		return 0
+	}
+	return float64(stars) * 100 / float64(starsForVictory)
+}
+
+// Analyze every player's progress towards victory.
+// The current snapshot is always included, history may be nil.
+func Analyze(resp *types.APIResponse, history *History, options *Options) Progress {
+	if options == nil {
+		options = &DefaultOptions
+	}
+	if history == nil {
+		history = NewHistory()
+	}
+	history.Record(resp)
+
+	data := &resp.ScanningData
+	progress := Progress{
+		Tick:               data.Tick,
+		StarsForVictory:    data.StarsForVictory,
+		TotalStars:         data.TotalStars,
+		ProjectedWinnerUID: -1,
+		Players:            []PlayerProgress{},
+	}
+
+	ticks := history.ticks()
+
+	for _, player := range data.Players {
+		playerProgress := PlayerProgress{
+			PlayerUID:      player.UID,
+			PlayerAlias:    player.Alias,
+			Stars:          player.TotalStars,
+			Percent:        Percent(player.TotalStars, data.StarsForVictory),
+			TicksToVictory: -1,
+			History:        []Point{},
+		}
+
+		for _, tick := range ticks {
+			if tick > data.Tick {
+				// time travelling, ignore the future
+				continue
+			}
+			stars, ok := history.samples[tick].stars[player.UID]
+			if !ok {
+				continue
+			}
+			playerProgress.History = append(playerProgress.History, Point{
+				Tick:  tick,
+				Now:   history.samples[tick].now,
+				Stars: stars,
+			})
+		}
+
+		// measure from the oldest point inside the window
+		for _, point := range playerProgress.History {
+			if data.Tick-point.Tick > options.RateWindowTicks || point.Tick == data.Tick {
+				continue
+			}
+			playerProgress.StarsPerTick = float64(player.TotalStars-point.Stars) / float64(data.Tick-point.Tick)
+			break
+		}
+
+		if player.TotalStars >= data.StarsForVictory && data.StarsForVictory > 0 {
+			playerProgress.TicksToVictory = 0
+		} else if playerProgress.StarsPerTick > 0 {
+			playerProgress.TicksToVictory = int(math.Ceil(float64(data.StarsForVictory-player.TotalStars) / playerProgress.StarsPerTick))
+		}
+		if playerProgress.TicksToVictory >= 0 {
+			playerProgress.VictoryAt = forecast.TimeOfTick(data, playerProgress.TicksToVictory)
+		}
+
+		progress.Players = append(progress.Players, playerProgress)
+	}
+
+	sort.Slice(progress.Players, func(i, j int) bool {
+		a, b := progress.Players[i], progress.Players[j]
+		if a.Stars != b.Stars {
+			return a.Stars > b.Stars
+		}
+		return a.PlayerUID < b.PlayerUID
+	})
+
+	bestTicks := -1
+	for _, player := range progress.Players {
+		if player.TicksToVictory < 0 {
+			continue
+		}
+		if bestTicks == -1 || player.TicksToVictory < bestTicks {
+			bestTicks = player.TicksToVictory
+			progress.ProjectedWinnerUID = player.PlayerUID
+			progress.ProjectedWinnerAlias = player.PlayerAlias
+		}
+	}
+
+	return progress
+}
+
+// Threshold finds the highest threshold a player has passed
+func (player PlayerProgress) Threshold(thresholds []int) (int, bool) {
+	passed := 0
+	ok := false
+	for _, threshold := range thresholds {
+		if player.Percent >= float64(threshold) && threshold > passed {
+			passed = threshold
+			ok = true
+		}
+	}
+	return passed, ok
+}
@@ -0,0 +1,90 @@
+package victory
+
+import (
+	"testing"
+
+	"go.albinodrought.com/neptunes-pride/internal/stats"
+	"go.albinodrought.com/neptunes-pride/internal/types"
+)
+
+func TestAnalyze(t *testing.T) {
+	snapshot := func(tick int, stars map[string]int) *types.APIResponse {
+		resp := &types.APIResponse{}
+		resp.ScanningData.Tick = tick
+		resp.ScanningData.StarsForVictory = 100
+		resp.ScanningData.TotalStars = 200
+		resp.ScanningData.Players = map[string]types.Player{}
+		for uid, count := range stars {
+			player := types.Player{}
+			player.Alias = "player " + uid
+			player.TotalStars = count
+			if uid == "1" {
+				player.UID = 1
+			}
+			resp.ScanningData.Players[uid] = player
+		}
+		return resp
+	}
+
+	history := NewHistory()
+	history.Record(snapshot(0, map[string]int{"0": 40, "1": 50}))
+	history.Record(snapshot(10, map[string]int{"0": 60, "1": 55}))
+
+	progress := Analyze(snapshot(20, map[string]int{"0": 80, "1": 60}), history, nil)
+
+	if progress.ProjectedWinnerUID != 0 {
+		t.Errorf("expected player 0 to be projected to win, got %v", progress.ProjectedWinnerUID)
+	}
+
+	leader := progress.Players[0]
+	if leader.StarsPerTick != 2 {
+		t.Errorf("expected 2 stars per tick, got %v", leader.StarsPerTick)
+	}
+	if leader.TicksToVictory != 10 {
+		t.Errorf("expected victory in 10 ticks, got %v", leader.TicksToVictory)
+	}
+	if len(leader.History) != 3 {
+		t.Errorf("expected 3 history points, got %v", len(leader.History))
+	}
+
+	threshold, ok := leader.Threshold(DefaultThresholds)
+	if !ok || threshold != 75 {
+		t.Errorf("expected the 75%% threshold to be passed, got %v %v", threshold, ok)
+	}
+	if _, ok := progress.Players[1].Threshold(DefaultThresholds); ok {
+		t.Errorf("expected no threshold to be passed for %v", progress.Players[1].PlayerAlias)
+	}
+}
+
+func TestRecordStats(t *testing.T) {
+	current := &types.APIResponse{}
+	current.ScanningData.Tick = 20
+	current.ScanningData.StarsForVictory = 100
+	current.ScanningData.Players = map[string]types.Player{}
+	player := types.Player{}
+	player.UID = 1
+	player.TotalStars = 80
+	current.ScanningData.Players["1"] = player
+
+	history := NewHistory()
+	history.RecordStats([]stats.Series{
+		{PlayerUID: 1, Rows: []stats.Row{
+			{Tick: 0, PlayerUID: 1, Stars: 40},
+			{Tick: 10, PlayerUID: 1, Stars: 60},
+			// stats from the future are ignored
+			{Tick: 30, PlayerUID: 1, Stars: 99},
+		}},
+		{PlayerUID: 2, Rows: []stats.Row{
+			{Tick: 10, PlayerUID: 2, Stars: 5},
+		}},
+	})
+
+	progress := Analyze(current, history, nil)
+	leader := progress.Players[0]
+	if len(leader.History) != 3 || leader.History[1].Stars != 60 {
+		t.Errorf("expected the stored stats and the current tick, got %+v", leader.History)
+	}
+	if leader.StarsPerTick != 2 || leader.TicksToVictory != 10 {
+		t.Errorf("expected 2 stars per tick and victory in 10 ticks, got %+v", leader)
+	}
+}
@@ -4,7 +4,7 @@ import (
 	"context"
 	"embed"
 	"encoding/json"
-	"errors"
+	"fmt"
 	"io/fs"
 	"log"
 	"net/http"
@@ -17,11 +17,17 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/rs/cors"
 	"go.albinodrought.com/neptunes-pride/internal/actions"
+	"go.albinodrought.com/neptunes-pride/internal/advisor"
+	"go.albinodrought.com/neptunes-pride/internal/diplomacy"
+	"go.albinodrought.com/neptunes-pride/internal/forecast"
+	"go.albinodrought.com/neptunes-pride/internal/geometry"
 	"go.albinodrought.com/neptunes-pride/internal/matches"
 	"go.albinodrought.com/neptunes-pride/internal/matchstore"
 	"go.albinodrought.com/neptunes-pride/internal/notifications"
 	"go.albinodrought.com/neptunes-pride/internal/npapi"
-	"go.albinodrought.com/neptunes-pride/internal/opsec"
+	"go.albinodrought.com/neptunes-pride/internal/stats"
+	"go.albinodrought.com/neptunes-pride/internal/targets"
+	"go.albinodrought.com/neptunes-pride/internal/trades"
 	"go.albinodrought.com/neptunes-pride/internal/types"
 )
 
@@ -31,11 +37,16 @@ var packaged embed.FS
 type WebOptions struct {
 	Address    string
 	PollPeriod time.Duration
+	// PrunePeriod is how often old snapshots are downsampled, 0 to never prune
+	PrunePeriod time.Duration
+	Retention   matchstore.RetentionPolicy
 }
 
 var DefaultWebOptions = WebOptions{
-	Address:    ":38080",
-	PollPeriod: time.Minute * 5,
+	Address:     ":38080",
+	PollPeriod:  time.Minute * 5,
+	PrunePeriod: 0,
+	Retention:   matchstore.DefaultRetentionPolicy,
 }
 
 func Run(ctx context.Context, db matchstore.MatchStore, client npapi.NeptunesPrideClient, guard notifications.Guard, sinks []notifications.Sink, options *WebOptions) error {
@@ -68,6 +79,11 @@ func Run(ctx context.Context, db matchstore.MatchStore, client npapi.NeptunesPri
 		log.Println("automatically polling every", options.PollPeriod)
 	}
 
+	if options.PrunePeriod > 0 {
+		go webServer.Prune(options.PrunePeriod, options.Retention)
+		log.Println("automatically pruning snapshots every", options.PrunePeriod)
+	}
+
 	log.Println("Serving on", options.Address)
 	return server.ListenAndServe()
 }
@@ -87,12 +103,16 @@ type webServer struct {
 	sinks  []notifications.Sink
 }
 
-func (ws *webServer) authorize(w http.ResponseWriter, r *http.Request, match *matches.Match) (matches.AccessProfile, bool) {
+// checkAccess finds what an access code unlocks, matches without access codes are public
+func checkAccess(match *matches.Match, accessCode []byte) (matches.AccessProfile, error) {
 	if !match.HasAccessCode() {
-		return matches.PermissiveAccessProfile(), true
+		return matches.PermissiveAccessProfile(), nil
 	}
+	return match.CheckAccessCode(accessCode)
+}
 
-	accessProfile, err := match.CheckAccessCode([]byte(r.URL.Query().Get("access_code")))
+func (ws *webServer) authorize(w http.ResponseWriter, r *http.Request, match *matches.Match) (matches.AccessProfile, bool) {
+	accessProfile, err := checkAccess(match, []byte(r.URL.Query().Get("access_code")))
 	if err == nil {
 		return accessProfile, true
 	}
@@ -130,24 +150,59 @@ func (ws *webServer) Poll(period time.Duration) {
 					continue
 				}
 
-				snapshot, err := ws.getMergedSnapshot(match, matches.PermissiveAccessProfile(), map[string]string{})
+				snapshot, err := actions.MergedSnapshot(ws.db, match, matches.PermissiveAccessProfile(), map[string]string{}, nil)
 				if err != nil {
 					log.Println("failed to get merged snapshot for notification use", gameNumber, err)
 					continue
 				}
 
-				notifiables := actions.CheckNotifiables(match, snapshot)
+				notifiables := actions.CheckNotifiables(ws.db, match, snapshot)
 				err = notifications.SendGuarded(ws.ctx, ws.guard, notifiables, ws.sinks)
 				if err != nil {
 					log.Println("failed to send notifications", err)
 				}
 			}
 
-			timer.Reset(period)
+			timer.Reset(ws.nextPollDelay(period))
 		}
 	}
 }
 
+func (ws *webServer) Prune(period time.Duration, policy matchstore.RetentionPolicy) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ws.ctx.Done():
+			return
+		case <-ticker.C:
+			_, err := ws.db.PruneSnapshots(policy, false, log.Println)
+			if err != nil {
+				log.Println("error while pruning snapshots", err)
+			}
+		}
+	}
+}
+
+// nextPollDelay waits the usual period, unless a turn-based match resolves sooner
+func (ws *webServer) nextPollDelay(period time.Duration) time.Duration {
+	nextTurnPoll, ok, err := actions.NextTurnPoll(ws.db, nil)
+	if err != nil {
+		log.Println("failed to find next turn-based deadline", err)
+		return period
+	}
+	if !ok {
+		return period
+	}
+
+	untilTurn := time.Until(nextTurnPoll)
+	if untilTurn <= 0 || untilTurn >= period {
+		return period
+	}
+	return untilTurn
+}
+
 func (ws *webServer) IndexMatch(w http.ResponseWriter, r *http.Request) {
 	allMatches := []matches.Match{}
 
@@ -316,63 +371,56 @@ func (ws *webServer) IndexPlayerSnapshots(w http.ResponseWriter, r *http.Request
 	json.NewEncoder(w).Encode(snapshotTimes)
 }
 
-var ErrNoSnapshotsLoaded = errors.New("no snapshots loaded")
+func (ws *webServer) snapshotOverrides(r *http.Request, match *matches.Match) map[string]string {
+	overrides := make(map[string]string)
 
-func (ws *webServer) getMergedSnapshot(match *matches.Match, accessProfile matches.AccessProfile, overrides map[string]string) (*types.APIResponse, error) {
-	ignoredSnapshots := 0
-	snapshotsToLoad := make(map[int]int64, len(match.PlayerCreds))
 	for _, creds := range match.PlayerCreds {
-		if !accessProfile.CanViewPlayerID(creds.PlayerUID) {
-			continue
-		}
+		stringID := strconv.Itoa(creds.PlayerUID)
+		overrides[stringID] = r.URL.Query().Get(stringID)
+	}
 
-		if creds.PollingDisabled {
-			// these were messing up the "Last Polled" time, only load these if specified by user below
-			snapshotsToLoad[creds.PlayerUID] = 0
-		} else {
-			snapshotsToLoad[creds.PlayerUID] = creds.LatestSnapshot
-		}
+	return overrides
+}
 
-		customSnapshot, ok := overrides[strconv.Itoa(creds.PlayerUID)]
-		if ok && customSnapshot != "" && customSnapshot != "latest" {
-			customSnapshotInt, err := strconv.ParseInt(customSnapshot, 10, 64)
-			if err != nil {
-				log.Printf("Malformed snapshot int for match %v player %v, %v: %v", match.GameNumber, creds.PlayerUID, customSnapshot, err)
-				return nil, err
-			}
+func (ws *webServer) mergeOptions(r *http.Request) *actions.MergeOptions {
+	extrapolate, _ := strconv.ParseBool(r.URL.Query().Get("extrapolate"))
+	return &actions.MergeOptions{
+		Extrapolate: extrapolate,
+	}
+}
 
-			snapshotsToLoad[creds.PlayerUID] = customSnapshotInt
-		}
+func (ws *webServer) ShowMergedSnapshot(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	gameNumber := vars["gameNumber"]
 
-		if snapshotsToLoad[creds.PlayerUID] == 0 {
-			ignoredSnapshots++
-		}
+	match, err := ws.db.FindMatchOrFail(gameNumber)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Match not found"))
+		log.Printf("Match %v not found: %v", gameNumber, err)
+		return
 	}
 
-	var err error
-	i := 0
-	loadedSnapshots := make([]*types.APIResponse, len(snapshotsToLoad)-ignoredSnapshots)
-	for playerID, snapshotTime := range snapshotsToLoad {
-		if snapshotTime == 0 {
-			continue // ignored
-		}
-
-		loadedSnapshots[i], err = ws.db.FindSnapshot(match.GameNumber, playerID, snapshotTime)
-		i++
-		if err != nil {
-			log.Printf("Could not load snapshot for match %v player %v, %v: %v", match.GameNumber, playerID, snapshotTime, err)
-			return nil, err
-		}
+	accessProfile, ok := ws.authorize(w, r, match)
+	if !ok {
+		return
 	}
 
-	if len(loadedSnapshots) == 0 {
-		return nil, ErrNoSnapshotsLoaded
+	overrides := ws.snapshotOverrides(r, match)
+
+	mergedSnapshot, err := actions.MergedSnapshot(ws.db, match, accessProfile, overrides, ws.mergeOptions(r))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("error merging snapshot"))
+		log.Printf("Failed to get merged snapshot for match %v with overrides %+v: %v", gameNumber, overrides, err)
+		return
 	}
 
-	return opsec.Merge(loadedSnapshots...), nil
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mergedSnapshot)
 }
 
-func (ws *webServer) ShowMergedSnapshot(w http.ResponseWriter, r *http.Request) {
+func (ws *webServer) findAuthorizedMatch(w http.ResponseWriter, r *http.Request) (*matches.Match, matches.AccessProfile, bool) {
 	vars := mux.Vars(r)
 	gameNumber := vars["gameNumber"]
 
@@ -381,31 +429,335 @@ func (ws *webServer) ShowMergedSnapshot(w http.ResponseWriter, r *http.Request)
 		w.WriteHeader(http.StatusNotFound)
 		w.Write([]byte("Match not found"))
 		log.Printf("Match %v not found: %v", gameNumber, err)
-		return
+		return nil, matches.AccessProfile{}, false
 	}
 
 	accessProfile, ok := ws.authorize(w, r, match)
+	return match, accessProfile, ok
+}
+
+func (ws *webServer) findMergedSnapshot(w http.ResponseWriter, r *http.Request, match *matches.Match, accessProfile matches.AccessProfile) (*types.APIResponse, bool) {
+	overrides := ws.snapshotOverrides(r, match)
+
+	mergedSnapshot, err := actions.MergedSnapshot(ws.db, match, accessProfile, overrides, ws.mergeOptions(r))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("error merging snapshot"))
+		log.Printf("Failed to get merged snapshot for match %v with overrides %+v: %v", match.GameNumber, overrides, err)
+		return nil, false
+	}
+
+	return mergedSnapshot, true
+}
+
+func (ws *webServer) ShowCoverage(w http.ResponseWriter, r *http.Request) {
+	match, accessProfile, ok := ws.findAuthorizedMatch(w, r)
 	if !ok {
 		return
 	}
 
-	overrides := make(map[string]string)
+	mergedSnapshot, ok := ws.findMergedSnapshot(w, r, match, accessProfile)
+	if !ok {
+		return
+	}
 
-	for _, creds := range match.PlayerCreds {
-		stringID := strconv.Itoa(creds.PlayerUID)
-		overrides[stringID] = r.URL.Query().Get(stringID)
+	report, err := actions.FindCoverageGaps(ws.db, match, accessProfile, mergedSnapshot, actions.DefaultCoverageHistoryLimit)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("error finding coverage gaps"))
+		log.Printf("Failed to find coverage gaps for match %v: %v", match.GameNumber, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+type territoryResponse struct {
+	Territories geometry.FeatureCollection `json:"territories"`
+	Frontline   []geometry.FrontlineStar   `json:"frontline"`
+}
+
+func (ws *webServer) ShowTerritory(w http.ResponseWriter, r *http.Request) {
+	match, accessProfile, ok := ws.findAuthorizedMatch(w, r)
+	if !ok {
+		return
+	}
+
+	mergedSnapshot, ok := ws.findMergedSnapshot(w, r, match, accessProfile)
+	if !ok {
+		return
+	}
+
+	territory := geometry.ComputeTerritory(mergedSnapshot)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(territoryResponse{
+		Territories: territory.GeoJSON(mergedSnapshot),
+		Frontline:   territory.Frontline(mergedSnapshot, actions.AllyIDs(match, accessProfile)),
+	})
+}
+
+func (ws *webServer) findAuthorizedPlayerID(w http.ResponseWriter, r *http.Request, accessProfile matches.AccessProfile) (int, bool) {
+	playerID, err := strconv.Atoi(mux.Vars(r)["player"])
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Malformed {player}"))
+		return 0, false
+	}
+
+	if !accessProfile.CanViewPlayerID(playerID) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("Not allowed to view {player}"))
+		return 0, false
+	}
+
+	return playerID, true
+}
+
+func (ws *webServer) IndexTargets(w http.ResponseWriter, r *http.Request) {
+	match, accessProfile, ok := ws.findAuthorizedMatch(w, r)
+	if !ok {
+		return
+	}
+
+	playerID, ok := ws.findAuthorizedPlayerID(w, r, accessProfile)
+	if !ok {
+		return
+	}
+
+	options := targets.DefaultOptions
+	if strMaxTicks := r.URL.Query().Get("max_ticks"); strMaxTicks != "" {
+		maxTicks, err := strconv.Atoi(strMaxTicks)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Malformed ?max_ticks"))
+			return
+		}
+		options.MaxTicks = maxTicks
+	}
+	if strLimit := r.URL.Query().Get("limit"); strLimit != "" {
+		limit, err := strconv.Atoi(strLimit)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Malformed ?limit"))
+			return
+		}
+		options.Limit = limit
+	}
+
+	mergedSnapshot, ok := ws.findMergedSnapshot(w, r, match, accessProfile)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(actions.FindTargets(match, accessProfile, mergedSnapshot, playerID, &options))
+}
+
+func (ws *webServer) ShowAdvice(w http.ResponseWriter, r *http.Request) {
+	match, accessProfile, ok := ws.findAuthorizedMatch(w, r)
+	if !ok {
+		return
+	}
+
+	playerID, ok := ws.findAuthorizedPlayerID(w, r, accessProfile)
+	if !ok {
+		return
+	}
+
+	options := advisor.DefaultOptions
+	if goal := r.URL.Query().Get("goal"); goal != "" {
+		options.Goal = goal
+	}
+	if strBudget := r.URL.Query().Get("budget"); strBudget != "" {
+		budget, err := strconv.Atoi(strBudget)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Malformed ?budget"))
+			return
+		}
+		options.Budget = budget
+	}
+
+	mergedSnapshot, ok := ws.findMergedSnapshot(w, r, match, accessProfile)
+	if !ok {
+		return
+	}
+
+	advice, err := advisor.Advise(mergedSnapshot, playerID, &options)
+	if err == advisor.ErrUnknownGoal {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Malformed ?goal"))
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("No private data for {player}"))
+		log.Printf("Failed to advise player %v in match %v: %v", playerID, match.GameNumber, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(advice)
+}
+
+func (ws *webServer) ShowForecast(w http.ResponseWriter, r *http.Request) {
+	match, accessProfile, ok := ws.findAuthorizedMatch(w, r)
+	if !ok {
+		return
+	}
+
+	mergedSnapshot, ok := ws.findMergedSnapshot(w, r, match, accessProfile)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(forecast.Predict(mergedSnapshot))
+}
+
+func (ws *webServer) ShowTrades(w http.ResponseWriter, r *http.Request) {
+	match, accessProfile, ok := ws.findAuthorizedMatch(w, r)
+	if !ok {
+		return
+	}
+
+	mergedSnapshot, ok := ws.findMergedSnapshot(w, r, match, accessProfile)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(trades.Find(mergedSnapshot, actions.AllyIDs(match, accessProfile), nil))
+}
+
+func (ws *webServer) ShowVictory(w http.ResponseWriter, r *http.Request) {
+	match, accessProfile, ok := ws.findAuthorizedMatch(w, r)
+	if !ok {
+		return
+	}
+
+	mergedSnapshot, ok := ws.findMergedSnapshot(w, r, match, accessProfile)
+	if !ok {
+		return
 	}
 
-	mergedSnapshot, err := ws.getMergedSnapshot(match, accessProfile, overrides)
+	progress, err := actions.VictoryProgress(ws.db, match, accessProfile, mergedSnapshot, actions.DefaultVictoryHistoryLimit)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte("error merging snapshot"))
-		log.Printf("Failed to get merged snapshot for match %v with overrides %+v: %v", gameNumber, overrides, err)
+		w.Write([]byte("error charting victory progress"))
+		log.Printf("Failed to chart victory progress for match %v: %v", match.GameNumber, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(mergedSnapshot)
+	json.NewEncoder(w).Encode(progress)
+}
+
+func (ws *webServer) ShowDiplomacy(w http.ResponseWriter, r *http.Request) {
+	match, accessProfile, ok := ws.findAuthorizedMatch(w, r)
+	if !ok {
+		return
+	}
+
+	mergedSnapshot, ok := ws.findMergedSnapshot(w, r, match, accessProfile)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(diplomacy.Matrix(mergedSnapshot))
+}
+
+func (ws *webServer) ShowPlayer(w http.ResponseWriter, r *http.Request) {
+	alias := mux.Vars(r)["alias"]
+	accessCode := []byte(r.URL.Query().Get("access_code"))
+
+	// only include matches that are public, or that the access code unlocks
+	accessProfileFor := func(match *matches.Match) (matches.AccessProfile, bool) {
+		accessProfile, err := checkAccess(match, accessCode)
+		return accessProfile, err == nil
+	}
+
+	playerDossier, err := actions.BuildDossier(ws.db, alias, accessProfileFor, actions.DefaultDossierHistoryLimit)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("error building dossier"))
+		log.Printf("Failed to build dossier for %v: %v", alias, err)
+		return
+	}
+
+	if len(playerDossier.Games) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Player not found"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(playerDossier)
+}
+
+func (ws *webServer) ShowReport(w http.ResponseWriter, r *http.Request) {
+	match, accessProfile, ok := ws.findAuthorizedMatch(w, r)
+	if !ok {
+		return
+	}
+
+	if !accessProfile.CanViewEveryPlayer {
+		// reports are built from every player's snapshots
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("Not allowed to view the report"))
+		return
+	}
+
+	report, err := ws.db.FindReport(match.GameNumber)
+	if err == matchstore.ErrReportNotFound {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Report not found"))
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("error loading report"))
+		log.Printf("Failed to load report for match %v: %v", match.GameNumber, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"np-%v-report.html\"", match.GameNumber))
+	w.Write(report)
+}
+
+func (ws *webServer) IndexStats(w http.ResponseWriter, r *http.Request) {
+	match, accessProfile, ok := ws.findAuthorizedMatch(w, r)
+	if !ok {
+		return
+	}
+
+	allSeries, err := ws.db.ListStats(match.GameNumber)
+	if err == matchstore.ErrMatchNotFound {
+		allSeries = []stats.Series{}
+	} else if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("error listing stats"))
+		log.Printf("Failed to list stats for match %v: %v", match.GameNumber, err)
+		return
+	}
+
+	// cash is private, only show it for players this profile can view
+	for _, series := range allSeries {
+		if accessProfile.CanViewPlayerID(series.PlayerUID) {
+			continue
+		}
+		for i := range series.Rows {
+			series.Rows[i].CashKnown = false
+			series.Rows[i].Cash = 0
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(allSeries)
 }
 
 func (ws *webServer) Router() http.Handler {
@@ -416,6 +768,17 @@ func (ws *webServer) Router() http.Handler {
 	r.HandleFunc("/api/matches/{gameNumber}/api-key", ws.AddApiKey)
 	r.HandleFunc("/api/matches/{gameNumber}/player-snapshots/{player}", ws.IndexPlayerSnapshots)
 	r.HandleFunc("/api/matches/{gameNumber}/merged-snapshot", ws.ShowMergedSnapshot)
+	r.HandleFunc("/api/matches/{gameNumber}/coverage", ws.ShowCoverage)
+	r.HandleFunc("/api/matches/{gameNumber}/territory", ws.ShowTerritory)
+	r.HandleFunc("/api/matches/{gameNumber}/targets/{player}", ws.IndexTargets)
+	r.HandleFunc("/api/matches/{gameNumber}/advisor/{player}", ws.ShowAdvice)
+	r.HandleFunc("/api/matches/{gameNumber}/forecast", ws.ShowForecast)
+	r.HandleFunc("/api/matches/{gameNumber}/trades", ws.ShowTrades)
+	r.HandleFunc("/api/matches/{gameNumber}/victory", ws.ShowVictory)
+	r.HandleFunc("/api/matches/{gameNumber}/diplomacy", ws.ShowDiplomacy)
+	r.HandleFunc("/api/matches/{gameNumber}/report", ws.ShowReport)
+	r.HandleFunc("/api/matches/{gameNumber}/stats", ws.IndexStats)
+	r.HandleFunc("/api/players/{alias}", ws.ShowPlayer)
 
 	sub, err := fs.Sub(packaged, "packaged")
 	if err != nil {
@@ -0,0 +1,83 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.albinodrought.com/neptunes-pride/internal/fixtures"
+	"go.albinodrought.com/neptunes-pride/internal/matches"
+	"go.albinodrought.com/neptunes-pride/internal/matchstore"
+	"go.albinodrought.com/neptunes-pride/internal/notifications"
+	"go.albinodrought.com/neptunes-pride/internal/types"
+)
+
+// TestEphemeral serves a store seeded from an archive, like `serve --ephemeral --seed`
+func TestEphemeral(t *testing.T) {
+	snapshot := fixtures.Load(t, "../opsec/aburrido.json")
+
+	from := matchstore.OpenMemory()
+	if err := from.SaveSnapshot("123", snapshot); err != nil {
+		t.Fatal(err)
+	}
+	match, _ := from.FindOrCreateMatch("123")
+	match.Name = "Burrito Galaxy"
+	match.PlayerCreds[4] = matches.PlayerCreds{PlayerUID: 4, APIKey: "abc", LatestSnapshot: snapshot.ScanningData.Now}
+	accessProfile, _ := matches.NewAccessProfile([]byte("hunter2"))
+	accessProfile.CanViewEveryPlayer = true
+	if err := match.AddAccessProfile(accessProfile, []byte("hunter2")); err != nil {
+		t.Fatal(err)
+	}
+	if err := from.SaveMatch(match); err != nil {
+		t.Fatal(err)
+	}
+
+	archive := &bytes.Buffer{}
+	if _, err := matchstore.Export(from, "123", archive, &matchstore.ExportOptions{Log: t.Log}); err != nil {
+		t.Fatal(err)
+	}
+	db := matchstore.OpenMemory()
+	if _, err := matchstore.Import(db, bytes.NewReader(archive.Bytes()), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	ws := &webServer{context.Background(), db, nil, notifications.NewMemoryGuard(), []notifications.Sink{}}
+	router := ws.Router()
+	get := func(path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder
+	}
+
+	index := get("/api/matches")
+	allMatches := []matches.Match{}
+	if err := json.NewDecoder(index.Body).Decode(&allMatches); err != nil {
+		t.Fatal(err)
+	}
+	if len(allMatches) != 1 || allMatches[0].Name != "Burrito Galaxy" || len(allMatches[0].PlayerCreds) != 0 || allMatches[0].HasAccessCode() {
+		t.Errorf("expected the seeded match without its secrets, got %+v", allMatches)
+	}
+
+	if response := get("/api/matches/123/merged-snapshot?access_code=wrong"); response.Code != http.StatusUnauthorized {
+		t.Errorf("expected a wrong access code to be refused, got %v", response.Code)
+	}
+
+	response := get("/api/matches/123/merged-snapshot?access_code=hunter2")
+	if response.Code != http.StatusOK {
+		t.Fatalf("expected the merged snapshot, got %v: %v", response.Code, response.Body.String())
+	}
+	merged := &types.APIResponse{}
+	if err := json.NewDecoder(response.Body).Decode(merged); err != nil {
+		t.Fatal(err)
+	}
+	if merged.ScanningData.Tick != snapshot.ScanningData.Tick || len(merged.ScanningData.Stars) == 0 {
+		t.Errorf("expected the seeded snapshot to be merged, got tick %v", merged.ScanningData.Tick)
+	}
+
+	if response := get("/api/matches/456"); response.Code != http.StatusNotFound {
+		t.Errorf("expected unknown matches to be missing, got %v", response.Code)
+	}
+}
@@ -10,6 +10,7 @@ export interface Fleet {
   st: number;
   lx: string;
   ly: string;
+  estimated?: boolean;
 }
 
 export interface PublicStar {
@@ -32,7 +33,7 @@ export interface PrivateStar {
   st: number;
 }
 
-export type Star = PublicStar & Partial<PrivateStar>;
+export type Star = PublicStar & Partial<PrivateStar> & { estimated?: boolean };
 
 export interface PublicTechResearchStatus {
   level: number;
@@ -136,6 +137,7 @@ export interface ScanningData {
   war: number;
   players: { [key: string]: Player };
   turn_based_time_out: number;
+  estimated_from?: number;
 }
 
 export interface APIResponse {
@@ -156,4 +158,5 @@ export interface Match {
   finished: boolean;
   last_poll: string;
   player_creds: { [key: string]: PlayerCreds };
+  turn_deadline?: number;
 }